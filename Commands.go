@@ -0,0 +1,547 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	agents "github.com/ADimoska/SOMASExtended/agents"
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/ADimoska/SOMASExtended/logging"
+	"github.com/ADimoska/SOMASExtended/simulation"
+	"github.com/ADimoska/SOMASExtended/voting"
+
+	gameRecorder "github.com/ADimoska/SOMASExtended/gameRecorder"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+)
+
+// --------- Shared flags ---------
+
+/*
+* Every command that plays games accepts a scenario file and one flag per
+* scenario value. Flags that are given override the file, which overrides the
+* defaults.
+ */
+type scenarioFlags struct {
+	flags     *flag.FlagSet
+	file      *string
+	overrides map[string]*string
+}
+
+func addScenarioFlags(flags *flag.FlagSet) *scenarioFlags {
+	sf := &scenarioFlags{
+		flags:     flags,
+		file:      flags.String("scenario", "", "JSON scenario file (defaults to the built-in scenario)"),
+		overrides: make(map[string]*string),
+	}
+	for _, key := range simulation.ScenarioKeys {
+		sf.overrides[key] = flags.String(key, "", "override the scenario's "+key)
+	}
+	return sf
+}
+
+func (sf *scenarioFlags) scenario() (simulation.Scenario, error) {
+	scenario := simulation.DefaultScenario()
+	if *sf.file != "" {
+		var err error
+		if scenario, err = simulation.LoadScenario(*sf.file); err != nil {
+			return scenario, err
+		}
+	}
+	var err error
+	sf.flags.Visit(func(f *flag.Flag) {
+		if value, ok := sf.overrides[f.Name]; ok && err == nil {
+			err = scenario.Set(f.Name, *value)
+		}
+	})
+	if err != nil {
+		return scenario, err
+	}
+	return scenario, scenario.Validate()
+}
+
+/*
+* Logging flags of the commands that play games. The level takes a default
+* followed by per component levels, e.g. "warn,agent=debug,aoa=trace".
+ */
+type logFlags struct {
+	level *string
+	json  *bool
+}
+
+func addLogFlags(flags *flag.FlagSet, defaultLevel string) *logFlags {
+	return &logFlags{
+		level: flags.String("log-level", defaultLevel, "log levels ("+strings.Join(logging.LevelNames(), ", ")+"), e.g. warn,agent=debug"),
+		json:  flags.Bool("log-json", false, "write the log as one JSON object per line"),
+	}
+}
+
+func (lf *logFlags) config() (logging.Config, error) {
+	config := logging.DefaultConfig()
+	level, levels, err := logging.ParseLevels(*lf.level)
+	config.Level, config.Levels, config.JSON = level, levels, *lf.json
+	return config, err
+}
+
+// Start logging as configured, returning the function to call when done
+func (lf *logFlags) setup() func() {
+	config, _ := lf.config()
+	if config.Level == logging.LevelOff && len(config.Levels) == 0 {
+		logging.Disable()
+		return func() {}
+	}
+	return setupLogging(config)
+}
+
+// breakpoints can be given multiple times on the command line
+type breakpointFlags []envServer.Breakpoint
+
+func (b *breakpointFlags) String() string {
+	specs := []string{}
+	for _, bp := range *b {
+		specs = append(specs, bp.String())
+	}
+	return strings.Join(specs, " ")
+}
+
+func (b *breakpointFlags) Set(spec string) error {
+	bp, err := envServer.ParseBreakpoint(spec)
+	if err != nil {
+		return err
+	}
+	*b = append(*b, bp)
+	return nil
+}
+
+// repeatable string flag
+type listFlags []string
+
+func (l *listFlags) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *listFlags) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func usageError(err error) int {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	return exitUsage
+}
+
+func runError(err error) int {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	return exitError
+}
+
+// Print the violations of a result and turn them into the exit code
+func reportViolations(results []*simulation.Result) int {
+	code := exitOK
+	for _, result := range results {
+		for _, violation := range result.Violations {
+			fmt.Fprintf(os.Stderr, "invariant violated in %s: %s\n", result.Scenario.Name, violation)
+			code = exitInvariantFailure
+		}
+	}
+	return code
+}
+
+// --------- Commands ---------
+
+func cmdRun(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	sf := addScenarioFlags(flags)
+	debug := flags.Bool("debug", false, "pause the game at breakpoints and open an interactive prompt")
+	var breakpoints breakpointFlags
+	flags.Var(&breakpoints, "break", "breakpoint, e.g. iteration=1,turn=9,phase=withdrawal (repeatable, implies -debug)")
+	eventLog := flags.String("event-log", "", "write every game event to this file, for replay")
+	out := flags.String("out", "", "save the result as JSON to this file")
+	lf := addLogFlags(flags, "info")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	scenario, err := sf.scenario()
+	if err != nil {
+		return usageError(err)
+	}
+	if _, err := lf.config(); err != nil {
+		return usageError(err)
+	}
+
+	closeLog := lf.setup()
+	defer closeLog()
+
+	options := simulation.RunOptions{
+		Debug:       *debug,
+		Breakpoints: breakpoints,
+		Finish: func(serv *envServer.EnvironmentServer) {
+			// custom function to see agent result
+			serv.LogAgentStatus()
+			serv.LogTeamStatus()
+
+			// record data
+			serv.DataRecorder.GamePlaybackSummary()
+		},
+	}
+	if *eventLog != "" {
+		f, err := os.Create(*eventLog)
+		if err != nil {
+			return runError(err)
+		}
+		defer f.Close()
+		options.EventLog = f
+	}
+
+	result, err := simulation.Run(scenario, options)
+	if err != nil {
+		return runError(err)
+	}
+	if *out != "" {
+		if err := simulation.SaveResult(result, *out); err != nil {
+			return runError(err)
+		}
+	}
+	return reportViolations([]*simulation.Result{result})
+}
+
+// Run a list of scenarios, saving each result in outputDir and printing a summary
+func runAll(scenarios []simulation.Scenario, outputDir string, lf *logFlags) int {
+	closeLog := lf.setup()
+	defer closeLog()
+
+	results := []*simulation.Result{}
+	for i, scenario := range scenarios {
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", i+1, len(scenarios), scenario.Name)
+		result, err := simulation.Run(scenario, simulation.RunOptions{})
+		if err != nil {
+			return runError(err)
+		}
+		if err := simulation.SaveResult(result, filepath.Join(outputDir, scenario.Name+".json")); err != nil {
+			return runError(err)
+		}
+		results = append(results, result)
+	}
+	simulation.WriteMarkdownReport(os.Stdout, results)
+	return reportViolations(results)
+}
+
+func cmdBatch(args []string) int {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	sf := addScenarioFlags(flags)
+	runs := flags.Int("runs", 10, "number of seeds to run, starting from the scenario's seed")
+	out := flags.String("out", "results", "directory to save the results in")
+	lf := addLogFlags(flags, "off")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	scenario, err := sf.scenario()
+	if err != nil {
+		return usageError(err)
+	}
+	if _, err := lf.config(); err != nil {
+		return usageError(err)
+	}
+	if *runs <= 0 {
+		return usageError(fmt.Errorf("runs must be positive"))
+	}
+	return runAll(simulation.BatchScenarios(scenario, *runs), filepath.Join(*out, scenario.Name), lf)
+}
+
+func cmdSweep(args []string) int {
+	flags := flag.NewFlagSet("sweep", flag.ContinueOnError)
+	sf := addScenarioFlags(flags)
+	var params listFlags
+	flags.Var(&params, "param", "parameter to sweep, e.g. turns=8,12,16 (repeatable; populations are separated by ';')")
+	runs := flags.Int("runs", 1, "number of seeds to run for each combination of parameters")
+	out := flags.String("out", "results", "directory to save the results in")
+	lf := addLogFlags(flags, "off")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	scenario, err := sf.scenario()
+	if err != nil {
+		return usageError(err)
+	}
+	if _, err := lf.config(); err != nil {
+		return usageError(err)
+	}
+	if len(params) == 0 {
+		return usageError(fmt.Errorf("sweep needs at least one -param"))
+	}
+	parameters := []simulation.SweepParameter{}
+	for _, spec := range params {
+		parameter, err := simulation.ParseSweepParameter(spec)
+		if err != nil {
+			return usageError(err)
+		}
+		parameters = append(parameters, parameter)
+	}
+	scenarios, err := simulation.GridScenarios(scenario, parameters, *runs)
+	if err != nil {
+		return usageError(err)
+	}
+	return runAll(scenarios, filepath.Join(*out, scenario.Name+"_sweep"), lf)
+}
+
+func cmdTournament(args []string) int {
+	flags := flag.NewFlagSet("tournament", flag.ContinueOnError)
+	sf := addScenarioFlags(flags)
+	strategies := flags.String("strategies", "", "comma separated strategies to enter (all registered strategies if empty)")
+	size := flags.Int("size", 2, "strategies per matchup (2 plays every pair head to head)")
+	perStrategy := flags.Int("agents-per-strategy", 3, "agents each strategy fields in a game")
+	games := flags.Int("games", 5, "seeded games per matchup, starting from the scenario's seed")
+	out := flags.String("out", "", "also write the markdown report to this file")
+	lf := addLogFlags(flags, "off")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	scenario, err := sf.scenario()
+	if err != nil {
+		return usageError(err)
+	}
+	if _, err := lf.config(); err != nil {
+		return usageError(err)
+	}
+	config := simulation.TournamentConfig{
+		Base:              scenario,
+		MatchupSize:       *size,
+		AgentsPerStrategy: *perStrategy,
+		Games:             *games,
+	}
+	if *strategies != "" {
+		config.Strategies = strings.Split(*strategies, ",")
+	} else {
+		config.Strategies = agents.StrategyNames()
+	}
+	if err := config.Validate(); err != nil {
+		return usageError(err)
+	}
+
+	closeLog := lf.setup()
+	defer closeLog()
+
+	result, err := simulation.RunTournament(config, func(game int, total int, scenario simulation.Scenario) {
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", game, total, scenario.Name)
+	})
+	if err != nil {
+		return runError(err)
+	}
+	simulation.WriteTournamentReport(os.Stdout, result)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return runError(err)
+		}
+		defer f.Close()
+		simulation.WriteTournamentReport(f, result)
+	}
+	if len(result.Violations()) > 0 {
+		return exitInvariantFailure
+	}
+	return exitOK
+}
+
+func cmdReplay(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	logPath := flags.String("log", "", "event log written by 'run -event-log'")
+	eventTypes := flags.String("events", "", "only show these event types, e.g. Audit,Death")
+	agentPrefix := flags.String("agent", "", "only show events involving this agent (ID prefix)")
+	htmlDir := flags.String("html", "", "regenerate the playback charts into this directory")
+	quiet := flags.Bool("quiet", false, "do not print the timeline")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *logPath == "" && flags.NArg() == 1 {
+		*logPath = flags.Arg(0)
+	}
+	if *logPath == "" {
+		return usageError(fmt.Errorf("replay needs an event log"))
+	}
+
+	f, err := os.Open(*logPath)
+	if err != nil {
+		return runError(err)
+	}
+	defer f.Close()
+	events, err := envServer.ReadEventLog(f)
+	if err != nil {
+		return runError(err)
+	}
+
+	// Replay the events through a fresh bus, with the same checks as a live game
+	bus := envServer.NewEventBus()
+	checker := simulation.NewInvariantChecker()
+	checker.Attach(bus)
+	timelineFilter := envServer.NewBreakpoint()
+	timelineFilter.AgentPrefix = *agentPrefix
+	shownTypes := map[envServer.EventType]bool{}
+	for _, eventType := range strings.Split(*eventTypes, ",") {
+		if eventType != "" {
+			shownTypes[envServer.EventType(eventType)] = true
+		}
+	}
+	recorder := gameRecorder.CreateRecorder()
+	envServer.AttachDataRecorder(bus, recorder)
+	if !*quiet {
+		bus.SubscribeAll(func(event envServer.Event) {
+			if len(shownTypes) > 0 && !shownTypes[event.Type()] {
+				return
+			}
+			if !timelineFilter.Matches(event) {
+				return
+			}
+			when := event.When()
+			fmt.Printf("%3d %3d  %-16s %s\n", when.Iteration, when.Turn, event.Type(), envServer.DescribeEvent(event))
+		})
+	}
+	for _, event := range events {
+		bus.Publish(event)
+	}
+
+	summary := simulation.SummariseEvents(events)
+	fmt.Printf("\n%d events, %d turns, %d/%d agents survived, %d deaths, %d audits (%d failed)\n",
+		len(events), summary.TurnsPlayed, summary.Survivors, summary.Agents, summary.Deaths, summary.Audits, summary.FailedAudits)
+
+	if *htmlDir != "" {
+		gameRecorder.CreatePlaybackHTMLIn(recorder, *htmlDir)
+	}
+
+	result := &simulation.Result{Scenario: simulation.Scenario{Name: *logPath}, Violations: checker.Violations}
+	return reportViolations([]*simulation.Result{result})
+}
+
+func cmdReport(args []string) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	format := flags.String("format", "markdown", "markdown or html")
+	out := flags.String("out", "", "output file for markdown (stdout if empty), output directory for html")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		return usageError(fmt.Errorf("report needs result files or directories"))
+	}
+	results, err := simulation.LoadResults(flags.Args())
+	if err != nil {
+		return runError(err)
+	}
+
+	switch *format {
+	case "markdown":
+		w := os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return runError(err)
+			}
+			defer f.Close()
+			w = f
+		}
+		simulation.WriteMarkdownReport(w, results)
+	case "html":
+		if *out == "" {
+			*out = "report"
+		}
+		if err := simulation.WriteHTMLReport(*out, results); err != nil {
+			return runError(err)
+		}
+		fmt.Printf("report written to %s\n", filepath.Join(*out, "index.html"))
+	default:
+		return usageError(fmt.Errorf("unknown report format %q", *format))
+	}
+	return exitOK
+}
+
+func cmdList(args []string) int {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	fmt.Println("agent strategies:")
+	for _, strategy := range agents.Strategies() {
+		team := "-"
+		if strategy.SomasTeam != 0 {
+			team = fmt.Sprintf("team %d", strategy.SomasTeam)
+		}
+		fmt.Printf("  %-12s %-7s %s\n", strategy.Name, team, strategy.Description)
+		if len(strategy.Params) > 0 {
+			fmt.Printf("  %-12s %-7s params: %s\n", "", "", strategy.Params)
+		}
+	}
+
+	fmt.Println("\nAoAs (chosen by team vote on AoA ID):")
+	for _, aoa := range common.AoAs() {
+		fmt.Printf("  %-2d %-8s %s\n", aoa.ID, aoa.Name, aoa.Description)
+		if len(aoa.Params) > 0 {
+			fmt.Printf("  %-2s %-8s params: %s\n", "", "", aoa.Params)
+		}
+	}
+	fmt.Printf("  *  any other ID falls back to AoA %d\n", common.DefaultAoAID)
+
+	fmt.Println("\nAoA rules (components of composable AoAs):")
+	listComponents(common.ContributionRules)
+	listComponents(common.WithdrawalRules)
+	listComponents(common.AuditTriggerRules)
+	listComponents(common.AuditCostRules)
+	listComponents(common.WithdrawalOrderRules)
+	listComponents(common.InfractionRules)
+	listComponents(common.VerdictRules)
+	listComponents(common.SanctionRules)
+
+	fmt.Println("\nvoting rules (for the AoA vote):")
+	for _, rule := range voting.Rules() {
+		fmt.Printf("  %-10s %s\n", rule.Name, rule.Description)
+	}
+
+	fmt.Println("\nthreshold policies:")
+	for _, policy := range envServer.ThresholdPolicies() {
+		fmt.Printf("  %-8s %s\n", policy.Name, policy.Description)
+	}
+
+	fmt.Println("\nelection methods (for team roles):")
+	for _, method := range envServer.ElectionMethods() {
+		fmt.Printf("  %-12s %s\n", method.Name, method.Description)
+	}
+
+	fmt.Println("\nevolution policies:")
+	for _, policy := range envServer.EvolutionPolicies() {
+		fmt.Printf("  %-10s %s\n", policy.Name, policy.Description)
+	}
+	return exitOK
+}
+
+/*
+* Check AoA definition files (see common.AoADefinition) and show the rules of
+* each with all their parameters. Scenarios load the files with aoaFiles.
+ */
+func cmdAoA(args []string) int {
+	flags := flag.NewFlagSet("aoa", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		return usageError(fmt.Errorf("aoa needs AoA definition files"))
+	}
+	for _, path := range flags.Args() {
+		info, err := common.LoadAoAFile(path)
+		if err != nil {
+			return usageError(err)
+		}
+		fmt.Printf("%s: AoA %d (%s) %s\n", path, info.ID, info.Name, info.Description)
+		fmt.Printf("  params: %s\n", info.Params)
+	}
+	return exitOK
+}
+
+func listComponents[T any](library *common.ComponentLibrary[T]) {
+	fmt.Printf("  %s:\n", library.Kind())
+	for _, component := range library.Components() {
+		fmt.Printf("    %-11s %s\n", component.Name, component.Description)
+		if len(component.Params) > 0 {
+			fmt.Printf("    %-11s params: %s\n", "", component.Params)
+		}
+	}
+}
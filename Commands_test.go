@@ -0,0 +1,98 @@
+package main
+
+/*
+* Code to test the subcommands: their flags and the exit codes they return.
+ */
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/ADimoska/SOMASExtended/simulation"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Run the test from an empty directory, since games write their logs and charts to the working directory
+func inTempDir(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+// a short game that still plays a few turns
+var quickGame = []string{"-iterations", "1", "-turns", "2", "-turnTimeoutMs", "10", "-agents", "honest=3", "-log-level", "off"}
+
+func TestScenarioFlagsOverrideDefaults(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	sf := addScenarioFlags(flags)
+	assert.NoError(t, flags.Parse([]string{"-turns", "7", "-agents", "honest=2,liar(declare=1)=1"}))
+	scenario, err := sf.scenario()
+	assert.NoError(t, err)
+	assert.Equal(t, 7, scenario.Turns)
+	assert.Equal(t, simulation.DefaultScenario().Iterations, scenario.Iterations)
+	assert.Equal(t, []simulation.PopulationEntry{
+		{Agent: "honest", Count: 2},
+		{Agent: "liar", Count: 1, Params: common.StrategyParams{"declare": 1}},
+	}, scenario.Population)
+
+	flags = flag.NewFlagSet("test", flag.ContinueOnError)
+	sf = addScenarioFlags(flags)
+	assert.NoError(t, flags.Parse([]string{"-turns", "many"}))
+	_, err = sf.scenario()
+	assert.Error(t, err)
+}
+
+func TestCommandExitCodes(t *testing.T) {
+	dir := inTempDir(t)
+	tests := []struct {
+		name    string
+		command func([]string) int
+		args    []string
+		want    int
+	}{
+		{name: "run", command: cmdRun, args: quickGame, want: exitOK},
+		{name: "run with an unknown flag", command: cmdRun, args: []string{"-colour", "red"}, want: exitUsage},
+		{name: "run with a bad override", command: cmdRun, args: []string{"-turns", "many"}, want: exitUsage},
+		{name: "run with an unknown agent", command: cmdRun, args: []string{"-agents", "nobody=2"}, want: exitUsage},
+		{name: "run with a missing scenario", command: cmdRun, args: []string{"-scenario", "missing.json"}, want: exitUsage},
+		{name: "run with an unwritable event log", command: cmdRun, args: append([]string{"-event-log", filepath.Join(dir, "missing", "events.jsonl")}, quickGame...), want: exitError},
+		{name: "batch of no runs", command: cmdBatch, args: []string{"-runs", "0"}, want: exitUsage},
+		{name: "sweep without parameters", command: cmdSweep, args: []string{}, want: exitUsage},
+		{name: "sweep with a bad parameter", command: cmdSweep, args: []string{"-param", "turns"}, want: exitUsage},
+		{name: "sweep with a bad value", command: cmdSweep, args: []string{"-param", "turns=8,many"}, want: exitUsage},
+		{name: "sweep", command: cmdSweep, args: append([]string{"-param", "turns=1,2", "-out", "results"}, quickGame...), want: exitOK},
+		{name: "replay without a log", command: cmdReplay, args: []string{}, want: exitUsage},
+		{name: "replay of a missing log", command: cmdReplay, args: []string{"missing.jsonl"}, want: exitError},
+		{name: "report without results", command: cmdReport, args: []string{}, want: exitUsage},
+		{name: "report in an unknown format", command: cmdReport, args: []string{"-format", "pdf", "results"}, want: exitUsage},
+		{name: "list", command: cmdList, args: []string{}, want: exitOK},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, test.command(test.args), test.name)
+	}
+}
+
+// A game replays cleanly from its event log, and a log breaking an invariant fails the replay
+func TestReplayExitCodes(t *testing.T) {
+	dir := inTempDir(t)
+	clean := filepath.Join(dir, "clean.jsonl")
+	assert.Equal(t, exitOK, cmdRun(append([]string{"-event-log", clean}, quickGame...)))
+	assert.Equal(t, exitOK, cmdReplay([]string{"-quiet", clean}))
+
+	broken := filepath.Join(dir, "broken.jsonl")
+	f, err := os.Create(broken)
+	assert.NoError(t, err)
+	write := envServer.NewEventLogWriter(f)
+	// an agent contributes to a team it was never in
+	write(envServer.ContributionEvent{EventContext: envServer.EventContext{Turn: 1}, TeamID: uuid.New(), AgentID: uuid.New()})
+	f.Close()
+	assert.Equal(t, exitInvariantFailure, cmdReplay([]string{"-quiet", broken}))
+}
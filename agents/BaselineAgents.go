@@ -0,0 +1,330 @@
+package agents
+
+import (
+	"math"
+	"math/rand"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+
+	"github.com/MattSScott/basePlatformSOMAS/v2/pkg/agent"
+	"github.com/google/uuid"
+)
+
+/*
+* Baseline agents: simple, parameterised behaviours to benchmark AoAs against.
+* They all share BaselineAgent, which decides how much of what the AoA expects
+* to actually contribute and withdraw, and whether to lie about it. The plain
+* baselines (honest, free-rider, liar, greedy) are parameterisations of it,
+* while tit-for-tat, vigilante and survivor replace one of its hooks.
+*
+* Decisions are taken once per turn and remembered, so the repeated calls the
+* server makes in a turn (actual, stated, broadcast, recording) agree.
+ */
+
+type BaselineAgent struct {
+	*ExtendedAgent
+
+	// hooks replaced by the specialised baselines
+	contributionShare func() float64                   // fraction of the expected contribution actually paid
+	withdrawalAmount  func(expected int, pool int) int // amount to try to withdraw
+	auditVote         func(withdrawal bool) common.Vote
+
+	decisions turnDecisions
+}
+
+type turnDecisions struct {
+	iteration, turn int
+	lying           bool
+
+	contributionDecided bool
+	contribution        int
+	statedContribution  int
+
+	withdrawalDecided bool
+	withdrawal        int
+	statedWithdrawal  int
+}
+
+func newBaselineAgent(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config AgentConfig, params common.StrategyParams) *BaselineAgent {
+	b := &BaselineAgent{
+		ExtendedAgent: GetBaseAgents(funcs, config),
+	}
+	b.StrategyParams = params
+	b.decisions.iteration = -1
+	b.contributionShare = func() float64 { return b.param("share", 1) }
+	b.withdrawalAmount = func(expected int, pool int) int {
+		return int(math.Round(float64(expected) * b.param("greed", 1)))
+	}
+	b.auditVote = func(bool) common.Vote { return common.CreateVote(0, b.GetID(), uuid.Nil) }
+	return b
+}
+
+func (b *BaselineAgent) param(key string, fallback float64) float64 {
+	return b.StrategyParams.Get(key, fallback)
+}
+
+// The decisions of the current turn, starting afresh when the turn changes
+func (b *BaselineAgent) turn() *turnDecisions {
+	iteration, turn := b.Server.GetIterationNumber(), b.Server.GetTurnNumber()
+	if b.decisions.iteration != iteration || b.decisions.turn != turn {
+		b.decisions = turnDecisions{
+			iteration: iteration,
+			turn:      turn,
+			lying:     rand.Float64() < b.param("lieRate", 0),
+		}
+	}
+	return &b.decisions
+}
+
+func (b *BaselineAgent) team() *common.Team {
+	if !b.HasTeam() {
+		return nil
+	}
+	return b.Server.GetTeam(b.GetID())
+}
+
+// What the team's AoA expects the agent to contribute, capped at its score
+func (b *BaselineAgent) expectedContribution() int {
+	team := b.team()
+	if team == nil {
+		return 0
+	}
+	return min(team.TeamAoA.GetExpectedContribution(b.GetID(), b.GetTrueScore()), b.GetTrueScore())
+}
+
+func (b *BaselineAgent) decideContribution() *turnDecisions {
+	decisions := b.turn()
+	if decisions.contributionDecided {
+		return decisions
+	}
+	expected := b.expectedContribution()
+	share := math.Max(0, b.contributionShare())
+	actual := min(int(math.Round(float64(expected)*share)), b.GetTrueScore())
+	decisions.contribution, decisions.statedContribution = actual, actual
+	if decisions.lying {
+		decisions.statedContribution = max(expected, actual)
+	}
+	decisions.contributionDecided = true
+	b.Logger.Debug("Decided contribution", "expected", expected, "actual", actual, "stated", decisions.statedContribution)
+	return decisions
+}
+
+func (b *BaselineAgent) decideWithdrawal() *turnDecisions {
+	decisions := b.turn()
+	if decisions.withdrawalDecided {
+		return decisions
+	}
+	team := b.team()
+	if team == nil {
+		decisions.withdrawalDecided = true
+		return decisions
+	}
+	pool := team.GetCommonPool()
+	expected := team.TeamAoA.GetExpectedWithdrawal(b.GetID(), b.GetTrueScore(), pool)
+	actual := max(0, min(b.withdrawalAmount(expected, pool), pool))
+	decisions.withdrawal, decisions.statedWithdrawal = actual, actual
+	if decisions.lying {
+		decisions.statedWithdrawal = min(expected, actual)
+	}
+	decisions.withdrawalDecided = true
+	b.Logger.Debug("Decided withdrawal", "expected", expected, "actual", actual, "stated", decisions.statedWithdrawal, "pool", pool)
+	return decisions
+}
+
+func (b *BaselineAgent) GetActualContribution(instance common.IExtendedAgent) int {
+	return b.decideContribution().contribution
+}
+
+func (b *BaselineAgent) GetStatedContribution(instance common.IExtendedAgent) int {
+	return b.decideContribution().statedContribution
+}
+
+// A lying agent declares only "declare" of its turn score, which matters to AoAs basing contributions on it
+func (b *BaselineAgent) GetStatedTurnScore(instance common.IExtendedAgent) int {
+	if !b.turn().lying {
+		return b.TurnScore
+	}
+	return int(math.Round(float64(b.TurnScore) * math.Max(0, b.param("declare", 1))))
+}
+
+func (b *BaselineAgent) GetActualWithdrawal(instance common.IExtendedAgent) int {
+	return b.decideWithdrawal().withdrawal
+}
+
+func (b *BaselineAgent) GetStatedWithdrawal(instance common.IExtendedAgent) int {
+	return b.decideWithdrawal().statedWithdrawal
+}
+
+// Tell the team what the AoA expected as well, so teammates can judge the statement
+func (b *BaselineAgent) StateContributionToTeam(instance common.IExtendedAgent) {
+	msg := b.CreateContributionMessage(instance.GetStatedContribution(instance))
+	msg.ExpectedAmount = b.expectedContribution()
+	b.BroadcastSyncMessageToTeam(msg)
+}
+
+// Teammates stating less than "accuseBelow" of what they were expected to contribute are accused
+func (b *BaselineAgent) HandleContributionMessage(msg *common.ContributionMessage) {
+	b.ExtendedAgent.HandleContributionMessage(msg)
+	cooperation, _ := b.Memory.Cooperation(msg.GetSender())
+	if cooperation < b.param("accuseBelow", 0) && b.HasTeam() {
+		evidence := &common.AccusationEvidence{StatedAmount: msg.StatedAmount, ExpectedAmount: msg.ExpectedAmount}
+		if err := b.Accuse(msg.GetSender(), common.ContributionAudit, evidence); err != nil {
+			b.Logger.Debug("Could not accuse teammate", "accused", msg.GetSender(), "error", err)
+		}
+	}
+}
+
+func (b *BaselineAgent) GetContributionAuditVote() common.Vote {
+	return b.auditVote(false)
+}
+
+func (b *BaselineAgent) GetWithdrawalAuditVote() common.Vote {
+	return b.auditVote(true)
+}
+
+// The other members of the agent's team
+func (b *BaselineAgent) teammates() []uuid.UUID {
+	teammates := []uuid.UUID{}
+	if !b.HasTeam() {
+		return teammates
+	}
+	for _, id := range b.Server.GetAgentsInTeam(b.TeamID) {
+		if id != b.GetID() {
+			teammates = append(teammates, id)
+		}
+	}
+	return teammates
+}
+
+// ----------------------- Specialised baselines -----------------------
+
+/*
+* Tit-for-tat: contributes the share of its expected contribution that its
+* teammates stated they contributed, on average. With probability
+* "forgiveness" it cooperates fully anyway, which breaks cycles of defection.
+ */
+func newTitForTatAgent(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config AgentConfig, params common.StrategyParams) common.IExtendedAgent {
+	b := newBaselineAgent(funcs, config, params)
+	b.contributionShare = func() float64 {
+		if rand.Float64() < b.param("forgiveness", 0.1) {
+			return 1
+		}
+		total, observed := 0.0, 0
+		for _, id := range b.teammates() {
+			if cooperation, ok := b.Memory.Cooperation(id); ok {
+				total += cooperation
+				observed++
+			}
+		}
+		if observed == 0 {
+			return 1 // cooperate until there is something to retaliate against
+		}
+		return math.Min(1, total/float64(observed))
+	}
+	return b
+}
+
+/*
+* Vigilante auditor: an honest agent that, with probability "auditRate",
+* votes to audit the teammate that looks the most suspicious: the lowest
+* stated cooperation for contributions, the largest stated withdrawal for
+* withdrawals. It also accuses teammates that state they contributed less
+* than "accuseBelow" of what was expected of them.
+ */
+func newVigilanteAgent(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config AgentConfig, params common.StrategyParams) common.IExtendedAgent {
+	b := newBaselineAgent(funcs, config, params)
+	b.auditVote = func(withdrawal bool) common.Vote {
+		teammates := b.teammates()
+		if len(teammates) == 0 || rand.Float64() >= b.param("auditRate", 1) {
+			return common.CreateVote(0, b.GetID(), uuid.Nil)
+		}
+		suspect, suspicion := teammates[rand.Intn(len(teammates))], math.Inf(-1)
+		for _, id := range teammates {
+			cooperation, seenContribution := b.Memory.Cooperation(id)
+			withdrawn, seenWithdrawal := b.Memory.LastWithdrawal(id)
+			score, seen := -cooperation, seenContribution
+			if withdrawal {
+				score, seen = float64(withdrawn), seenWithdrawal
+			}
+			if seen && score > suspicion {
+				suspect, suspicion = id, score
+			}
+		}
+		vote := common.CreateVote(1, b.GetID(), suspect)
+		vote.AuditDuration = int(b.param("auditDuration", 3))
+		return vote
+	}
+	return b
+}
+
+/*
+* Threshold-aware survivor: keeps its score above the threshold it believes
+* in ("threshold", plus a safety "margin"). It only contributes its surplus
+* above that line, and withdraws extra to make up any shortfall.
+ */
+func newSurvivorAgent(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config AgentConfig, params common.StrategyParams) common.IExtendedAgent {
+	b := newBaselineAgent(funcs, config, params)
+	safeScore := func() int { return int(b.param("threshold", 19) + b.param("margin", 2)) }
+	b.contributionShare = func() float64 {
+		expected := b.expectedContribution()
+		surplus := b.GetTrueScore() - safeScore()
+		if surplus <= 0 || expected == 0 {
+			return 0
+		}
+		return math.Min(1, float64(surplus)/float64(expected))
+	}
+	b.withdrawalAmount = func(expected int, pool int) int {
+		return expected + max(0, safeScore()-b.GetTrueScore())
+	}
+	return b
+}
+
+func init() {
+	baseline := func(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config AgentConfig, params common.StrategyParams) common.IExtendedAgent {
+		return newBaselineAgent(funcs, config, params)
+	}
+	for _, info := range []StrategyInfo{
+		{
+			Name:        "honest",
+			Description: "always contributes and withdraws exactly what its AoA expects, and says so",
+			Params:      common.StrategyParams{"share": 1, "greed": 1},
+		},
+		{
+			Name:        "free-rider",
+			Description: "contributes a fraction (share) of what is expected but withdraws in full; honest about it",
+			Params:      common.StrategyParams{"share": 0, "greed": 1},
+		},
+		{
+			Name:        "liar",
+			Description: "with probability lieRate under-contributes and over-withdraws while stating the expected amounts, and declares a share (declare) of its turn score",
+			Params:      common.StrategyParams{"share": 0.5, "greed": 1.5, "lieRate": 1, "declare": 0.5},
+		},
+		{
+			Name:        "greedy",
+			Description: "contributes in full but withdraws greed times what is expected; honest about it",
+			Params:      common.StrategyParams{"share": 1, "greed": 3},
+		},
+	} {
+		info.Constructor = baseline
+		RegisterStrategy(info)
+	}
+
+	RegisterStrategy(StrategyInfo{
+		Name:        "tit-for-tat",
+		Description: "contributes as much as its teammates say they do, forgiving with probability forgiveness",
+		Params:      common.StrategyParams{"forgiveness": 0.1, "greed": 1},
+		Constructor: newTitForTatAgent,
+	})
+	RegisterStrategy(StrategyInfo{
+		Name:        "vigilante",
+		Description: "honest, votes to audit the most suspicious teammate with probability auditRate and accuses open under-contributors",
+		Params:      common.StrategyParams{"share": 1, "greed": 1, "auditRate": 1, "auditDuration": 3, "accuseBelow": 1},
+		Constructor: newVigilanteAgent,
+	})
+	RegisterStrategy(StrategyInfo{
+		Name:        "survivor",
+		Description: "keeps its score above threshold+margin, contributing only its surplus",
+		Params:      common.StrategyParams{"threshold": 19, "margin": 2},
+		Constructor: newSurvivorAgent,
+	})
+}
@@ -1,12 +1,14 @@
 package agents
 
 import (
-	"log"
+	"log/slog"
 	"math/rand"
+	"sort"
 
 	"github.com/google/uuid"
 
 	gameRecorder "github.com/ADimoska/SOMASExtended/gameRecorder"
+	"github.com/ADimoska/SOMASExtended/logging"
 
 	common "github.com/ADimoska/SOMASExtended/common"
 
@@ -25,8 +27,15 @@ type ExtendedAgent struct {
 	// private
 	LastScore int
 
-	// debug
-	VerboseLevel int
+	// what the agent rolled in the current turn, and how many times it rolled again
+	TurnScore int
+	Rerolls   int
+
+	// debug: logs of the agent component, tagged with the agent's ID
+	Logger *slog.Logger
+
+	// what the agent has seen of its teammates, filled in by the message handlers
+	Memory *TeammateMemory
 
 	// AoA vote
 	AoARanking []int
@@ -41,21 +50,36 @@ type ExtendedAgent struct {
 
 	// for recording purpose
 	TrueSomasTeamID int // your true team id! e.g. team 4 -> 4. Override this in your agent constructor
+
+	// the registered strategy the agent was created from (see Strategies.go)
+	StrategyName   string
+	StrategyParams common.StrategyParams
+}
+
+func init() {
+	RegisterStrategy(StrategyInfo{
+		Name:        "base",
+		Description: "the default agent: contributes and withdraws what its AoA expects",
+		Constructor: func(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config AgentConfig, params common.StrategyParams) common.IExtendedAgent {
+			return GetBaseAgents(funcs, config)
+		},
+	})
 }
 
 type AgentConfig struct {
-	InitScore    int
-	VerboseLevel int
+	InitScore int
 }
 
 func GetBaseAgents(funcs agent.IExposedServerFunctions[common.IExtendedAgent], configParam AgentConfig) *ExtendedAgent {
+	baseAgent := agent.CreateBaseAgent(funcs)
 	return &ExtendedAgent{
-		BaseAgent:    agent.CreateBaseAgent(funcs),
-		Server:       funcs.(common.IServer), // Type assert the server functions to IServer interface
-		Score:        configParam.InitScore,
-		VerboseLevel: configParam.VerboseLevel,
-		AoARanking:   []int{0},
-		TeamRanking:  []uuid.UUID{},
+		BaseAgent:   baseAgent,
+		Server:      funcs.(common.IServer), // Type assert the server functions to IServer interface
+		Score:       configParam.InitScore,
+		Logger:      logging.For(logging.ComponentAgent).With("agent", baseAgent.GetID()),
+		AoARanking:  []int{0},
+		TeamRanking: []uuid.UUID{},
+		Memory:      NewTeammateMemory(0),
 	}
 }
 
@@ -82,6 +106,20 @@ func (mi *ExtendedAgent) GetTrueSomasTeamID() int {
 	return mi.TrueSomasTeamID
 }
 
+// Get the name of the registered strategy the agent was created from
+func (mi *ExtendedAgent) GetStrategyName() string {
+	return mi.StrategyName
+}
+
+func (mi *ExtendedAgent) GetStrategyParams() common.StrategyParams {
+	return mi.StrategyParams
+}
+
+func (mi *ExtendedAgent) SetStrategy(name string, params common.StrategyParams) {
+	mi.StrategyName = name
+	mi.StrategyParams = params
+}
+
 // Setter for the server to call, in order to set the true score for this agent
 func (mi *ExtendedAgent) SetTrueScore(score int) {
 	mi.Score = score
@@ -89,11 +127,10 @@ func (mi *ExtendedAgent) SetTrueScore(score int) {
 
 // custom function: ask for rolling the dice
 func (mi *ExtendedAgent) StartRollingDice(instance common.IExtendedAgent) {
-	if mi.VerboseLevel > 10 {
-		log.Printf("%s is rolling the Dice\n", mi.GetID())
-	}
-	if mi.VerboseLevel > 9 {
-		log.Println("---------------------")
+	logging.Trace(mi.Logger, "Rolling the dice")
+	// teammates may have left since the last turn, and the server does not tell the agent
+	if mi.HasTeam() {
+		mi.Memory.ForgetAllBut(mi.Server.GetAgentsInTeam(mi.TeamID))
 	}
 	// TODO: implement the logic in environment, do a random of 3d6 now with 50% chance to stick
 	mi.LastScore = -1
@@ -119,9 +156,7 @@ func (mi *ExtendedAgent) StartRollingDice(instance common.IExtendedAgent) {
 			mi.DecideRollAgain() //used just for debugging
 		} else {
 			// burst, lose all turn score
-			if mi.VerboseLevel > 4 {
-				log.Printf("%s **BURSTED!** round: %v, current score: %v\n", mi.GetID(), rounds, currentScore)
-			}
+			mi.Logger.Debug("Bursted", "round", rounds, "roll", currentScore)
 			turnScore = 0
 			break
 		}
@@ -131,32 +166,25 @@ func (mi *ExtendedAgent) StartRollingDice(instance common.IExtendedAgent) {
 
 	// add turn score to total score
 	mi.Score += turnScore
+	mi.TurnScore = turnScore
+	mi.Rerolls = rounds - 1
 
-	if mi.VerboseLevel > 4 {
-		log.Printf("%s's turn score: %v, total score: %v\n", mi.GetID(), turnScore, mi.Score)
-	}
+	mi.Logger.Debug("Finished rolling", "turnScore", turnScore, "score", mi.Score)
 }
 
 // stick or again
 func (mi *ExtendedAgent) StickOrAgain(accumulatedScore int, prevRoll int) bool {
-	// if mi.verboseLevel > 8 {
-	// 	log.Printf("%s is deciding to stick or again\n", mi.GetID())
-	// }
 	return rand.Intn(2) == 0
 }
 
 // decide to stick
 func (mi *ExtendedAgent) DecideStick() {
-	if mi.VerboseLevel > 6 {
-		log.Printf("%s decides to [STICK], last score: %v\n", mi.GetID(), mi.LastScore)
-	}
+	logging.Trace(mi.Logger, "Decided to stick", "lastRoll", mi.LastScore)
 }
 
 // decide to roll again
 func (mi *ExtendedAgent) DecideRollAgain() {
-	if mi.VerboseLevel > 6 {
-		log.Printf("%s decides to ROLL AGAIN, last score: %v\n", mi.GetID(), mi.LastScore)
-	}
+	logging.Trace(mi.Logger, "Decided to roll again", "lastRoll", mi.LastScore)
 }
 
 // TODO: TO BE IMPLEMENTED BY TEAM'S AGENT
@@ -168,14 +196,10 @@ func (mi *ExtendedAgent) GetActualContribution(instance common.IExtendedAgent) i
 		if mi.GetTrueScore() < contribution {
 			contribution = mi.GetTrueScore() // give all score if less than expected
 		}
-		if mi.VerboseLevel > 6 {
-			log.Printf("%s is contributing %d to the common pool and thinks the common pool size is %d\n", mi.GetID(), contribution, mi.Server.GetTeam(mi.GetID()).GetCommonPool())
-		}
+		mi.Logger.Debug("Contributing to the common pool", "team", mi.TeamID, "amount", contribution)
 		return contribution
 	} else {
-		if mi.VerboseLevel > 6 {
-			log.Printf("%s has no team, skipping contribution\n", mi.GetID())
-		}
+		mi.Logger.Debug("No team, skipping contribution")
 		return 0
 	}
 }
@@ -194,6 +218,12 @@ func (mi *ExtendedAgent) GetStatedContribution(instance common.IExtendedAgent) i
 	return statedContribution
 }
 
+// get the turn score the agent declares to its team after rolling
+// This function MUST return the same value when called multiple times in the same turn
+func (mi *ExtendedAgent) GetStatedTurnScore(instance common.IExtendedAgent) int {
+	return mi.TurnScore
+}
+
 // make withdrawal from common pool
 func (mi *ExtendedAgent) GetActualWithdrawal(instance common.IExtendedAgent) int {
 	// first check if the agent has a team
@@ -205,7 +235,7 @@ func (mi *ExtendedAgent) GetActualWithdrawal(instance common.IExtendedAgent) int
 	if commonPool < withdrawal {
 		withdrawal = commonPool
 	}
-	log.Printf("%s is withdrawing %d from the common pool of size %d\n", mi.GetID(), withdrawal, commonPool)
+	mi.Logger.Debug("Withdrawing from the common pool", "team", mi.TeamID, "amount", withdrawal, "pool", commonPool)
 	return withdrawal
 }
 
@@ -232,7 +262,7 @@ func (mi *ExtendedAgent) StickOrAgainFor(agentId uuid.UUID, accumulatedScore int
 
 // dev function
 func (mi *ExtendedAgent) LogSelfInfo() {
-	log.Printf("[Agent %s] score: %v\n", mi.GetID(), mi.Score)
+	mi.Logger.Info("Agent status", "team", mi.TeamID, "score", mi.Score)
 }
 
 // Agent returns their preference for an audit on contribution
@@ -251,6 +281,7 @@ func (mi *ExtendedAgent) GetWithdrawalAuditVote() common.Vote {
 	return common.CreateVote(0, mi.GetID(), uuid.Nil)
 }
 
+// Memory learns of audits from HandleAuditVerdictMessage, which tells the whole entry
 func (mi *ExtendedAgent) SetAgentContributionAuditResult(agentID uuid.UUID, result bool) {}
 
 func (mi *ExtendedAgent) SetAgentWithdrawalAuditResult(agentID uuid.UUID, result bool) {}
@@ -258,14 +289,11 @@ func (mi *ExtendedAgent) SetAgentWithdrawalAuditResult(agentID uuid.UUID, result
 // ----Withdrawal------- Messaging functions -----------------------
 
 func (mi *ExtendedAgent) HandleTeamFormationMessage(msg *common.TeamFormationMessage) {
-	log.Printf("Agent %s received team forming invitation from %s\n", mi.GetID(), msg.GetSender())
+	mi.Logger.Debug("Received team forming invitation", "sender", msg.GetSender())
 
 	// Already in a team - reject invitation
 	if mi.TeamID != (uuid.UUID{}) {
-		if mi.VerboseLevel > 6 {
-			log.Printf("Agent %s rejected invitation from %s - already in team %v\n",
-				mi.GetID(), msg.GetSender(), mi.TeamID)
-		}
+		mi.Logger.Debug("Rejected invitation, already in a team", "sender", msg.GetSender(), "team", mi.TeamID)
 		return
 	}
 
@@ -280,44 +308,67 @@ func (mi *ExtendedAgent) HandleTeamFormationMessage(msg *common.TeamFormationMes
 }
 
 func (mi *ExtendedAgent) HandleContributionMessage(msg *common.ContributionMessage) {
-	if mi.VerboseLevel > 8 {
-		log.Printf("Agent %s received contribution notification from %s: amount=%d\n",
-			mi.GetID(), msg.GetSender(), msg.StatedAmount)
-	}
-
-	// Team's agent should implement logic to store or process the reported contribution amount as desired
+	logging.Trace(mi.Logger, "Received contribution notification", "sender", msg.GetSender(), "amount", msg.StatedAmount)
+	mi.Memory.ObserveContribution(msg.GetSender(), msg.StatedAmount, msg.ExpectedAmount)
 }
 
 func (mi *ExtendedAgent) HandleScoreReportMessage(msg *common.ScoreReportMessage) {
-	if mi.VerboseLevel > 8 {
-		log.Printf("Agent %s received score report from %s: score=%d\n",
-			mi.GetID(), msg.GetSender(), msg.TurnScore)
-	}
-
-	// Team's agent should implement logic to store or process score of other agents as desired
+	logging.Trace(mi.Logger, "Received score report", "sender", msg.GetSender(), "score", msg.TurnScore)
+	mi.Memory.ObserveTurnScore(msg.GetSender(), msg.TurnScore)
 }
 
 func (mi *ExtendedAgent) HandleWithdrawalMessage(msg *common.WithdrawalMessage) {
-	if mi.VerboseLevel > 8 {
-		log.Printf("Agent %s received withdrawal notification from %s: amount=%d\n",
-			mi.GetID(), msg.GetSender(), msg.StatedAmount)
-	}
-
-	// Team's agent should implement logic to store or process the reported withdrawal amount as desired
+	logging.Trace(mi.Logger, "Received withdrawal notification", "sender", msg.GetSender(), "amount", msg.StatedAmount)
+	mi.Memory.ObserveWithdrawal(msg.GetSender(), msg.StatedAmount, msg.ExpectedAmount)
 }
 
 func (mi *ExtendedAgent) HandleAgentOpinionRequestMessage(msg *common.AgentOpinionRequestMessage) {
 	// Team's agent should implement logic to respond to opinion request as desired
-	log.Printf("Agent %s received opinion request from %s\n", mi.GetID(), msg.AgentID)
+	logging.Trace(mi.Logger, "Received opinion request", "sender", msg.GetSender(), "about", msg.AgentID)
 	opinion := 70
 	opinionResponseMsg := mi.CreateAgentOpinionResponseMessage(msg.AgentID, opinion)
-	log.Printf("Sending opinion response to %s\n", msg.AgentID)
 	mi.SendMessage(opinionResponseMsg, msg.AgentID) // Sent asynchronously, because this is "extra information"
 }
 
 func (mi *ExtendedAgent) HandleAgentOpinionResponseMessage(msg *common.AgentOpinionResponseMessage) {
-	// Team's agent should implement logic to store or process opinion response as desired
-	log.Printf("Agent %s received opinion response from %s: opinion=%d\n", mi.GetID(), msg.GetSender(), msg.AgentOpinion)
+	logging.Trace(mi.Logger, "Received opinion response", "sender", msg.GetSender(), "opinion", msg.AgentOpinion)
+	mi.Memory.ObserveOpinion(msg.GetSender(), msg.AgentID, msg.AgentOpinion)
+}
+
+func (mi *ExtendedAgent) HandleAuditVerdictMessage(msg *common.AuditVerdictMessage) {
+	// the whole history of the team is also available from the server with GetAuditHistory
+	logging.Trace(mi.Logger, "Received audit verdict", "audited", msg.Entry.AgentID, "phase", msg.Entry.Phase, "verdict", msg.Entry.Verdict)
+	if msg.Entry.AgentID != mi.GetID() {
+		mi.Memory.ObserveAudit(msg.Entry)
+	}
+}
+
+func (mi *ExtendedAgent) HandleAccusationMessage(msg *common.AccusationMessage) {
+	// Team's agent should implement logic to weigh teammates' accusations as desired; accusations
+	// that are not settled yet are also available from the server with GetAccusations
+	logging.Trace(mi.Logger, "Received accusation", "accuser", msg.Accusation.AccuserID, "accused", msg.Accusation.AccusedID, "phase", msg.Accusation.Phase)
+}
+
+/*
+* Accuse a teammate of cheating in a phase of this turn, with the evidence
+* if any: the accusation is filed with the server and told to the team.
+ */
+func (mi *ExtendedAgent) Accuse(accusedID uuid.UUID, phase common.AuditPhase, evidence *common.AccusationEvidence) error {
+	accusation := common.Accusation{
+		TeamID:    mi.TeamID,
+		AccuserID: mi.GetID(),
+		AccusedID: accusedID,
+		Iteration: mi.Server.GetIterationNumber(),
+		Turn:      mi.Server.GetTurnNumber(),
+		Phase:     phase,
+		Evidence:  evidence,
+	}
+	if err := mi.Server.FileAccusation(accusation); err != nil {
+		return err
+	}
+	mi.Logger.Debug("Accused teammate", "accused", accusedID, "phase", phase)
+	mi.BroadcastSyncMessageToTeam(mi.CreateAccusationMessage(accusation))
+	return nil
 }
 
 func (mi *ExtendedAgent) BroadcastSyncMessageToTeam(msg message.IMessage[common.IExtendedAgent]) {
@@ -330,6 +381,12 @@ func (mi *ExtendedAgent) BroadcastSyncMessageToTeam(msg message.IMessage[common.
 	}
 }
 
+func (mi *ExtendedAgent) StateScoreToTeam(instance common.IExtendedAgent) {
+	// Broadcast declared turn score to team
+	scoreReportMsg := mi.CreateScoreReportMessage(instance.GetStatedTurnScore(instance))
+	mi.BroadcastSyncMessageToTeam(scoreReportMsg)
+}
+
 func (mi *ExtendedAgent) StateContributionToTeam(instance common.IExtendedAgent) {
 	// Broadcast contribution to team
 	statedContribution := instance.GetStatedContribution(instance)
@@ -352,10 +409,11 @@ func (mi *ExtendedAgent) GetExposedInfo() common.ExposedAgentInfo {
 	}
 }
 
-func (mi *ExtendedAgent) CreateScoreReportMessage() *common.ScoreReportMessage {
+func (mi *ExtendedAgent) CreateScoreReportMessage(turnScore int) *common.ScoreReportMessage {
 	return &common.ScoreReportMessage{
 		BaseMessage: mi.CreateBaseMessage(),
-		TurnScore:   mi.LastScore,
+		TurnScore:   turnScore,
+		Rerolls:     mi.Rerolls,
 	}
 }
 
@@ -388,6 +446,13 @@ func (mi *ExtendedAgent) CreateAgentOpinionResponseMessage(agentID uuid.UUID, op
 	}
 }
 
+func (mi *ExtendedAgent) CreateAccusationMessage(accusation common.Accusation) *common.AccusationMessage {
+	return &common.AccusationMessage{
+		BaseMessage: mi.CreateBaseMessage(),
+		Accusation:  accusation,
+	}
+}
+
 // ----------------------- Debug functions -----------------------
 
 func Roll3Dice() int {
@@ -407,9 +472,7 @@ func Roll3Dice() int {
 // ----------------------- Team forming functions -----------------------
 func (mi *ExtendedAgent) StartTeamForming(instance common.IExtendedAgent, agentInfoList []common.ExposedAgentInfo) {
 	// TODO: implement team forming logic
-	if mi.VerboseLevel > 6 {
-		log.Printf("%s is starting team formation\n", mi.GetID())
-	}
+	mi.Logger.Debug("Starting team formation")
 
 	chosenAgents := instance.DecideTeamForming(agentInfoList)
 	mi.SendTeamFormingInvitation(chosenAgents)
@@ -446,42 +509,39 @@ func (mi *ExtendedAgent) SendTeamFormingInvitation(agentIDs []uuid.UUID) {
 			AgentInfo:   mi.GetExposedInfo(),
 			Message:     "Would you like to form a team?",
 		}
-		// Debug print to check message contents
-		log.Printf("Sending invitation: sender=%v, teamID=%v, receiver=%v\n", mi.GetID(), mi.GetTeamID(), agentID)
+		mi.Logger.Debug("Sending team forming invitation", "team", mi.GetTeamID(), "receiver", agentID)
 		mi.SendSynchronousMessage(invitationMsg, agentID)
 	}
 }
 
 func (mi *ExtendedAgent) createNewTeam(senderID uuid.UUID) {
-	log.Printf("Agent %s is creating a new team\n", mi.GetID())
+	mi.Logger.Debug("Creating a new team", "with", senderID)
 	teamIDs := []uuid.UUID{mi.GetID(), senderID}
 	newTeamID := mi.Server.CreateAndInitTeamWithAgents(teamIDs)
 
 	if newTeamID == (uuid.UUID{}) {
-		if mi.VerboseLevel > 6 {
-			log.Printf("Agent %s failed to create a new team\n", mi.GetID())
-		}
+		mi.Logger.Debug("Failed to create a new team", "with", senderID)
 		return
 	}
 
 	mi.TeamID = newTeamID
-	if mi.VerboseLevel > 6 {
-		log.Printf("Agent %s created a new team with ID %v\n", mi.GetID(), newTeamID)
-	}
+	mi.Logger.Debug("Created a new team", "team", newTeamID)
 }
 
 func (mi *ExtendedAgent) joinExistingTeam(teamID uuid.UUID) {
 	mi.TeamID = teamID
 	mi.Server.AddAgentToTeam(mi.GetID(), teamID)
-	if mi.VerboseLevel > 6 {
-		log.Printf("Agent %s joined team %v\n", mi.GetID(), teamID)
-	}
+	mi.Logger.Debug("Joined team", "team", teamID)
 }
 
 // SetTeamID assigns a new team ID to the agent
 // Parameters:
 //   - teamID: The UUID of the team to assign to this agent
 func (mi *ExtendedAgent) SetTeamID(teamID uuid.UUID) {
+	// what was remembered of the old team is of no use in the new one
+	if teamID != mi.TeamID {
+		mi.Memory.ForgetAllBut(nil)
+	}
 	// Store the previous team ID
 	mi.LastTeamID = mi.TeamID
 	mi.TeamID = teamID
@@ -512,6 +572,72 @@ func (mi *ExtendedAgent) VoteOnAgentEntry(candidateID uuid.UUID) bool {
 	return true
 }
 
+// Propose a change of the team's AoA during a turn, nil to propose nothing
+func (mi *ExtendedAgent) ProposeAoAAmendment(instance common.IExtendedAgent) *common.AoAAmendment {
+	return nil
+}
+
+/*
+* Vote for an amendment if it moves the team to an AoA ranked higher in the
+* agent's AoA ranking than the current one. Unranked AoAs come last.
+ */
+func (mi *ExtendedAgent) VoteOnAoAAmendment(amendment common.AoAAmendment) bool {
+	team := mi.Server.GetTeam(mi.GetID())
+	if team == nil {
+		return false
+	}
+	rank := func(aoaID int) int {
+		for i, ranked := range mi.AoARanking {
+			if ranked == aoaID {
+				return i
+			}
+		}
+		return len(mi.AoARanking)
+	}
+	return rank(amendment.AoAID) < rank(team.TeamAoAID)
+}
+
+// Abstain on ballots opened by teammates; return a ranking of option indices to vote
+func (mi *ExtendedAgent) VoteOnBallot(ballot common.Ballot) []int {
+	return nil
+}
+
+/*
+* Rank the candidates for any role by what the agent remembers of them: the
+* most trusted first (see TeammateMemory.Honesty), then those that stated
+* they contributed the most. The agent knows nothing of itself this way, so it
+* does not simply vote for itself.
+ */
+func (mi *ExtendedAgent) VoteForRole(role common.RoleSpec, candidates []uuid.UUID) []uuid.UUID {
+	ranking := append([]uuid.UUID{}, candidates...)
+	sort.SliceStable(ranking, func(i, j int) bool {
+		honestyI, honestyJ := mi.Memory.Honesty(ranking[i]), mi.Memory.Honesty(ranking[j])
+		if honestyI != honestyJ {
+			return honestyI > honestyJ
+		}
+		contributionI, _ := mi.Memory.AverageContribution(ranking[i])
+		contributionJ, _ := mi.Memory.AverageContribution(ranking[j])
+		return contributionI > contributionJ
+	})
+	return ranking
+}
+
+// Appeal whenever the sanction costs more than the fee to be heard
+func (mi *ExtendedAgent) AppealVerdict(entry common.AuditEntry, terms common.AppealTerms) bool {
+	sanction := entry.Sanction
+	return sanction.Expel || sanction.WithdrawalBan > 0 || sanction.VoteBan > 0 || sanction.Fine > terms.Fee
+}
+
+// Give the benefit of the doubt to agents the team has never convicted before
+func (mi *ExtendedAgent) VoteOnAppeal(entry common.AuditEntry) bool {
+	for _, previous := range mi.Server.GetAuditHistory(entry.TeamID) {
+		if previous.AgentID == entry.AgentID && previous.Verdict && !previous.Overturned {
+			return false
+		}
+	}
+	return true
+}
+
 // Return the team ranking
 func (mi *ExtendedAgent) GetTeamRanking() []uuid.UUID {
 	return mi.TeamRanking
@@ -536,23 +662,16 @@ func (mi *ExtendedAgent) RecordAgentStatus(instance common.IExtendedAgent) gameR
 		instance.GetStatedWithdrawal(instance),
 		instance.GetTeamID(),
 	)
+	record.Strategy = instance.GetStrategyName()
 	return record
 }
 
 // ----------------------- Team 1 AoA Functions -----------------------
 
-func (mi *ExtendedAgent) Team1_ChairUpdateRanks(currentRanking map[uuid.UUID]int) map[uuid.UUID]int {
-	// Chair iterates through existing rank map in team
-	// and gets the new ranks of the agents in the team
-	// according to AoA function
-	newRanking := make(map[uuid.UUID]int)
-	for agentUUID, _ := range currentRanking {
-		newRank := mi.Server.GetTeam(agentUUID).TeamAoA.(*common.Team1AoA).GetAgentNewRank(agentUUID)
-		newRanking[agentUUID] = newRank
-	}
-
-	// Returns a map of agent UUIDs to new Rank (int)
-	return newRanking
+func (mi *ExtendedAgent) Team1_ChairUpdateRanks(currentRanks map[uuid.UUID]int, guidelineRanks map[uuid.UUID]int) map[uuid.UUID]int {
+	// Default behaviour is to rank every member by the guideline,
+	// i.e. by their recent contributions against the rank boundaries
+	return guidelineRanks
 }
 
 func (mi *ExtendedAgent) Team1_VoteOnRankBoundaries(initialBoundaries [5]int) [5]int {
@@ -1,10 +1,10 @@
 package agents
 
 import (
-	"log"
 	"math/rand"
 
 	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/ADimoska/SOMASExtended/logging"
 
 	"github.com/MattSScott/basePlatformSOMAS/v2/pkg/agent"
 	"github.com/google/uuid"
@@ -14,6 +14,17 @@ type MI_256_v1 struct {
 	*ExtendedAgent
 }
 
+func init() {
+	RegisterStrategy(StrategyInfo{
+		Name:        "team4",
+		SomasTeam:   4,
+		Description: "Team 4's agent (MI_256_v1): always sticks, invites a random teamless agent",
+		Constructor: func(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config AgentConfig, params common.StrategyParams) common.IExtendedAgent {
+			return Team4_CreateAgent(funcs, config)
+		},
+	})
+}
+
 // constructor for MI_256_v1
 func Team4_CreateAgent(funcs agent.IExposedServerFunctions[common.IExtendedAgent], agentConfig AgentConfig) *MI_256_v1 {
 	mi_256 := &MI_256_v1{
@@ -26,7 +37,7 @@ func Team4_CreateAgent(funcs agent.IExposedServerFunctions[common.IExtendedAgent
 // ----------------------- Strategies -----------------------
 // Team-forming Strategy
 func (mi *MI_256_v1) DecideTeamForming(agentInfoList []common.ExposedAgentInfo) []uuid.UUID {
-	log.Printf("Called overriden DecideTeamForming\n")
+	logging.Trace(mi.Logger, "Called overriden DecideTeamForming")
 	invitationList := []uuid.UUID{}
 	for _, agentInfo := range agentInfoList {
 		// exclude the agent itself
@@ -49,7 +60,7 @@ func (mi *MI_256_v1) DecideTeamForming(agentInfoList []common.ExposedAgentInfo)
 
 // Dice Strategy
 func (mi *MI_256_v1) StickOrAgain(accumulatedScore int, prevRoll int) bool {
-	log.Printf("Called overriden StickOrAgain\n")
+	logging.Trace(mi.Logger, "Called overriden StickOrAgain")
 	// TODO: implement dice strategy
 	return true
 }
@@ -0,0 +1,98 @@
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+
+	"github.com/MattSScott/basePlatformSOMAS/v2/pkg/agent"
+)
+
+/*
+* Registry of agent strategies. Each strategy registers itself by name from an
+* init function in its own file, so populations can be built from names (in
+* scenario files, on the command line or in tests) without calling the
+* constructors directly.
+ */
+
+type StrategyConstructor func(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config AgentConfig, params common.StrategyParams) common.IExtendedAgent
+
+type StrategyInfo struct {
+	Name        string
+	SomasTeam   int // SOMAS team that wrote the strategy, 0 for the shared ones
+	Description string
+	Params      common.StrategyParams // parameters the strategy reads, with their defaults
+	Constructor StrategyConstructor
+}
+
+var strategies = map[string]StrategyInfo{}
+
+// Register a strategy. Registering the same name twice is a programming error.
+func RegisterStrategy(info StrategyInfo) {
+	if info.Name == "" || info.Constructor == nil {
+		panic("agents: a strategy needs a name and a constructor")
+	}
+	if _, exists := strategies[info.Name]; exists {
+		panic(fmt.Sprintf("agents: strategy %q registered twice", info.Name))
+	}
+	if info.Params == nil {
+		info.Params = common.StrategyParams{}
+	}
+	strategies[info.Name] = info
+}
+
+func LookupStrategy(name string) (StrategyInfo, bool) {
+	info, ok := strategies[name]
+	return info, ok
+}
+
+// Every registered strategy, sorted by name
+func Strategies() []StrategyInfo {
+	infos := make([]StrategyInfo, 0, len(strategies))
+	for _, info := range strategies {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func StrategyNames() []string {
+	names := []string{}
+	for _, info := range Strategies() {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+/*
+* Fill in the parameters of the named strategy: parameters that are not given
+* take the strategy's defaults, and parameters the strategy does not know are
+* an error (they are almost always typos).
+ */
+func ResolveStrategyParams(name string, params common.StrategyParams) (common.StrategyParams, error) {
+	info, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown agent strategy %q (known strategies: %s)", name, strings.Join(StrategyNames(), ", "))
+	}
+	resolved := info.Params.Copy()
+	for key, value := range params {
+		if _, known := info.Params[key]; !known {
+			return nil, fmt.Errorf("strategy %q has no parameter %q", name, key)
+		}
+		resolved[key] = value
+	}
+	return resolved, nil
+}
+
+// Build an agent of the named strategy, which remembers its strategy name and parameters
+func CreateAgent(name string, funcs agent.IExposedServerFunctions[common.IExtendedAgent], config AgentConfig, params common.StrategyParams) (common.IExtendedAgent, error) {
+	resolved, err := ResolveStrategyParams(name, params)
+	if err != nil {
+		return nil, err
+	}
+	created := strategies[name].Constructor(funcs, config, resolved)
+	created.SetStrategy(name, resolved)
+	return created, nil
+}
@@ -0,0 +1,230 @@
+package agents
+
+import (
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+)
+
+/*
+* What an agent remembers about its teammates: the contributions and
+* withdrawals they stated, the turn scores they declared, their audits and
+* what other agents think of them. ExtendedAgent keeps one in Memory and its
+* message handlers fill it in, so a strategy overriding a handler should pass
+* the message on, e.g. b.ExtendedAgent.HandleContributionMessage(msg).
+*
+* Honesty is a Beta belief per teammate: it starts from the prior and each
+* audit adds one honest or one dishonest observation. Strategies can add
+* their own evidence, weighted as they see fit, with ObserveHonesty.
+ */
+type TeammateMemory struct {
+	window         int // observations kept per teammate and kind, 0 for all
+	priorHonest    float64
+	priorDishonest float64
+	teammates      map[uuid.UUID]*TeammateRecord
+}
+
+// An amount a teammate stated, with what its AoA expected if it said
+type Statement struct {
+	Stated   int
+	Expected int
+}
+
+// How much of what was expected the teammate stated, 1 if nothing was expected
+func (s Statement) Cooperation() float64 {
+	if s.Expected <= 0 {
+		return 1
+	}
+	return float64(s.Stated) / float64(s.Expected)
+}
+
+// Everything remembered about one teammate, oldest first
+type TeammateRecord struct {
+	Contributions []Statement
+	Withdrawals   []Statement
+	TurnScores    []int
+	Audits        []common.AuditEntry
+	Opinions      map[uuid.UUID]int // the latest opinion of the teammate from each agent that gave one
+	Honest        float64           // evidence of honesty, not counting the prior
+	Dishonest     float64
+}
+
+func NewTeammateMemory(window int) *TeammateMemory {
+	return &TeammateMemory{
+		window:         window,
+		priorHonest:    1,
+		priorDishonest: 1,
+		teammates:      make(map[uuid.UUID]*TeammateRecord),
+	}
+}
+
+// Start every teammate from a Beta(honest, dishonest) belief instead of the uniform Beta(1, 1)
+func (m *TeammateMemory) SetPrior(honest float64, dishonest float64) {
+	m.priorHonest, m.priorDishonest = honest, dishonest
+}
+
+func (m *TeammateMemory) record(agentID uuid.UUID) *TeammateRecord {
+	record, ok := m.teammates[agentID]
+	if !ok {
+		record = &TeammateRecord{Opinions: make(map[uuid.UUID]int)}
+		m.teammates[agentID] = record
+	}
+	return record
+}
+
+func remember[T any](history []T, observation T, window int) []T {
+	history = append(history, observation)
+	if window > 0 && len(history) > window {
+		history = history[len(history)-window:]
+	}
+	return history
+}
+
+// What is remembered about a teammate, nil if nothing is
+func (m *TeammateMemory) Teammate(agentID uuid.UUID) *TeammateRecord {
+	return m.teammates[agentID]
+}
+
+func (m *TeammateMemory) Forget(agentID uuid.UUID) {
+	delete(m.teammates, agentID)
+}
+
+// Forget every teammate not among the given ones, e.g. those that left, died or were expelled
+func (m *TeammateMemory) ForgetAllBut(teammates []uuid.UUID) {
+	keep := make(map[uuid.UUID]bool, len(teammates))
+	for _, agentID := range teammates {
+		keep[agentID] = true
+	}
+	for agentID := range m.teammates {
+		if !keep[agentID] {
+			m.Forget(agentID)
+		}
+	}
+}
+
+func (m *TeammateMemory) ObserveContribution(agentID uuid.UUID, stated int, expected int) {
+	record := m.record(agentID)
+	record.Contributions = remember(record.Contributions, Statement{Stated: stated, Expected: expected}, m.window)
+}
+
+func (m *TeammateMemory) ObserveWithdrawal(agentID uuid.UUID, stated int, expected int) {
+	record := m.record(agentID)
+	record.Withdrawals = remember(record.Withdrawals, Statement{Stated: stated, Expected: expected}, m.window)
+}
+
+func (m *TeammateMemory) ObserveTurnScore(agentID uuid.UUID, declared int) {
+	record := m.record(agentID)
+	record.TurnScores = remember(record.TurnScores, declared, m.window)
+}
+
+// An audit counts against the teammate if it was found to have cheated and the verdict stood
+func (m *TeammateMemory) ObserveAudit(entry common.AuditEntry) {
+	record := m.record(entry.AgentID)
+	record.Audits = remember(record.Audits, entry, m.window)
+	m.ObserveHonesty(entry.AgentID, !entry.Verdict || entry.Overturned, 1)
+}
+
+func (m *TeammateMemory) ObserveOpinion(holderID uuid.UUID, agentID uuid.UUID, opinion int) {
+	m.record(agentID).Opinions[holderID] = opinion
+}
+
+func (m *TeammateMemory) ObserveHonesty(agentID uuid.UUID, honest bool, weight float64) {
+	record := m.record(agentID)
+	if honest {
+		record.Honest += weight
+	} else {
+		record.Dishonest += weight
+	}
+}
+
+// The mean of the Beta belief that the teammate is honest, the prior's mean for a stranger
+func (m *TeammateMemory) Honesty(agentID uuid.UUID) float64 {
+	honest, dishonest := m.priorHonest, m.priorDishonest
+	if record, ok := m.teammates[agentID]; ok {
+		honest += record.Honest
+		dishonest += record.Dishonest
+	}
+	if honest+dishonest == 0 {
+		return 0.5
+	}
+	return honest / (honest + dishonest)
+}
+
+// The cooperation of the teammate's latest stated contribution
+func (m *TeammateMemory) Cooperation(agentID uuid.UUID) (float64, bool) {
+	record, ok := m.teammates[agentID]
+	if !ok || len(record.Contributions) == 0 {
+		return 0, false
+	}
+	return record.Contributions[len(record.Contributions)-1].Cooperation(), true
+}
+
+func (m *TeammateMemory) AverageContribution(agentID uuid.UUID) (float64, bool) {
+	record, ok := m.teammates[agentID]
+	if !ok {
+		return 0, false
+	}
+	return averageStated(record.Contributions)
+}
+
+func (m *TeammateMemory) AverageWithdrawal(agentID uuid.UUID) (float64, bool) {
+	record, ok := m.teammates[agentID]
+	if !ok {
+		return 0, false
+	}
+	return averageStated(record.Withdrawals)
+}
+
+// The teammate's latest stated withdrawal
+func (m *TeammateMemory) LastWithdrawal(agentID uuid.UUID) (int, bool) {
+	record, ok := m.teammates[agentID]
+	if !ok || len(record.Withdrawals) == 0 {
+		return 0, false
+	}
+	return record.Withdrawals[len(record.Withdrawals)-1].Stated, true
+}
+
+/*
+* How much the teammate's stated contribution changes from one observation to
+* the next, as the slope of a least squares line through them: positive if it
+* is contributing more and more. 0 with fewer than two observations.
+ */
+func (m *TeammateMemory) ContributionTrend(agentID uuid.UUID) float64 {
+	record, ok := m.teammates[agentID]
+	if !ok || len(record.Contributions) < 2 {
+		return 0
+	}
+	n := float64(len(record.Contributions))
+	meanX := (n - 1) / 2
+	meanY, _ := averageStated(record.Contributions)
+	covariance, variance := 0.0, 0.0
+	for i, statement := range record.Contributions {
+		dx := float64(i) - meanX
+		covariance += dx * (float64(statement.Stated) - meanY)
+		variance += dx * dx
+	}
+	return covariance / variance
+}
+
+// The mean opinion other agents gave of the teammate
+func (m *TeammateMemory) AverageOpinion(agentID uuid.UUID) (float64, bool) {
+	record, ok := m.teammates[agentID]
+	if !ok || len(record.Opinions) == 0 {
+		return 0, false
+	}
+	total := 0
+	for _, opinion := range record.Opinions {
+		total += opinion
+	}
+	return float64(total) / float64(len(record.Opinions)), true
+}
+
+func averageStated(statements []Statement) (float64, bool) {
+	if len(statements) == 0 {
+		return 0, false
+	}
+	total := 0
+	for _, statement := range statements {
+		total += statement.Stated
+	}
+	return float64(total) / float64(len(statements)), true
+}
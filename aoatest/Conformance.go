@@ -0,0 +1,441 @@
+package aoatest
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"strings"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+)
+
+/*
+* Conformance harness for AoAs. An AoA is put through random sequences of the
+* calls the server makes during a game (contributions, withdrawals, audit
+* votes and audits, members joining, leaving and dying) and its answers are
+* checked against the contract every AoA must keep:
+*
+*	- no call panics
+*	- an audit vote picks nobody or a member of the team
+*	- the withdrawal order is a permutation of the members
+*	- expected contributions, withdrawals and audit costs are not negative
+*	- a resource allocation only gives to members and never more than the pool
+*	- the amendment majority is a share between 0 and 1
+*
+* The sequences only depend on the seed, so a failure can be replayed. Every
+* registered AoA is run through it by the tests, so a new AoA that breaks the
+* contract fails CI. RunPostContributionAoaLogic is left out: it needs live
+* agents, so it is covered by the server's tests instead.
+ */
+
+// Builds a fresh AoA for a team, e.g. through common.CreateAoA
+type Factory func(team *common.Team) common.IArticlesOfAssociation
+
+type Config struct {
+	Seed       int64
+	Sequences  int // independent sequences, each with a fresh team and AoA
+	Steps      int // calls per sequence
+	MaxMembers int
+}
+
+func DefaultConfig() Config {
+	return Config{Seed: 1, Sequences: 20, Steps: 200, MaxMembers: 8}
+}
+
+// A broken contract, with what is needed to replay it
+type Violation struct {
+	Seed     int64 // seed of the sequence
+	Step     int
+	Call     string
+	Message  string
+	Sequence []string // calls made in the sequence, up to the violation
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("seed %d, step %d, %s: %s", v.Seed, v.Step, v.Call, v.Message)
+}
+
+/*
+* Run an AoA through the harness. Each sequence stops at its first violation,
+* so the result holds at most one violation per sequence.
+ */
+func Check(factory Factory, config Config) []Violation {
+	violations := []Violation{}
+	for i := 0; i < config.Sequences; i++ {
+		s := newSequence(config, config.Seed+int64(i))
+		if v := s.run(factory); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+	return violations
+}
+
+// Check every registered AoA with its default parameters, by AoA name
+func CheckRegistered(config Config) map[string][]Violation {
+	results := map[string][]Violation{}
+	for _, info := range common.AoAs() {
+		id := info.ID
+		results[info.Name] = Check(func(team *common.Team) common.IArticlesOfAssociation {
+			aoa, _ := common.CreateAoA(id, team, nil)
+			return aoa
+		}, config)
+	}
+	return results
+}
+
+// One random sequence of calls against one AoA
+type sequence struct {
+	rng    *rand.Rand
+	config Config
+	seed   int64
+	team   *common.Team
+	aoa    common.IArticlesOfAssociation
+	scores map[uuid.UUID]int
+	calls  []string
+}
+
+func newSequence(config Config, seed int64) *sequence {
+	s := &sequence{
+		rng:    rand.New(rand.NewSource(seed)),
+		config: config,
+		seed:   seed,
+		team:   common.NewTeam(uuid.New()),
+		scores: map[uuid.UUID]int{},
+	}
+	for i := 0; i < 2+s.rng.Intn(max(config.MaxMembers-1, 1)); i++ {
+		s.addMember()
+	}
+	return s
+}
+
+func (s *sequence) addMember() uuid.UUID {
+	agentID := uuid.New()
+	s.team.Agents = append(s.team.Agents, agentID)
+	s.scores[agentID] = s.rng.Intn(30)
+	return agentID
+}
+
+func (s *sequence) removeMember(agentID uuid.UUID) {
+	for i, id := range s.team.Agents {
+		if id == agentID {
+			s.team.Agents = append(s.team.Agents[:i], s.team.Agents[i+1:]...)
+			break
+		}
+	}
+	delete(s.scores, agentID)
+}
+
+func (s *sequence) isMember(agentID uuid.UUID) bool {
+	_, ok := s.scores[agentID]
+	return ok
+}
+
+func (s *sequence) randomMember() uuid.UUID {
+	return s.team.Agents[s.rng.Intn(len(s.team.Agents))]
+}
+
+/*
+* Make one call, turning a panic into a violation message. The function
+* returns a message if the AoA broke the contract, or "" if it did not.
+ */
+func (s *sequence) call(name string, f func() string) (message string) {
+	s.calls = append(s.calls, name)
+	defer func() {
+		if r := recover(); r != nil {
+			message = fmt.Sprintf("panic: %v\n%s", r, panicSite(string(debug.Stack())))
+		}
+	}()
+	return f()
+}
+
+func (s *sequence) run(factory Factory) *Violation {
+	message := s.call("create", func() string {
+		if s.aoa = factory(s.team); s.aoa == nil {
+			return "the factory built no AoA"
+		}
+		return ""
+	})
+	if v := s.violation(0, message); v != nil {
+		v.Call = "create"
+		return v
+	}
+	steps := []struct {
+		weight int
+		step   func() (string, string)
+	}{
+		{4, s.contributions},
+		{4, s.withdrawals},
+		{3, s.audit},
+		{1, s.join},
+		{1, s.leave},
+		{1, s.allocation},
+		{1, s.amendmentMajority},
+		{1, s.roles},
+	}
+	total := 0
+	for _, step := range steps {
+		total += step.weight
+	}
+	for i := 1; i <= s.config.Steps; i++ {
+		pick := s.rng.Intn(total)
+		for _, step := range steps {
+			if pick -= step.weight; pick < 0 {
+				call, message := step.step()
+				if v := s.violation(i, message); v != nil {
+					v.Call = call
+					return v
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (s *sequence) violation(step int, message string) *Violation {
+	if message == "" {
+		return nil
+	}
+	return &Violation{Seed: s.seed, Step: step, Message: message, Sequence: append([]string{}, s.calls...)}
+}
+
+// Every member contributes, honestly or not, as in the contribution phase
+func (s *sequence) contributions() (string, string) {
+	for _, agentID := range s.team.Agents {
+		score := s.scores[agentID]
+		if message := s.call("GetExpectedContribution", func() string {
+			if expected := s.aoa.GetExpectedContribution(agentID, score); expected < 0 {
+				return fmt.Sprintf("expected contribution %d is negative", expected)
+			}
+			return ""
+		}); message != "" {
+			return "GetExpectedContribution", message
+		}
+		actual := s.rng.Intn(score + 1)
+		stated := actual
+		if s.rng.Intn(3) == 0 {
+			stated = s.rng.Intn(score + 1)
+		}
+		if message := s.call("SetContributionAuditResult", func() string {
+			s.aoa.SetContributionAuditResult(agentID, score, actual, stated)
+			return ""
+		}); message != "" {
+			return "SetContributionAuditResult", message
+		}
+		s.scores[agentID] = score - actual + s.rng.Intn(10)
+	}
+	return "", ""
+}
+
+// Members withdraw in the AoA's order, as in the withdrawal phase
+func (s *sequence) withdrawals() (string, string) {
+	pool := s.rng.Intn(100)
+	var order []uuid.UUID
+	if message := s.call("GetWithdrawalOrder", func() string {
+		members := append([]uuid.UUID{}, s.team.Agents...)
+		order = s.aoa.GetWithdrawalOrder(members)
+		return checkPermutation(order, s.team.Agents)
+	}); message != "" {
+		return "GetWithdrawalOrder", message
+	}
+	for _, agentID := range order {
+		score := s.scores[agentID]
+		if message := s.call("GetExpectedWithdrawal", func() string {
+			if expected := s.aoa.GetExpectedWithdrawal(agentID, score, pool); expected < 0 {
+				return fmt.Sprintf("expected withdrawal %d is negative", expected)
+			}
+			return ""
+		}); message != "" {
+			return "GetExpectedWithdrawal", message
+		}
+		actual := s.rng.Intn(pool/2 + 1)
+		stated := actual
+		if s.rng.Intn(3) == 0 {
+			stated = s.rng.Intn(pool/2 + 1)
+		}
+		if message := s.call("SetWithdrawalAuditResult", func() string {
+			s.aoa.SetWithdrawalAuditResult(agentID, score, actual, stated, pool)
+			return ""
+		}); message != "" {
+			return "SetWithdrawalAuditResult", message
+		}
+		s.scores[agentID] = score + actual
+	}
+	return "", ""
+}
+
+// The team votes on an audit and, if the AoA calls one, it is carried out
+func (s *sequence) audit() (string, string) {
+	ballot := common.BallotResult{
+		BallotSpec: common.BallotSpec{Options: []string{common.NoAudit}},
+		Scores:     map[int]float64{},
+	}
+	for _, agentID := range s.team.Agents {
+		ballot.Options = append(ballot.Options, agentID.String())
+	}
+	for range s.team.Agents {
+		option := 0
+		if s.rng.Intn(2) == 1 {
+			option = 1 + s.rng.Intn(len(s.team.Agents))
+		}
+		ballot.Scores[option]++
+		ballot.Eligible++
+		ballot.Turnout++
+	}
+	var audited uuid.UUID
+	if message := s.call("GetVoteResult", func() string {
+		audited = s.aoa.GetVoteResult(ballot)
+		if audited != uuid.Nil && !s.isMember(audited) {
+			return fmt.Sprintf("picked %s for audit, who is not a member", audited)
+		}
+		return ""
+	}); message != "" || audited == uuid.Nil {
+		return "GetVoteResult", message
+	}
+	if message := s.call("GetAuditCost", func() string {
+		if cost := s.aoa.GetAuditCost(s.rng.Intn(100)); cost < 0 {
+			return fmt.Sprintf("audit cost %d is negative", cost)
+		}
+		return ""
+	}); message != "" {
+		return "GetAuditCost", message
+	}
+	if s.rng.Intn(2) == 0 {
+		return "GetContributionAuditResult", s.call("GetContributionAuditResult", func() string {
+			s.aoa.GetContributionAuditResult(audited)
+			return ""
+		})
+	}
+	return "GetWithdrawalAuditResult", s.call("GetWithdrawalAuditResult", func() string {
+		s.aoa.GetWithdrawalAuditResult(audited)
+		return ""
+	})
+}
+
+// The server hands role holders to AoAs that declare roles, as runElections does
+func (s *sequence) roles() (string, string) {
+	holder, ok := s.aoa.(common.RoleHolder)
+	if !ok {
+		return "", ""
+	}
+	var specs []common.RoleSpec
+	if message := s.call("GetRoles", func() string {
+		specs = holder.GetRoles()
+		seen := map[common.Role]bool{}
+		for _, spec := range specs {
+			if seen[spec.Role] {
+				return fmt.Sprintf("role %s is declared twice", spec.Role)
+			}
+			seen[spec.Role] = true
+		}
+		return ""
+	}); message != "" {
+		return "GetRoles", message
+	}
+	for _, spec := range specs {
+		holders := []uuid.UUID{}
+		for _, i := range s.rng.Perm(len(s.team.Agents))[:min(spec.SeatCount(), len(s.team.Agents))] {
+			holders = append(holders, s.team.Agents[i])
+		}
+		if message := s.call("SetRoleHolders", func() string {
+			holder.SetRoleHolders(spec.Role, holders)
+			return ""
+		}); message != "" {
+			return "SetRoleHolders", message
+		}
+	}
+	return "", ""
+}
+
+// An orphan is admitted, as AllocateOrphans does
+func (s *sequence) join() (string, string) {
+	if len(s.team.Agents) >= s.config.MaxMembers {
+		return "", ""
+	}
+	agentID := s.addMember()
+	return "OnMemberJoined", s.call("OnMemberJoined", func() string {
+		s.aoa.OnMemberJoined(agentID)
+		return ""
+	})
+}
+
+// A member leaves or dies; the team always keeps two members
+func (s *sequence) leave() (string, string) {
+	if len(s.team.Agents) <= 2 {
+		return "", ""
+	}
+	agentID := s.randomMember()
+	s.removeMember(agentID)
+	if s.rng.Intn(2) == 0 {
+		return "OnMemberDied", s.call("OnMemberDied", func() string {
+			s.aoa.OnMemberDied(agentID)
+			return ""
+		})
+	}
+	return "OnMemberLeft", s.call("OnMemberLeft", func() string {
+		s.aoa.OnMemberLeft(agentID)
+		return ""
+	})
+}
+
+func (s *sequence) allocation() (string, string) {
+	pool := s.rng.Intn(100)
+	scores := map[uuid.UUID]int{}
+	for agentID, score := range s.scores {
+		scores[agentID] = score
+	}
+	return "ResourceAllocation", s.call("ResourceAllocation", func() string {
+		total := 0
+		for agentID, amount := range s.aoa.ResourceAllocation(scores, pool) {
+			if !s.isMember(agentID) {
+				return fmt.Sprintf("allocated %d to %s, who is not a member", amount, agentID)
+			}
+			if amount < 0 {
+				return fmt.Sprintf("allocated %d, a negative amount, to %s", amount, agentID)
+			}
+			total += amount
+		}
+		if total > pool {
+			return fmt.Sprintf("allocated %d out of a pool of %d", total, pool)
+		}
+		return ""
+	})
+}
+
+func (s *sequence) amendmentMajority() (string, string) {
+	return "GetAmendmentMajority", s.call("GetAmendmentMajority", func() string {
+		if majority := s.aoa.GetAmendmentMajority(); majority < 0 || majority > 1 {
+			return fmt.Sprintf("amendment majority %g is not between 0 and 1", majority)
+		}
+		return ""
+	})
+}
+
+func checkPermutation(order []uuid.UUID, members []uuid.UUID) string {
+	if len(order) != len(members) {
+		return fmt.Sprintf("withdrawal order has %d agents for %d members", len(order), len(members))
+	}
+	count := map[uuid.UUID]int{}
+	for _, agentID := range members {
+		count[agentID]++
+	}
+	for _, agentID := range order {
+		if count[agentID]--; count[agentID] < 0 {
+			return fmt.Sprintf("withdrawal order lists %s more often than it is a member", agentID)
+		}
+	}
+	return ""
+}
+
+// The frames of a stack trace just below the panic, enough to find the panicking line
+func panicSite(stack string) string {
+	lines := strings.Split(stack, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.Contains(lines[i], "runtime/panic.go") {
+			lines = lines[i+1:]
+			break
+		}
+	}
+	return strings.Join(lines[:min(len(lines), 4)], "\n")
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// What the accuser saw, e.g. the amounts of the accused's ContributionMessage
+type AccusationEvidence struct {
+	StatedAmount   int
+	ExpectedAmount int
+}
+
+/*
+* An agent's claim that a teammate cheated in a phase of the current turn. It
+* is filed with the server (see IServer.FileAccusation) and told to the team in
+* an AccusationMessage. Accusations are settled when the accused is audited in
+* that phase, and expire at the end of the turn otherwise.
+ */
+type Accusation struct {
+	TeamID    uuid.UUID
+	AccuserID uuid.UUID
+	AccusedID uuid.UUID
+	Iteration int
+	Turn      int
+	Phase     AuditPhase
+	Evidence  *AccusationEvidence // nil if the accuser offers none
+}
+
+/*
+* How an AoA treats accusations. An agent accused by at least MinAccusers
+* teammates is audited when the audit vote calls no audit. Once the accused is
+* audited, each of its accusers is paid the reward out of the common pool if
+* it is found to have cheated, and pays the penalty into the pool if not. The
+* zero value ignores accusations.
+ */
+type WhistleblowerTerms struct {
+	MinAccusers int `json:"minAccusers,omitempty" yaml:"minAccusers,omitempty"` // 0 for accusations never to call an audit
+	Reward      int `json:"reward,omitempty" yaml:"reward,omitempty"`
+	Penalty     int `json:"penalty,omitempty" yaml:"penalty,omitempty"`
+}
+
+// AoAs that act on accusations
+type AccusationPolicy interface {
+	GetWhistleblowerTerms() WhistleblowerTerms
+}
+
+func (w WhistleblowerTerms) Validate() error {
+	if w.MinAccusers < 0 || w.Reward < 0 || w.Penalty < 0 {
+		return fmt.Errorf("whistleblower minAccusers, reward and penalty cannot be negative")
+	}
+	return nil
+}
+
+/*
+* The accused agents with enough distinct accusers to be audited, most
+* accused first; ties go to the one accused first.
+ */
+func (w WhistleblowerTerms) Shortlist(accusations []Accusation) []uuid.UUID {
+	if w.MinAccusers <= 0 {
+		return nil
+	}
+	accusers := map[uuid.UUID]map[uuid.UUID]bool{}
+	accused := []uuid.UUID{}
+	for _, accusation := range accusations {
+		if accusers[accusation.AccusedID] == nil {
+			accusers[accusation.AccusedID] = map[uuid.UUID]bool{}
+			accused = append(accused, accusation.AccusedID)
+		}
+		accusers[accusation.AccusedID][accusation.AccuserID] = true
+	}
+	shortlist := []uuid.UUID{}
+	for _, id := range accused {
+		if len(accusers[id]) >= w.MinAccusers {
+			shortlist = append(shortlist, id)
+		}
+	}
+	sort.SliceStable(shortlist, func(i, j int) bool {
+		return len(accusers[shortlist[i]]) > len(accusers[shortlist[j]])
+	})
+	return shortlist
+}
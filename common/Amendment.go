@@ -0,0 +1,29 @@
+package common
+
+import "github.com/google/uuid"
+
+/*
+* A constitutional amendment: a proposal to move a team to another AoA, or to
+* the same AoA with different parameters, part way through an iteration. The
+* team's current AoA decides the share of members that must vote for it (see
+* GetAmendmentMajority).
+ */
+type AoAAmendment struct {
+	ProposerID       uuid.UUID
+	AoAID            int
+	Params           AoAParams // parameters of the new AoA, defaults if left out
+	KeepAuditHistory bool      // carry the audit history over, if both AoAs can
+}
+
+// Share of a team's members that must vote for an amendment, unless its AoA says otherwise
+const DefaultAmendmentMajority = 2.0 / 3.0
+
+/*
+* AoAs that can hand their audit history over to another AoA. The history is
+* given per agent as a list of infractions, oldest first. AoAs that do not
+* implement it start the new AoA with no history.
+ */
+type AuditHistoryHolder interface {
+	GetAuditHistory() map[uuid.UUID][]int
+	SetAuditHistory(history map[uuid.UUID][]int)
+}
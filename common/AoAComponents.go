@@ -0,0 +1,541 @@
+package common
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+/*
+* The rules a composable AoA is assembled from (see ComposableAoA). Each kind
+* of rule has a library of stock components, extracted from the hand-written
+* AoAs, that can be looked up by name and built with numeric parameters, in
+* the same way as strategies and AoAs.
+ */
+
+/*
+* What a rule can see of the team it governs. Scores are the members' scores
+* after their latest contribution, as far as the AoA has seen them.
+ */
+type TeamView struct {
+	Team   *Team
+	Scores map[uuid.UUID]int
+}
+
+// The members of the team, or none if the AoA is not attached to a team
+func (v TeamView) Members() []uuid.UUID {
+	if v.Team == nil {
+		return nil
+	}
+	return v.Team.Agents
+}
+
+// What an agent is expected to contribute out of its score
+type ContributionRule func(view TeamView, agentID uuid.UUID, agentScore int) int
+
+// What an agent is expected to withdraw from the common pool
+type WithdrawalRule func(view TeamView, agentID uuid.UUID, agentScore int, commonPool int) int
+
+// Which agent the team audits given its audit ballot, uuid.Nil for none
+type AuditTriggerRule func(view TeamView, ballot BallotResult) uuid.UUID
+
+// What an audit costs the common pool
+type AuditCostRule func(commonPool int) int
+
+// The order in which agents withdraw from the common pool
+type WithdrawalOrderRule func(view TeamView, agentIDs []uuid.UUID) []uuid.UUID
+
+// Whether an agent's contribution or withdrawal counts as an infraction
+type InfractionRule func(phase AuditPhase, expected int, actual int, stated int) bool
+
+// Whether an audited agent fails the audit, given its infraction record
+type VerdictRule func(record *AuditRecord, agentID uuid.UUID) bool
+
+/*
+* The sanction for an agent that has just failed its n-th audit, not counting
+* the ones forgiven. A "forgiveness" parameter, where a rule takes one, is the
+* number of clean turns after which a failed audit is forgiven.
+ */
+type SanctionRule func(failedAudits int, commonPool int) Sanction
+
+type AuditPhase string
+
+const (
+	ContributionAudit AuditPhase = "contribution"
+	WithdrawalAudit   AuditPhase = "withdrawal"
+)
+
+// A stock component, built from its parameters
+type Component[T any] struct {
+	Name        string
+	Description string
+	Params      AoAParams // parameters the component reads, with their defaults
+	Build       func(params AoAParams) T
+}
+
+type ComponentLibrary[T any] struct {
+	kind       string
+	components map[string]Component[T]
+}
+
+func newComponentLibrary[T any](kind string) *ComponentLibrary[T] {
+	return &ComponentLibrary[T]{kind: kind, components: map[string]Component[T]{}}
+}
+
+// Register a component. Registering the same name twice is a programming error.
+func (l *ComponentLibrary[T]) Register(component Component[T]) {
+	if component.Name == "" || component.Build == nil {
+		panic(fmt.Sprintf("common: a %s rule needs a name and a builder", l.kind))
+	}
+	if _, exists := l.components[component.Name]; exists {
+		panic(fmt.Sprintf("common: %s rule %q registered twice", l.kind, component.Name))
+	}
+	if component.Params == nil {
+		component.Params = AoAParams{}
+	}
+	l.components[component.Name] = component
+}
+
+func (l *ComponentLibrary[T]) Kind() string {
+	return l.kind
+}
+
+func (l *ComponentLibrary[T]) Lookup(name string) (Component[T], bool) {
+	component, ok := l.components[name]
+	return component, ok
+}
+
+// Every component in the library, sorted by name
+func (l *ComponentLibrary[T]) Components() []Component[T] {
+	components := make([]Component[T], 0, len(l.components))
+	for _, component := range l.components {
+		components = append(components, component)
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+	return components
+}
+
+func (l *ComponentLibrary[T]) names() string {
+	names := []string{}
+	for _, component := range l.Components() {
+		names = append(names, component.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Fill in the defaults of a component's parameters, rejecting those it does not know
+func (l *ComponentLibrary[T]) resolve(spec ComponentSpec) (AoAParams, error) {
+	component, ok := l.components[spec.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s rule %q (known rules: %s)", l.kind, spec.Name, l.names())
+	}
+	resolved := component.Params.Copy()
+	for key, value := range spec.Params {
+		if _, known := component.Params[key]; !known {
+			return nil, fmt.Errorf("%s rule %q has no parameter %q", l.kind, spec.Name, key)
+		}
+		resolved[key] = value
+	}
+	return resolved, nil
+}
+
+/*
+* Build a component by name. Parameters that are not given take the
+* component's defaults, and parameters it does not know are an error.
+ */
+func (l *ComponentLibrary[T]) Build(name string, params AoAParams) (T, error) {
+	var zero T
+	resolved, err := l.resolve(ComponentSpec{Name: name, Params: params})
+	if err != nil {
+		return zero, err
+	}
+	return l.components[name].Build(resolved), nil
+}
+
+var (
+	ContributionRules    = newComponentLibrary[ContributionRule]("contribution")
+	WithdrawalRules      = newComponentLibrary[WithdrawalRule]("withdrawal")
+	AuditTriggerRules    = newComponentLibrary[AuditTriggerRule]("auditTrigger")
+	AuditCostRules       = newComponentLibrary[AuditCostRule]("auditCost")
+	WithdrawalOrderRules = newComponentLibrary[WithdrawalOrderRule]("withdrawalOrder")
+	InfractionRules      = newComponentLibrary[InfractionRule]("infraction")
+	VerdictRules         = newComponentLibrary[VerdictRule]("verdict")
+	SanctionRules        = newComponentLibrary[SanctionRule]("sanction")
+)
+
+// The libraries by kind, in the order of the rules of an AoASpec
+var componentLibraries = []interface {
+	Kind() string
+	resolve(spec ComponentSpec) (AoAParams, error)
+}{
+	ContributionRules, WithdrawalRules, AuditTriggerRules, AuditCostRules,
+	WithdrawalOrderRules, InfractionRules, VerdictRules, SanctionRules,
+}
+
+// A copy of agentIDs in random order
+func shuffledOrder(agentIDs []uuid.UUID) []uuid.UUID {
+	shuffledAgents := make([]uuid.UUID, len(agentIDs))
+	copy(shuffledAgents, agentIDs)
+	rand.Shuffle(len(shuffledAgents), func(i, j int) {
+		shuffledAgents[i], shuffledAgents[j] = shuffledAgents[j], shuffledAgents[i]
+	})
+	return shuffledAgents
+}
+
+// ---------- Stock contribution rules ----------
+
+func init() {
+	ContributionRules.Register(Component[ContributionRule]{
+		Name:        "all",
+		Description: "the whole score (fixed and team 2 AoAs)",
+		Build: func(params AoAParams) ContributionRule {
+			return func(view TeamView, agentID uuid.UUID, agentScore int) int { return agentScore }
+		},
+	})
+	ContributionRules.Register(Component[ContributionRule]{
+		Name:        "share",
+		Description: "a share of the score (team 5 AoA: 0.75)",
+		Params:      AoAParams{"share": 0.75},
+		Build: func(params AoAParams) ContributionRule {
+			share := params.Get("share", 0.75)
+			return func(view TeamView, agentID uuid.UUID, agentScore int) int {
+				return int(float64(agentScore) * share)
+			}
+		},
+	})
+	ContributionRules.Register(Component[ContributionRule]{
+		Name:        "fixed",
+		Description: "a fixed amount (team 1 AoA: 1)",
+		Params:      AoAParams{"amount": 1},
+		Build: func(params AoAParams) ContributionRule {
+			amount := int(params.Get("amount", 1))
+			return func(view TeamView, agentID uuid.UUID, agentScore int) int { return amount }
+		},
+	})
+}
+
+// ---------- Stock withdrawal rules ----------
+
+func init() {
+	WithdrawalRules.Register(Component[WithdrawalRule]{
+		Name:        "fixed",
+		Description: "a fixed amount (fixed AoA: 2)",
+		Params:      AoAParams{"amount": 2},
+		Build: func(params AoAParams) WithdrawalRule {
+			amount := int(params.Get("amount", 2))
+			return func(view TeamView, agentID uuid.UUID, agentScore int, commonPool int) int { return amount }
+		},
+	})
+	WithdrawalRules.Register(Component[WithdrawalRule]{
+		Name:        "pool-share",
+		Description: "a share of the common pool (team 2 AoA: 0.1)",
+		Params:      AoAParams{"share": 0.1},
+		Build: func(params AoAParams) WithdrawalRule {
+			share := params.Get("share", 0.1)
+			return func(view TeamView, agentID uuid.UUID, agentScore int, commonPool int) int {
+				return int(float64(commonPool) * share)
+			}
+		},
+	})
+	WithdrawalRules.Register(Component[WithdrawalRule]{
+		Name:        "equal-share",
+		Description: "the common pool split equally between the members",
+		Build: func(params AoAParams) WithdrawalRule {
+			return func(view TeamView, agentID uuid.UUID, agentScore int, commonPool int) int {
+				if len(view.Members()) == 0 {
+					return 0
+				}
+				return commonPool / len(view.Members())
+			}
+		},
+	})
+	WithdrawalRules.Register(Component[WithdrawalRule]{
+		Name:        "need",
+		Description: "members below a need threshold first, the rest split equally (team 5 AoA: alpha 0.7)",
+		Params:      AoAParams{"alpha": 0.7},
+		Build: func(params AoAParams) WithdrawalRule {
+			alpha := params.Get("alpha", 0.7)
+			return func(view TeamView, agentID uuid.UUID, agentScore int, commonPool int) int {
+				scores := map[uuid.UUID]int{agentID: agentScore}
+				for _, member := range view.Members() {
+					if score, ok := view.Scores[member]; ok && member != agentID {
+						scores[member] = score
+					}
+				}
+				return needAllocation(scores, commonPool, alpha)[agentID]
+			}
+		},
+	})
+}
+
+/*
+* Allocate the pool by need, as the team 5 AoA does: the threshold is the
+* larger of the median score and alpha times the mean, members below it are
+* topped up poorest first, and whatever is left is split equally. Ties are
+* broken by agent ID so that the allocation does not depend on map order.
+ */
+func needAllocation(scores map[uuid.UUID]int, pool int, alpha float64) map[uuid.UUID]int {
+	allocation := make(map[uuid.UUID]int)
+	if len(scores) == 0 {
+		return allocation
+	}
+	values := make([]int, 0, len(scores))
+	agentIDs := make([]uuid.UUID, 0, len(scores))
+	for agentID, score := range scores {
+		values = append(values, score)
+		agentIDs = append(agentIDs, agentID)
+	}
+	threshold := max(calculateMedian(values), int(float64(calculateMean(values))*alpha))
+	sort.Slice(agentIDs, func(i, j int) bool {
+		if scores[agentIDs[i]] != scores[agentIDs[j]] {
+			return scores[agentIDs[i]] < scores[agentIDs[j]]
+		}
+		return agentIDs[i].String() < agentIDs[j].String()
+	})
+
+	remaining, nPriority := pool, 0
+	for _, agentID := range agentIDs {
+		if remaining <= 0 {
+			break
+		}
+		if need := threshold - scores[agentID]; need > 0 {
+			allocation[agentID] = min(need, remaining/(nPriority+1))
+			remaining -= allocation[agentID]
+			nPriority++
+		}
+	}
+	if remaining > 0 {
+		for _, agentID := range agentIDs {
+			allocation[agentID] += remaining / len(agentIDs)
+		}
+	}
+	return allocation
+}
+
+// ---------- Stock audit trigger rules ----------
+
+func init() {
+	AuditTriggerRules.Register(Component[AuditTriggerRule]{
+		Name:        "never",
+		Description: "nobody is ever audited (fixed AoA)",
+		Build: func(params AoAParams) AuditTriggerRule {
+			return func(view TeamView, ballot BallotResult) uuid.UUID { return uuid.Nil }
+		},
+	})
+	AuditTriggerRules.Register(Component[AuditTriggerRule]{
+		Name:        "plurality",
+		Description: "the agent with the most votes, if anyone voted (team 1 AoA)",
+		Build: func(params AoAParams) AuditTriggerRule {
+			return func(view TeamView, ballot BallotResult) uuid.UUID {
+				agentID, _ := ballot.MostAuditVotes()
+				return agentID
+			}
+		},
+	})
+	AuditTriggerRules.Register(Component[AuditTriggerRule]{
+		Name:        "majority",
+		Description: "the agent voted for by more than a share of all voters (team 5 AoA: 0.5)",
+		Params:      AoAParams{"share": 0.5},
+		Build: func(params AoAParams) AuditTriggerRule {
+			share := params.Get("share", 0.5)
+			return func(view TeamView, ballot BallotResult) uuid.UUID {
+				if agentID, votes := ballot.MostAuditVotes(); votes > share*float64(ballot.Turnout) {
+					return agentID
+				}
+				return uuid.Nil
+			}
+		},
+	})
+	AuditTriggerRules.Register(Component[AuditTriggerRule]{
+		Name:        "threshold",
+		Description: "the agent with the most votes, if it has more than a number of them (team 2 AoA: 4)",
+		Params:      AoAParams{"votes": 4},
+		Build: func(params AoAParams) AuditTriggerRule {
+			threshold := params.Get("votes", 4)
+			return func(view TeamView, ballot BallotResult) uuid.UUID {
+				if agentID, votes := ballot.MostAuditVotes(); votes > threshold {
+					return agentID
+				}
+				return uuid.Nil
+			}
+		},
+	})
+}
+
+// ---------- Stock audit cost rules ----------
+
+func init() {
+	AuditCostRules.Register(Component[AuditCostRule]{
+		Name:        "fixed",
+		Description: "a fixed cost (team 1 AoA: 5)",
+		Params:      AoAParams{"cost": 5},
+		Build: func(params AoAParams) AuditCostRule {
+			cost := int(params.Get("cost", 5))
+			return func(commonPool int) int { return cost }
+		},
+	})
+	AuditCostRules.Register(Component[AuditCostRule]{
+		Name:        "pool-share",
+		Description: "a share of the common pool, at least 1 (team 5 AoA: 0.05)",
+		Params:      AoAParams{"share": 0.05},
+		Build: func(params AoAParams) AuditCostRule {
+			share := params.Get("share", 0.05)
+			return func(commonPool int) int {
+				return max(int(float64(commonPool)*share), 1)
+			}
+		},
+	})
+	AuditCostRules.Register(Component[AuditCostRule]{
+		Name:        "tiered",
+		Description: "2 for a small pool, then 5 plus 1 for every 5 in the pool above 5 (team 2 AoA)",
+		Build: func(params AoAParams) AuditCostRule {
+			return func(commonPool int) int {
+				if commonPool < 5 {
+					return 2
+				}
+				return 5 + ((commonPool - 5) / 5)
+			}
+		},
+	})
+}
+
+// ---------- Stock withdrawal order rules ----------
+
+func init() {
+	WithdrawalOrderRules.Register(Component[WithdrawalOrderRule]{
+		Name:        "random",
+		Description: "a random order every turn (fixed, team 2 and team 5 AoAs)",
+		Build: func(params AoAParams) WithdrawalOrderRule {
+			return func(view TeamView, agentIDs []uuid.UUID) []uuid.UUID { return shuffledOrder(agentIDs) }
+		},
+	})
+	WithdrawalOrderRules.Register(Component[WithdrawalOrderRule]{
+		Name:        "seniority",
+		Description: "the order in which agents joined the team",
+		Build: func(params AoAParams) WithdrawalOrderRule {
+			return func(view TeamView, agentIDs []uuid.UUID) []uuid.UUID { return append([]uuid.UUID{}, agentIDs...) }
+		},
+	})
+}
+
+// ---------- Stock infraction rules ----------
+
+func init() {
+	InfractionRules.Register(Component[InfractionRule]{
+		Name:        "misreport",
+		Description: "stating anything other than the actual amount (fixed AoA)",
+		Build: func(params AoAParams) InfractionRule {
+			return func(phase AuditPhase, expected int, actual int, stated int) bool { return actual != stated }
+		},
+	})
+	InfractionRules.Register(Component[InfractionRule]{
+		Name:        "overstate",
+		Description: "misreporting in the agent's own favour (team 1 and team 5 AoAs)",
+		Build: func(params AoAParams) InfractionRule {
+			return func(phase AuditPhase, expected int, actual int, stated int) bool {
+				if phase == ContributionAudit {
+					return stated > actual
+				}
+				return actual > stated
+			}
+		},
+	})
+	InfractionRules.Register(Component[InfractionRule]{
+		Name:        "deviation",
+		Description: "contributing less or withdrawing more than expected (team 2 AoA)",
+		Build: func(params AoAParams) InfractionRule {
+			return func(phase AuditPhase, expected int, actual int, stated int) bool {
+				if phase == ContributionAudit {
+					return actual < expected
+				}
+				return actual > expected
+			}
+		},
+	})
+}
+
+// ---------- Stock verdict rules ----------
+
+func init() {
+	VerdictRules.Register(Component[VerdictRule]{
+		Name:        "window",
+		Description: "any infraction in the last turns, which the audit then clears (fixed AoA: 1 turn)",
+		Params:      AoAParams{"turns": 1},
+		Build: func(params AoAParams) VerdictRule {
+			turns := int(params.Get("turns", 1))
+			return func(record *AuditRecord, agentID uuid.UUID) bool {
+				record.SetAuditDuration(turns)
+				failed := record.GetAllInfractions(agentID) > 0
+				record.ClearAllInfractions(agentID)
+				return failed
+			}
+		},
+	})
+	VerdictRules.Register(Component[VerdictRule]{
+		Name:        "latest",
+		Description: "an infraction in the latest turn (team 1 AoA)",
+		Build: func(params AoAParams) VerdictRule {
+			return func(record *AuditRecord, agentID uuid.UUID) bool {
+				records := record.GetAuditMap()[agentID]
+				return len(records) > 0 && records[len(records)-1] > 0
+			}
+		},
+	})
+	VerdictRules.Register(Component[VerdictRule]{
+		Name:        "ever",
+		Description: "any infraction since the agent joined (team 5 AoA)",
+		Build: func(params AoAParams) VerdictRule {
+			return func(record *AuditRecord, agentID uuid.UUID) bool {
+				for _, infractions := range record.GetAuditMap()[agentID] {
+					if infractions > 0 {
+						return true
+					}
+				}
+				return false
+			}
+		},
+	})
+}
+
+// ---------- Stock sanction rules ----------
+
+func init() {
+	SanctionRules.Register(Component[SanctionRule]{
+		Name:        "none",
+		Description: "failing an audit has no consequence beyond the team knowing",
+		Build: func(params AoAParams) SanctionRule {
+			return func(failedAudits int, commonPool int) Sanction { return Sanction{} }
+		},
+	})
+	SanctionRules.Register(Component[SanctionRule]{
+		Name:        "fine",
+		Description: "a fine paid into the common pool",
+		Params:      AoAParams{"amount": 5},
+		Build: func(params AoAParams) SanctionRule {
+			amount := int(params.Get("amount", 5))
+			return func(failedAudits int, commonPool int) Sanction { return Sanction{Fine: amount} }
+		},
+	})
+	SanctionRules.Register(Component[SanctionRule]{
+		Name:        "strikes",
+		Description: "expulsion after a number of failed audits (team 5 AoA: 3)",
+		Params:      AoAParams{"strikes": 3, "forgiveness": 0},
+		Build: func(params AoAParams) SanctionRule {
+			strikes := int(params.Get("strikes", 3))
+			return func(failedAudits int, commonPool int) Sanction {
+				return Sanction{Expel: failedAudits >= strikes}
+			}
+		},
+	})
+	SanctionRules.Register(Component[SanctionRule]{
+		Name:        "ladder",
+		Description: "sanctions that escalate from a warning to a fine, loss of withdrawals, loss of vote and expulsion",
+		Params:      AoAParams{"fine": 5, "withdrawalBan": 2, "voteBan": 3, "forgiveness": 5},
+		Build: func(params AoAParams) SanctionRule {
+			return SanctionLadderFromParams(params).Rule()
+		},
+	})
+}
@@ -0,0 +1,231 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+* AoAs defined in a JSON or YAML file rather than in Go, so that institutions
+* can be designed without writing code. A definition names a stock rule of
+* each kind (see AoAComponents.go) with its parameters, for example
+*
+*	id: 8
+*	name: needs
+*	description: contribute 75%, withdraw by need
+*	contribution: {rule: share, share: 0.75}
+*	withdrawal: {rule: need, alpha: 0.7}
+*	auditTrigger: {rule: majority, share: 0.5}
+*	auditCost: {rule: pool-share, share: 0.05}
+*	sanction: {rule: strikes, strikes: 3}
+*	appeal: {method: jury, fee: 2}
+*	whistleblowing: {minAccusers: 2, reward: 3, penalty: 3}
+*	scoreBasis: declared
+*
+* A rule without parameters can be given by its name alone, e.g.
+* "verdict: ever", and rules left out behave as in the fixed AoA. Loading a
+* definition checks every rule and parameter and registers the AoA, after which
+* teams can vote for it by ID like any other.
+ */
+type AoADefinition struct {
+	ID          int    `json:"id" yaml:"id"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	AoASpec     `yaml:",inline"`
+}
+
+// Definitions loaded so far by name, so that loading the same file twice is harmless
+var definedAoAs = map[string]AoADefinition{}
+
+/*
+* Parse a definition, in YAML or JSON according to format ("yaml" or "json").
+* Keys that are not part of the format are an error, to catch typos.
+ */
+func ParseAoADefinition(data []byte, format string) (AoADefinition, error) {
+	var definition AoADefinition
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&definition); err != nil {
+			return definition, err
+		}
+	case "yaml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&definition); err != nil {
+			return definition, err
+		}
+	default:
+		return definition, fmt.Errorf("unknown AoA definition format %q (use json or yaml)", format)
+	}
+	return definition, definition.Validate()
+}
+
+// Read a definition from a .json, .yaml or .yml file
+func LoadAoADefinition(path string) (AoADefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return AoADefinition{}, err
+	}
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format == "yml" {
+		format = "yaml"
+	}
+	definition, err := ParseAoADefinition(data, format)
+	if err != nil {
+		return definition, fmt.Errorf("%s: %v", path, err)
+	}
+	return definition, nil
+}
+
+func (d AoADefinition) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("an AoA needs a name")
+	}
+	if d.ID < 0 {
+		return fmt.Errorf("AoA %s: the ID cannot be negative", d.Name)
+	}
+	if err := d.AoASpec.Validate(); err != nil {
+		return fmt.Errorf("AoA %s: %v", d.Name, err)
+	}
+	return nil
+}
+
+/*
+* Check that a definition could be registered, without registering it: it must
+* be valid, and its ID and name must be free unless it is the same definition.
+ */
+func CheckAoADefinition(definition AoADefinition) error {
+	if err := definition.Validate(); err != nil {
+		return err
+	}
+	if isDefined(definition) {
+		return nil
+	}
+	if info, taken := LookupAoA(definition.ID); taken {
+		return fmt.Errorf("AoA %s: ID %d is already taken by %s", definition.Name, definition.ID, info.Name)
+	}
+	if _, taken := LookupAoAByName(definition.Name); taken {
+		return fmt.Errorf("AoA %s: the name is already taken", definition.Name)
+	}
+	return nil
+}
+
+func isDefined(definition AoADefinition) bool {
+	previous, ok := definedAoAs[definition.Name]
+	return ok && reflect.DeepEqual(previous, definition)
+}
+
+// Fill in the parameters of the AoA defined, as ResolveAoAParams does for a registered one
+func (d AoADefinition) ResolveParams(params AoAParams) (AoAParams, error) {
+	defaults, err := d.AoASpec.Params()
+	if err != nil {
+		return nil, err
+	}
+	return resolveParams(d.Name, defaults, params)
+}
+
+/*
+* Register a definition so that teams can vote for it. Defining an AoA whose ID
+* or name is already taken is an error, unless it is the same definition.
+ */
+func DefineAoA(definition AoADefinition) (AoAInfo, error) {
+	if err := CheckAoADefinition(definition); err != nil {
+		return AoAInfo{}, err
+	}
+	if isDefined(definition) {
+		info, _ := LookupAoA(definition.ID)
+		return info, nil
+	}
+	RegisterComposableAoA(definition.ID, definition.Name, definition.Description, definition.AoASpec)
+	definedAoAs[definition.Name] = definition
+	info, _ := LookupAoA(definition.ID)
+	return info, nil
+}
+
+// Load a definition from a file and register it
+func LoadAoAFile(path string) (AoAInfo, error) {
+	definition, err := LoadAoADefinition(path)
+	if err != nil {
+		return AoAInfo{}, err
+	}
+	return DefineAoA(definition)
+}
+
+// A rule is written either as its name or as {rule: name, param: value, ...}
+func (c *ComponentSpec) fromValue(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		*c = ComponentSpec{Name: v}
+	case map[string]interface{}:
+		spec := ComponentSpec{Params: AoAParams{}}
+		for key, raw := range v {
+			if key == "rule" {
+				name, ok := raw.(string)
+				if !ok {
+					return fmt.Errorf("the rule must be a name, got %v", raw)
+				}
+				spec.Name = name
+				continue
+			}
+			switch number := raw.(type) {
+			case float64:
+				spec.Params[key] = number
+			case int:
+				spec.Params[key] = float64(number)
+			default:
+				return fmt.Errorf("parameter %q must be a number, got %v", key, raw)
+			}
+		}
+		if spec.Name == "" {
+			return fmt.Errorf("a rule with parameters needs a \"rule\" name")
+		}
+		*c = spec
+	default:
+		return fmt.Errorf("a rule must be a name or {rule: name, ...}, got %v", value)
+	}
+	return nil
+}
+
+func (c ComponentSpec) toValue() interface{} {
+	if len(c.Params) == 0 {
+		return c.Name
+	}
+	value := map[string]interface{}{"rule": c.Name}
+	for key, param := range c.Params {
+		value[key] = param
+	}
+	return value
+}
+
+func (c *ComponentSpec) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return c.fromValue(value)
+}
+
+func (c ComponentSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.toValue())
+}
+
+func (c *ComponentSpec) UnmarshalYAML(node *yaml.Node) error {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	return c.fromValue(value)
+}
+
+func (c ComponentSpec) MarshalYAML() (interface{}, error) {
+	return c.toValue(), nil
+}
@@ -0,0 +1,129 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+* Registry of AoAs. Each AoA registers itself with the ID teams vote for, from
+* an init function in its own file, so new AoAs can be added without touching
+* the server. An ID nobody registered falls back to the fixed AoA.
+ */
+
+// Numeric parameters of an AoA, e.g. {"auditDuration": 5}
+type AoAParams = StrategyParams
+
+type AoAConstructor func(team *Team, params AoAParams) IArticlesOfAssociation
+
+type AoAInfo struct {
+	ID          int
+	Name        string
+	Description string
+	Params      AoAParams // parameters the AoA reads, with their defaults
+	Constructor AoAConstructor
+}
+
+// The AoA teams run when they have not chosen one (or chose one that does not exist)
+const DefaultAoAID = 0
+
+var aoas = map[int]AoAInfo{}
+
+// Register an AoA. Registering the same ID or name twice is a programming error.
+func RegisterAoA(info AoAInfo) {
+	if info.Name == "" || info.Constructor == nil {
+		panic("common: an AoA needs a name and a constructor")
+	}
+	for _, other := range aoas {
+		if other.ID == info.ID || other.Name == info.Name {
+			panic(fmt.Sprintf("common: AoA %d (%s) registered twice", info.ID, info.Name))
+		}
+	}
+	if info.Params == nil {
+		info.Params = AoAParams{}
+	}
+	aoas[info.ID] = info
+}
+
+func LookupAoA(id int) (AoAInfo, bool) {
+	info, ok := aoas[id]
+	return info, ok
+}
+
+func LookupAoAByName(name string) (AoAInfo, bool) {
+	for _, info := range aoas {
+		if info.Name == name {
+			return info, true
+		}
+	}
+	return AoAInfo{}, false
+}
+
+// Every registered AoA, sorted by ID
+func AoAs() []AoAInfo {
+	infos := make([]AoAInfo, 0, len(aoas))
+	for _, info := range aoas {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// The IDs of every registered AoA, in order
+func AoAIDs() []int {
+	ids := []int{}
+	for _, info := range AoAs() {
+		ids = append(ids, info.ID)
+	}
+	return ids
+}
+
+func aoaNames() string {
+	names := []string{}
+	for _, info := range AoAs() {
+		names = append(names, info.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+/*
+* Fill in the parameters of an AoA: parameters that are not given take the
+* AoA's defaults, and parameters it does not know are an error.
+ */
+func ResolveAoAParams(name string, params AoAParams) (AoAParams, error) {
+	info, ok := LookupAoAByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown AoA %q (known AoAs: %s)", name, aoaNames())
+	}
+	return resolveParams(name, info.Params, params)
+}
+
+func resolveParams(name string, defaults AoAParams, params AoAParams) (AoAParams, error) {
+	resolved := defaults.Copy()
+	for key, value := range params {
+		if _, known := defaults[key]; !known {
+			return nil, fmt.Errorf("AoA %q has no parameter %q", name, key)
+		}
+		resolved[key] = value
+	}
+	return resolved, nil
+}
+
+/*
+* Build the AoA a team voted for. Returns the ID of the AoA actually built,
+* which is the default one if nothing is registered under the ID. Parameters
+* not given take the AoA's defaults.
+ */
+func CreateAoA(id int, team *Team, params AoAParams) (IArticlesOfAssociation, int) {
+	info, ok := aoas[id]
+	if !ok {
+		aoaLog.Warn("No AoA registered with this ID, using the default", "aoa", id, "default", DefaultAoAID)
+		info = aoas[DefaultAoAID]
+	}
+	resolved := info.Params.Copy()
+	for key, value := range params {
+		resolved[key] = value
+	}
+	return info.Constructor(team, resolved), info.ID
+}
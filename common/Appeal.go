@@ -0,0 +1,63 @@
+package common
+
+import "fmt"
+
+// How an appeal against a verdict is heard
+const (
+	AppealByAudit = "audit" // a second, more thorough audit
+	AppealByJury  = "jury"  // a vote of the rest of the team
+)
+
+/*
+* The terms on which an AoA lets an agent appeal a verdict that went against
+* it. The agent pays the fee into the common pool to be heard; if the verdict
+* is overturned the fee is refunded and the sanction reversed. The zero value
+* offers no appeals.
+ */
+type AppealTerms struct {
+	Method     string  `json:"method,omitempty" yaml:"method,omitempty"`         // AppealByAudit or AppealByJury, empty for no appeals
+	Fee        int     `json:"fee,omitempty" yaml:"fee,omitempty"`               // what the appellant pays to be heard
+	CostFactor float64 `json:"costFactor,omitempty" yaml:"costFactor,omitempty"` // a second audit costs this many times the first, 0 for 2
+	Majority   float64 `json:"majority,omitempty" yaml:"majority,omitempty"`     // share of the jury needed to overturn, 0 for a half
+}
+
+/*
+* AoAs that hear appeals against their verdicts. The server offers the appeal
+* to the agent straight after it is sanctioned.
+ */
+type AppealCourt interface {
+	GetAppealTerms() AppealTerms
+}
+
+func (t AppealTerms) Offered() bool {
+	return t.Method != ""
+}
+
+func (t AppealTerms) Validate() error {
+	if t.Method != "" && t.Method != AppealByAudit && t.Method != AppealByJury {
+		return fmt.Errorf("unknown appeal method %q (use %s or %s)", t.Method, AppealByAudit, AppealByJury)
+	}
+	if t.Fee < 0 || t.CostFactor < 0 {
+		return fmt.Errorf("the appeal fee and cost factor cannot be negative")
+	}
+	if t.Majority < 0 || t.Majority > 1 {
+		return fmt.Errorf("the appeal majority must be between 0 and 1, got %g", t.Majority)
+	}
+	return nil
+}
+
+// What the second audit of an appeal costs, given what the first one did
+func (t AppealTerms) AuditCost(firstCost int) int {
+	factor := t.CostFactor
+	if factor == 0 {
+		factor = 2
+	}
+	return int(float64(max(firstCost, 1)) * factor)
+}
+
+func (t AppealTerms) JuryMajority() float64 {
+	if t.Majority == 0 {
+		return 0.5
+	}
+	return t.Majority
+}
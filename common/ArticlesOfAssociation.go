@@ -14,13 +14,21 @@ type IArticlesOfAssociation interface {
 	GetExpectedWithdrawal(agentId uuid.UUID, agentScore int, commonPool int) int
 	SetWithdrawalAuditResult(agentId uuid.UUID, agentScore int, agentActualWithdrawal int, agentStatedWithdrawal int, commonPool int)
 	GetAuditCost(commonPool int) int
-	GetVoteResult(votes []Vote) uuid.UUID
+	// Who to audit given the team's audit ballot (see NoAudit), uuid.Nil for nobody
+	GetVoteResult(ballot BallotResult) uuid.UUID
 	GetContributionAuditResult(agentId uuid.UUID) bool
 	GetWithdrawalAuditResult(agentId uuid.UUID) bool
 	SetContributionAuditResult(agentId uuid.UUID, agentScore int, agentActualContribution int, agentStatedContribution int)
 	GetWithdrawalOrder(agentIDs []uuid.UUID) []uuid.UUID
 	RunPostContributionAoaLogic(team *Team, agentMap map[uuid.UUID]IExtendedAgent)
 	ResourceAllocation(agentScores map[uuid.UUID]int, remainingResources int) map[uuid.UUID]int
+	GetAmendmentMajority() float64
+
+	// Membership changes after the AoA was created; the server calls these once
+	// the team's list of agents has been updated
+	OnMemberJoined(agentId uuid.UUID)
+	OnMemberLeft(agentId uuid.UUID)
+	OnMemberDied(agentId uuid.UUID)
 }
 
 func CreateVote(isVote int, voterId uuid.UUID, votedForId uuid.UUID) Vote {
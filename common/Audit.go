@@ -6,7 +6,6 @@ type AuditRecord struct {
 	auditMap map[uuid.UUID][]int
 	duration int
 	cost     int
-	// reliability float64
 }
 
 func NewAuditRecord(duration int) *AuditRecord {
@@ -37,7 +36,7 @@ func (a *AuditRecord) SetAuditDuration(duration int) {
 	a.duration, a.cost = duration, calculateCost(duration)
 }
 
-// Implement a more sophisticated cost calculation if needed, could compound with reliability if implemented
+// Longer audits cost more; how reliable an audit is for its cost is up to the server (see AuditReliability)
 func calculateCost(duration int) int {
 	return duration
 }
@@ -65,6 +64,11 @@ func (a *AuditRecord) ClearAllInfractions(agentId uuid.UUID) {
 	a.auditMap[agentId] = []int{}
 }
 
+// Forget an agent's record, e.g. when it leaves the team
+func (a *AuditRecord) RemoveRecord(agentId uuid.UUID) {
+	delete(a.auditMap, agentId)
+}
+
 // After an agent's contribution, add a new record to the audit map - infraction could be 1 or 0 instead of bool
 func (a *AuditRecord) AddRecord(agentId uuid.UUID, infraction int) {
 	if _, ok := a.auditMap[agentId]; !ok {
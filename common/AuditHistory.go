@@ -0,0 +1,36 @@
+package common
+
+import "github.com/google/uuid"
+
+/*
+* One audit as the audited agent's team saw it: who was audited, in which turn
+* and phase, who voted for the audit, what it cost the common pool, the
+* verdict and the sanction that followed, and how any appeal went. An
+* overturned verdict leaves Verdict true and the sanction as first imposed. The server keeps every entry of a
+* team (see IServer.GetAuditHistory) and sends each new one to the team in an
+* AuditVerdictMessage, so agents can build up reputations from it.
+ */
+type AuditEntry struct {
+	TeamID     uuid.UUID
+	AgentID    uuid.UUID // the audited agent
+	Iteration  int
+	Turn       int
+	Phase      AuditPhase
+	Voters     []uuid.UUID // agents that voted to audit this agent
+	Cost       int
+	Verdict    bool // true means the agent was found to have cheated
+	Sanction   Sanction
+	Appealed   bool
+	Overturned bool // the appeal succeeded and the sanction was reversed
+}
+
+// The agents whose vote asked for an audit of the given agent
+func AuditVoters(votes []Vote, agentID uuid.UUID) []uuid.UUID {
+	voters := []uuid.UUID{}
+	for _, vote := range votes {
+		if vote.IsVote == 1 && vote.VotedForID == agentID {
+			voters = append(voters, vote.VoterID)
+		}
+	}
+	return voters
+}
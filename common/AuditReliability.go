@@ -0,0 +1,98 @@
+package common
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+/*
+* How reliable audits are. An audit can miss an agent that did cheat, or
+* convict one that did not; both error rates shrink with the effort put into
+* the audit, which is what the audit costs plus TurnEffort for every turn of
+* history it covers. With a HalfEffort of effort the error rates are halved,
+* with twice as much they are a third, and so on.
+*
+* The zero value is a perfect audit, which always finds exactly what the AoA's
+* records show.
+ */
+type AuditReliability struct {
+	Miss          float64 `json:"miss"`          // chance of missing an infraction with no effort
+	FalsePositive float64 `json:"falsePositive"` // chance of convicting an honest agent with no effort
+	HalfEffort    float64 `json:"halfEffort"`    // effort that halves the error rates, 0 if effort does not help
+	TurnEffort    float64 `json:"turnEffort"`    // effort for every turn of history the audit covers
+}
+
+/*
+* AoAs that look back over a number of turns when they audit. Audits of AoAs
+* that do not implement it cover one turn.
+ */
+type AuditDurationHolder interface {
+	GetAuditDuration() int
+}
+
+/*
+* AoAs whose members vote on how many turns audits cover. The server passes
+* on the AuditDuration of every audit vote before the audit ballot is held.
+ */
+type AuditDurationVoter interface {
+	SetAuditDurationVotes(durations []int)
+}
+
+// Parameters of AuditReliability by their JSON names, e.g. {"miss": 0.2}
+func AuditReliabilityFromParams(params StrategyParams) (AuditReliability, error) {
+	r := AuditReliability{}
+	fields := map[string]*float64{
+		"miss":          &r.Miss,
+		"falsePositive": &r.FalsePositive,
+		"halfEffort":    &r.HalfEffort,
+		"turnEffort":    &r.TurnEffort,
+	}
+	for key, value := range params {
+		field, ok := fields[key]
+		if !ok {
+			return r, fmt.Errorf("audit reliability has no parameter %q (use miss, falsePositive, halfEffort, turnEffort)", key)
+		}
+		*field = value
+	}
+	return r, r.Validate()
+}
+
+func (r AuditReliability) Validate() error {
+	if r.Miss < 0 || r.Miss > 1 || r.FalsePositive < 0 || r.FalsePositive > 1 {
+		return fmt.Errorf("audit miss and false positive rates must be between 0 and 1")
+	}
+	if r.HalfEffort < 0 || r.TurnEffort < 0 {
+		return fmt.Errorf("audit halfEffort and turnEffort cannot be negative")
+	}
+	return nil
+}
+
+func (r AuditReliability) IsPerfect() bool {
+	return r.Miss == 0 && r.FalsePositive == 0
+}
+
+// Chances of catching a cheat and of convicting an honest agent, for an audit with this spend and duration
+func (r AuditReliability) Rates(spend int, duration int) (detection float64, falsePositive float64) {
+	scale := 1.0
+	if r.HalfEffort > 0 {
+		effort := float64(max(spend, 0)) + r.TurnEffort*float64(max(duration, 1))
+		scale = r.HalfEffort / (r.HalfEffort + effort)
+	}
+	return 1 - r.Miss*scale, r.FalsePositive * scale
+}
+
+/*
+* The verdict of an audit of an agent whose records show an infraction or
+* not: true means the agent is found to have cheated. Perfect audits draw no
+* random numbers, so games without noise play out as they always have.
+ */
+func (r AuditReliability) Verdict(infraction bool, spend int, duration int) bool {
+	if r.IsPerfect() {
+		return infraction
+	}
+	detection, falsePositive := r.Rates(spend, duration)
+	if infraction {
+		return rand.Float64() < detection
+	}
+	return rand.Float64() < falsePositive
+}
@@ -0,0 +1,93 @@
+package common
+
+import "github.com/google/uuid"
+
+/*
+* Ballots are how a team decides things by vote. A ballot puts a question and
+* its options to the team's eligible voters; each voter ranks the options by
+* index, most preferred first, or abstains, and the server tallies the
+* rankings once the ballot closes. Audit votes, orphan admission and AoA
+* amendments all go through ballots, and agents can open their own on
+* anything else (see IServer.OpenBallot).
+ */
+type BallotSpec struct {
+	TeamID    uuid.UUID
+	Question  string
+	Options   []string
+	Voters    []uuid.UUID       // who may vote, every member of the team if left out
+	Weights   map[uuid.UUID]int // votes each voter casts, 1 if left out
+	Quorum    float64           // share of the voting weight that must take part for the ballot to choose
+	Rule      string            // a voting rule (see voting.Rules) or BallotRuleThreshold, plurality if left out
+	Threshold float64           // for BallotRuleThreshold, the share of the voting weight the first option needs
+	Secret    bool              // secret ballots record the totals but not who voted how
+	Turns     int               // further turns the ballot stays open for; 0 closes it at the end of this turn
+}
+
+// The first option wins if it is the first choice of Threshold of the voting weight, otherwise the second
+const BallotRuleThreshold = "threshold"
+
+// A ballot that is still open
+type Ballot struct {
+	BallotSpec
+	ID        uuid.UUID
+	Iteration int
+	Turn      int                 // turn it was opened in
+	CloseTurn int                 // turn at the end of which it closes
+	Votes     map[uuid.UUID][]int // rankings cast so far, nil for secret ballots when read by agents
+}
+
+type BallotResult struct {
+	BallotSpec
+	BallotID  uuid.UUID
+	Iteration int
+	Turn      int                 // turn it closed in
+	Votes     map[uuid.UUID][]int // the rankings cast, nil for secret ballots
+	Eligible  int                 // voting weight of the eligible voters
+	Turnout   int                 // voting weight of the voters that took part
+	Scores    map[int]float64     // score of every option under the rule
+	Winner    int                 // index of the option chosen, -1 if none was (no votes, or no quorum)
+}
+
+// The option chosen, empty if none was
+func (r BallotResult) Chosen() string {
+	if r.Winner < 0 || r.Winner >= len(r.Options) {
+		return ""
+	}
+	return r.Options[r.Winner]
+}
+
+/*
+* Audit votes are put to a plurality ballot whose first option is NoAudit and
+* whose others are the team's members. A vote for an audit ranks the agent it
+* names, a vote against ranks NoAudit; the AoA decides who to audit from the
+* result (see IArticlesOfAssociation.GetVoteResult).
+ */
+const NoAudit = "none"
+
+// AoAs that give some members' audit votes more weight than others
+type AuditBallotPolicy interface {
+	GetAuditVoteWeights() map[uuid.UUID]int
+}
+
+// The agent with the most votes on an audit ballot and its votes, uuid.Nil if no agent got any
+func (r BallotResult) MostAuditVotes() (uuid.UUID, float64) {
+	best, bestVotes := uuid.Nil, 0.0
+	for i, option := range r.Options {
+		agentID, err := uuid.Parse(option)
+		if err != nil || i == 0 {
+			continue
+		}
+		if r.Scores[i] > bestVotes {
+			best, bestVotes = agentID, r.Scores[i]
+		}
+	}
+	return best, bestVotes
+}
+
+// Votes a voter casts on a ballot
+func (spec BallotSpec) WeightOf(voterID uuid.UUID) int {
+	if weight, ok := spec.Weights[voterID]; ok {
+		return weight
+	}
+	return 1
+}
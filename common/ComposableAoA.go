@@ -0,0 +1,367 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+/*
+* An AoA assembled from interchangeable rules (see AoAComponents.go) rather
+* than written by hand. Infractions are kept in an AuditRecord, one record per
+* turn: the contribution adds it and a withdrawal infraction increments it, as
+* in the fixed AoA. When an audited agent fails, the sanction rule decides
+* what happens to it and the server carries the sanction out.
+ */
+type ComposableAoA struct {
+	view              TeamView
+	contribution      ContributionRule
+	withdrawal        WithdrawalRule
+	auditTrigger      AuditTriggerRule
+	auditCost         AuditCostRule
+	withdrawalOrder   WithdrawalOrderRule
+	infraction        InfractionRule
+	verdict           VerdictRule
+	sanction          SanctionRule
+	amendmentMajority float64
+	auditRecord       *AuditRecord
+	offences          *OffenceTracker
+	appeal            AppealTerms
+	whistleblowing    WhistleblowerTerms
+	scoreBasis        ScoreBasis
+	scoreReports      map[uuid.UUID]ScoreReport // of the current turn
+}
+
+// A stock component and its parameters, defaults if left out
+type ComponentSpec struct {
+	Name   string
+	Params AoAParams
+}
+
+/*
+* The rules of a composable AoA. A rule whose name is left empty takes the
+* behaviour of the fixed AoA.
+ */
+type AoASpec struct {
+	Contribution      ComponentSpec      `json:"contribution" yaml:"contribution"`
+	Withdrawal        ComponentSpec      `json:"withdrawal" yaml:"withdrawal"`
+	AuditTrigger      ComponentSpec      `json:"auditTrigger" yaml:"auditTrigger"`
+	AuditCost         ComponentSpec      `json:"auditCost" yaml:"auditCost"`
+	WithdrawalOrder   ComponentSpec      `json:"withdrawalOrder" yaml:"withdrawalOrder"`
+	Infraction        ComponentSpec      `json:"infraction" yaml:"infraction"`
+	Verdict           ComponentSpec      `json:"verdict" yaml:"verdict"`
+	Sanction          ComponentSpec      `json:"sanction" yaml:"sanction"`
+	AmendmentMajority float64            `json:"amendmentMajority,omitempty" yaml:"amendmentMajority,omitempty"` // 0 for DefaultAmendmentMajority
+	Appeal            AppealTerms        `json:"appeal,omitempty" yaml:"appeal,omitempty"`                       // no appeals if left out
+	Whistleblowing    WhistleblowerTerms `json:"whistleblowing,omitempty" yaml:"whistleblowing,omitempty"`       // accusations ignored if left out
+	ScoreBasis        ScoreBasis         `json:"scoreBasis,omitempty" yaml:"scoreBasis,omitempty"`               // true scores if left out
+}
+
+// Stand-ins for rules an AoASpec leaves out, which behave like the fixed AoA
+var defaultComponents = map[string]ComponentSpec{
+	"contribution":    {Name: "all"},
+	"withdrawal":      {Name: "fixed", Params: AoAParams{"amount": 2}},
+	"auditTrigger":    {Name: "never"},
+	"auditCost":       {Name: "fixed", Params: AoAParams{"cost": 1}},
+	"withdrawalOrder": {Name: "random"},
+	"infraction":      {Name: "misreport"},
+	"verdict":         {Name: "window", Params: AoAParams{"turns": 1}},
+	"sanction":        {Name: "none"},
+}
+
+// The rule of each kind, with the stand-in filled in if it was left out
+func (spec ComponentSpec) orDefault(kind string) ComponentSpec {
+	if spec.Name != "" {
+		return spec
+	}
+	fallback := defaultComponents[kind]
+	params := fallback.Params.Copy()
+	for key, value := range spec.Params {
+		params[key] = value
+	}
+	return ComponentSpec{Name: fallback.Name, Params: params}
+}
+
+// The rules of the spec by kind, in the order of the libraries
+func (spec *AoASpec) rules() []*ComponentSpec {
+	return []*ComponentSpec{
+		&spec.Contribution, &spec.Withdrawal, &spec.AuditTrigger, &spec.AuditCost,
+		&spec.WithdrawalOrder, &spec.Infraction, &spec.Verdict, &spec.Sanction,
+	}
+}
+
+func buildComponent[T any](library *ComponentLibrary[T], spec ComponentSpec, err *error) T {
+	spec = spec.orDefault(library.Kind())
+	component, buildErr := library.Build(spec.Name, spec.Params)
+	if buildErr != nil && *err == nil {
+		*err = buildErr
+	}
+	return component
+}
+
+/*
+* The parameters of every rule of the spec, with their defaults, keyed by the
+* kind of rule, e.g. {"contribution.share": 0.75}. These are the parameters a
+* registered composable AoA takes.
+ */
+func (spec AoASpec) Params() (AoAParams, error) {
+	params := AoAParams{}
+	for i, rule := range spec.rules() {
+		library := componentLibraries[i]
+		resolved, err := library.resolve(rule.orDefault(library.Kind()))
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range resolved {
+			params[library.Kind()+"."+key] = value
+		}
+	}
+	return params, nil
+}
+
+// The spec with some of its rule parameters, keyed as by Params, overridden
+func (spec AoASpec) WithParams(params AoAParams) (AoASpec, error) {
+	rules := spec.rules()
+	for i := range rules {
+		*rules[i] = rules[i].orDefault(componentLibraries[i].Kind())
+		rules[i].Params = rules[i].Params.Copy()
+	}
+	for key, value := range params {
+		kind, param, _ := strings.Cut(key, ".")
+		found := false
+		for i, library := range componentLibraries {
+			if library.Kind() == kind {
+				rules[i].Params[param] = value
+				found = true
+			}
+		}
+		if !found {
+			return spec, fmt.Errorf("%q is not a rule parameter, e.g. contribution.share", key)
+		}
+	}
+	return spec, spec.Validate()
+}
+
+// Check that every rule of the spec exists and takes the parameters given
+func (spec AoASpec) Validate() error {
+	_, err := spec.Build(nil)
+	return err
+}
+
+// Build the AoA described by the spec for a team
+func (spec AoASpec) Build(team *Team) (*ComposableAoA, error) {
+	var err error
+	aoa := &ComposableAoA{
+		view:              TeamView{Team: team, Scores: make(map[uuid.UUID]int)},
+		contribution:      buildComponent(ContributionRules, spec.Contribution, &err),
+		withdrawal:        buildComponent(WithdrawalRules, spec.Withdrawal, &err),
+		auditTrigger:      buildComponent(AuditTriggerRules, spec.AuditTrigger, &err),
+		auditCost:         buildComponent(AuditCostRules, spec.AuditCost, &err),
+		withdrawalOrder:   buildComponent(WithdrawalOrderRules, spec.WithdrawalOrder, &err),
+		infraction:        buildComponent(InfractionRules, spec.Infraction, &err),
+		verdict:           buildComponent(VerdictRules, spec.Verdict, &err),
+		sanction:          buildComponent(SanctionRules, spec.Sanction, &err),
+		amendmentMajority: spec.AmendmentMajority,
+		appeal:            spec.Appeal,
+		whistleblowing:    spec.Whistleblowing,
+		scoreBasis:        spec.ScoreBasis,
+		scoreReports:      make(map[uuid.UUID]ScoreReport),
+		auditRecord:       NewAuditRecord(1),
+	}
+	if err != nil {
+		return nil, err
+	}
+	sanctionParams, _ := SanctionRules.resolve(spec.Sanction.orDefault(SanctionRules.Kind()))
+	aoa.offences = NewOffenceTracker(aoa.sanction, int(sanctionParams.Get("forgiveness", 0)))
+	if aoa.amendmentMajority < 0 || aoa.amendmentMajority > 1 {
+		return nil, fmt.Errorf("amendment majority must be between 0 and 1, got %g", aoa.amendmentMajority)
+	}
+	if err := aoa.appeal.Validate(); err != nil {
+		return nil, err
+	}
+	if err := aoa.whistleblowing.Validate(); err != nil {
+		return nil, err
+	}
+	if err := aoa.scoreBasis.Validate(); err != nil {
+		return nil, err
+	}
+	if aoa.amendmentMajority == 0 {
+		aoa.amendmentMajority = DefaultAmendmentMajority
+	}
+	return aoa, nil
+}
+
+/*
+* Register a composable AoA under an ID and a name, so that teams can vote for
+* it like any hand-written AoA. An invalid spec is a programming error.
+ */
+func RegisterComposableAoA(id int, name string, description string, spec AoASpec) {
+	params, err := spec.Params()
+	if err != nil {
+		panic(fmt.Sprintf("common: composable AoA %s: %v", name, err))
+	}
+	RegisterAoA(AoAInfo{
+		ID:          id,
+		Name:        name,
+		Description: description,
+		Params:      params,
+		Constructor: func(team *Team, params AoAParams) IArticlesOfAssociation {
+			tuned, err := spec.WithParams(params)
+			if err != nil {
+				aoaLog.Warn("Ignoring invalid AoA parameters", "aoa", name, "err", err)
+				tuned = spec
+			}
+			aoa, _ := tuned.Build(team)
+			return aoa
+		},
+	})
+}
+
+/*
+* With declared scores as the basis, an agent is expected to contribute from
+* the score it would have had if it rolled what it declared this turn.
+ */
+func (c *ComposableAoA) GetExpectedContribution(agentId uuid.UUID, agentScore int) int {
+	if report, ok := c.scoreReports[agentId]; ok && c.scoreBasis == DeclaredScores {
+		agentScore = report.DeclaredScore(agentScore)
+	}
+	return c.contribution(c.view, agentId, agentScore)
+}
+
+// Under-declaring a turn score that contributions are based on is an infraction too
+func (c *ComposableAoA) SetContributionAuditResult(agentId uuid.UUID, agentScore int, agentActualContribution int, agentStatedContribution int) {
+	infraction := 0
+	if c.infraction(ContributionAudit, c.GetExpectedContribution(agentId, agentScore), agentActualContribution, agentStatedContribution) {
+		infraction = 1
+	}
+	if report, ok := c.scoreReports[agentId]; ok && c.scoreBasis == DeclaredScores && report.Declared < report.Rolled {
+		infraction = 1
+	}
+	c.auditRecord.AddRecord(agentId, infraction)
+	c.view.Scores[agentId] = agentScore - agentActualContribution
+}
+
+func (c *ComposableAoA) GetContributionAuditResult(agentId uuid.UUID) bool {
+	return c.judge(agentId)
+}
+
+func (c *ComposableAoA) GetExpectedWithdrawal(agentId uuid.UUID, agentScore int, commonPool int) int {
+	return c.withdrawal(c.view, agentId, agentScore, commonPool)
+}
+
+func (c *ComposableAoA) SetWithdrawalAuditResult(agentId uuid.UUID, agentScore int, agentActualWithdrawal int, agentStatedWithdrawal int, commonPool int) {
+	if c.infraction(WithdrawalAudit, c.GetExpectedWithdrawal(agentId, agentScore, commonPool), agentActualWithdrawal, agentStatedWithdrawal) {
+		c.auditRecord.IncrementLastRecord(agentId)
+	}
+}
+
+func (c *ComposableAoA) GetWithdrawalAuditResult(agentId uuid.UUID) bool {
+	return c.judge(agentId)
+}
+
+// true means the agent failed the audit
+func (c *ComposableAoA) judge(agentId uuid.UUID) bool {
+	return c.verdict(c.auditRecord, agentId)
+}
+
+func (c *ComposableAoA) GetAuditCost(commonPool int) int {
+	return c.auditCost(commonPool)
+}
+
+func (c *ComposableAoA) GetAuditDuration() int {
+	return c.auditRecord.GetAuditDuration()
+}
+
+func (c *ComposableAoA) GetVoteResult(ballot BallotResult) uuid.UUID {
+	return c.auditTrigger(c.view, ballot)
+}
+
+func (c *ComposableAoA) GetWithdrawalOrder(agentIDs []uuid.UUID) []uuid.UUID {
+	return c.withdrawalOrder(c.view, agentIDs)
+}
+
+func (c *ComposableAoA) RunPostContributionAoaLogic(team *Team, agentMap map[uuid.UUID]IExtendedAgent) {
+}
+
+func (c *ComposableAoA) ResourceAllocation(agentScores map[uuid.UUID]int, remainingResources int) map[uuid.UUID]int {
+	return make(map[uuid.UUID]int)
+}
+
+func (c *ComposableAoA) GetAmendmentMajority() float64 {
+	return c.amendmentMajority
+}
+
+// Asked once for every audit the agent is found to fail, which is what the sanction rule counts
+func (c *ComposableAoA) GetSanction(agentId uuid.UUID, commonPool int) Sanction {
+	return c.offences.GetSanction(agentId, commonPool)
+}
+
+func (c *ComposableAoA) GetOffences(agentId uuid.UUID) int {
+	return c.offences.GetOffences(agentId)
+}
+
+func (c *ComposableAoA) SetOffences(agentId uuid.UUID, offences int) {
+	c.offences.SetOffences(agentId, offences)
+}
+
+func (c *ComposableAoA) PassTurn() {
+	c.offences.PassTurn()
+}
+
+func (c *ComposableAoA) Pardon(agentId uuid.UUID) {
+	c.offences.Pardon(agentId)
+}
+
+func (c *ComposableAoA) GetAppealTerms() AppealTerms {
+	return c.appeal
+}
+
+func (c *ComposableAoA) GetWhistleblowerTerms() WhistleblowerTerms {
+	return c.whistleblowing
+}
+
+func (c *ComposableAoA) ResetScoreReports() {
+	c.scoreReports = make(map[uuid.UUID]ScoreReport)
+}
+
+func (c *ComposableAoA) SetScoreReport(report ScoreReport) {
+	c.scoreReports[report.AgentID] = report
+}
+
+func (c *ComposableAoA) OnMemberJoined(agentId uuid.UUID) {}
+
+func (c *ComposableAoA) OnMemberLeft(agentId uuid.UUID) {
+	c.auditRecord.RemoveRecord(agentId)
+	delete(c.view.Scores, agentId)
+	c.offences.Forget(agentId)
+	delete(c.scoreReports, agentId)
+}
+
+func (c *ComposableAoA) OnMemberDied(agentId uuid.UUID) {
+	c.OnMemberLeft(agentId)
+}
+
+func (c *ComposableAoA) GetAuditHistory() map[uuid.UUID][]int {
+	return c.auditRecord.GetAuditMap()
+}
+
+func (c *ComposableAoA) SetAuditHistory(history map[uuid.UUID][]int) {
+	for agentID, infractions := range history {
+		c.auditRecord.auditMap[agentID] = append([]int{}, infractions...)
+	}
+}
+
+func init() {
+	RegisterComposableAoA(7, "commons", "composed AoA; the pool is shared equally, majority-called audits and three strikes",
+		AoASpec{
+			Contribution:    ComponentSpec{Name: "share", Params: AoAParams{"share": 0.5}},
+			Withdrawal:      ComponentSpec{Name: "equal-share"},
+			AuditTrigger:    ComponentSpec{Name: "majority"},
+			AuditCost:       ComponentSpec{Name: "pool-share"},
+			WithdrawalOrder: ComponentSpec{Name: "random"},
+			Infraction:      ComponentSpec{Name: "overstate"},
+			Verdict:         ComponentSpec{Name: "window", Params: AoAParams{"turns": 3}},
+			Sanction:        ComponentSpec{Name: "strikes", Params: AoAParams{"strikes": 3}},
+		})
+}
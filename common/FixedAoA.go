@@ -1,11 +1,6 @@
 package common
 
-import (
-	"math/rand"
-	"time"
-
-	"github.com/google/uuid"
-)
+import "github.com/google/uuid"
 
 type FixedAoA struct {
 	auditRecord *AuditRecord
@@ -53,38 +48,29 @@ func (f *FixedAoA) GetAuditCost(commonPool int) int {
 	return f.auditRecord.GetAuditCost()
 }
 
+func (f *FixedAoA) GetAuditDuration() int {
+	return f.auditRecord.GetAuditDuration()
+}
+
 // MUST return UUID nil if audit should not be executed
-// Otherwise, implement a voting mechanism to determine the agent to be audited
-// and return its UUID
-func (f *FixedAoA) GetVoteResult(votes []Vote) uuid.UUID {
-	if len(votes) == 0 {
-		return uuid.Nil
-	}
+func (f *FixedAoA) GetVoteResult(ballot BallotResult) uuid.UUID {
+	return uuid.Nil
+}
 
+// Audits cover the average of the durations voted for
+func (f *FixedAoA) SetAuditDurationVotes(durations []int) {
+	if len(durations) == 0 {
+		return
+	}
 	duration := 0
-	for _, vote := range votes {
-		duration += vote.AuditDuration
+	for _, d := range durations {
+		duration += d
 	}
-	duration /= len(votes)
-
-	f.auditRecord.SetAuditDuration(duration)
-	return uuid.Nil
+	f.auditRecord.SetAuditDuration(duration / len(durations))
 }
 
 func (t *FixedAoA) GetWithdrawalOrder(agentIDs []uuid.UUID) []uuid.UUID {
-	// Seed the random number generator to ensure different shuffles each time
-	rand.Seed(time.Now().UnixNano())
-
-	// Create a copy of the agentIDs to avoid modifying the original list
-	shuffledAgents := make([]uuid.UUID, len(agentIDs))
-	copy(shuffledAgents, agentIDs)
-
-	// Shuffle the agent list
-	rand.Shuffle(len(shuffledAgents), func(i, j int) {
-		shuffledAgents[i], shuffledAgents[j] = shuffledAgents[j], shuffledAgents[i]
-	})
-
-	return shuffledAgents
+	return shuffledOrder(agentIDs)
 }
 
 func (t *FixedAoA) RunPostContributionAoaLogic(team *Team, agentMap map[uuid.UUID]IExtendedAgent) {}
@@ -93,9 +79,45 @@ func (f *FixedAoA) ResourceAllocation(agentScores map[uuid.UUID]int, remainingRe
 	return make(map[uuid.UUID]int)
 }
 
+func (f *FixedAoA) GetAmendmentMajority() float64 {
+	return DefaultAmendmentMajority
+}
+
+func (f *FixedAoA) OnMemberJoined(agentId uuid.UUID) {}
+
+func (f *FixedAoA) OnMemberLeft(agentId uuid.UUID) {
+	f.auditRecord.RemoveRecord(agentId)
+}
+
+func (f *FixedAoA) OnMemberDied(agentId uuid.UUID) {
+	f.OnMemberLeft(agentId)
+}
+
+func (f *FixedAoA) GetAuditHistory() map[uuid.UUID][]int {
+	return f.auditRecord.GetAuditMap()
+}
+
+func (f *FixedAoA) SetAuditHistory(history map[uuid.UUID][]int) {
+	for agentID, infractions := range history {
+		f.auditRecord.auditMap[agentID] = append([]int{}, infractions...)
+	}
+}
+
 func CreateFixedAoA(duration int) IArticlesOfAssociation {
 	auditRecord := NewAuditRecord(duration)
 	return &FixedAoA{
 		auditRecord: auditRecord,
 	}
 }
+
+func init() {
+	RegisterAoA(AoAInfo{
+		ID:          DefaultAoAID,
+		Name:        "fixed",
+		Description: "everyone contributes their whole score; withdrawals in random order",
+		Params:      AoAParams{"duration": 1},
+		Constructor: func(team *Team, params AoAParams) IArticlesOfAssociation {
+			return CreateFixedAoA(int(params.Get("duration", 1)))
+		},
+	})
+}
@@ -24,6 +24,7 @@ type IExtendedAgent interface {
 	GetActualContribution(instance IExtendedAgent) int
 	GetActualWithdrawal(instance IExtendedAgent) int
 	GetStatedContribution(instance IExtendedAgent) int
+	GetStatedTurnScore(instance IExtendedAgent) int
 	GetStatedWithdrawal(instance IExtendedAgent) int
 
 	// Setters
@@ -40,6 +41,12 @@ type IExtendedAgent interface {
 	DecideTeamForming(agentInfoList []ExposedAgentInfo) []uuid.UUID
 	StickOrAgain(accumulatedScore int, prevRoll int) bool
 	VoteOnAgentEntry(candidateID uuid.UUID) bool
+	ProposeAoAAmendment(instance IExtendedAgent) *AoAAmendment
+	VoteOnAoAAmendment(amendment AoAAmendment) bool
+	VoteOnBallot(ballot Ballot) []int
+	VoteForRole(role RoleSpec, candidates []uuid.UUID) []uuid.UUID
+	AppealVerdict(entry AuditEntry, terms AppealTerms) bool
+	VoteOnAppeal(entry AuditEntry) bool
 	StickOrAgainFor(agentId uuid.UUID, accumulatedScore int, prevRoll int) int
 
 	// Messaging functions
@@ -50,12 +57,15 @@ type IExtendedAgent interface {
 	HandleContributionMessage(msg *ContributionMessage)
 	HandleAgentOpinionRequestMessage(msg *AgentOpinionRequestMessage)
 	HandleAgentOpinionResponseMessage(msg *AgentOpinionResponseMessage)
+	HandleAuditVerdictMessage(msg *AuditVerdictMessage)
+	HandleAccusationMessage(msg *AccusationMessage)
+	StateScoreToTeam(instance IExtendedAgent)
 	StateContributionToTeam(instance IExtendedAgent)
 	StateWithdrawalToTeam(instance IExtendedAgent)
 
 	// Info
 	GetExposedInfo() ExposedAgentInfo
-	CreateScoreReportMessage() *ScoreReportMessage
+	CreateScoreReportMessage(turnScore int) *ScoreReportMessage
 	CreateContributionMessage(statedAmount int) *ContributionMessage
 	CreateWithdrawalMessage(statedAmount int) *WithdrawalMessage
 	CreateAgentOpinionRequestMessage(agentID uuid.UUID) *AgentOpinionRequestMessage
@@ -66,11 +76,14 @@ type IExtendedAgent interface {
 	GetContributionAuditVote() Vote
 	GetWithdrawalAuditVote() Vote
 	GetTrueSomasTeamID() int
+	GetStrategyName() string
+	GetStrategyParams() StrategyParams
+	SetStrategy(name string, params StrategyParams)
 
 	// Data Recording
 	RecordAgentStatus(instance IExtendedAgent) gameRecorder.AgentRecord
 
 	// Team 1 specific functions
-	Team1_ChairUpdateRanks(rankMap map[uuid.UUID]int) map[uuid.UUID]int
+	Team1_ChairUpdateRanks(currentRanks map[uuid.UUID]int, guidelineRanks map[uuid.UUID]int) map[uuid.UUID]int
 	Team1_VoteOnRankBoundaries(initialBoundaries [5]int) [5]int
 }
@@ -21,6 +21,30 @@ type IServer interface {
 	GetTeamFromTeamID(teamID uuid.UUID) *Team
 	GetTeamIDs() []uuid.UUID
 	GetTeamCommonPool(teamID uuid.UUID) int
+	GetAuditHistory(teamID uuid.UUID) []AuditEntry
+
+	// Accusations of the current turn (see Accusation)
+	FileAccusation(accusation Accusation) error
+	GetAccusations(teamID uuid.UUID) []Accusation
+
+	// Sanctions in force (see Sanction)
+	IsWithdrawalBanned(agentID uuid.UUID) bool
+	IsVoteBanned(agentID uuid.UUID) bool
+
+	// Team ballots (see Ballot)
+	OpenBallot(spec BallotSpec) (uuid.UUID, error)
+	CastBallot(ballotID uuid.UUID, voterID uuid.UUID, ranking []int) error
+	GetOpenBallots(teamID uuid.UUID) []Ballot
+	GetBallotResults(teamID uuid.UUID) []BallotResult
+
+	// Team roles (see RoleHolder)
+	GetRoles(teamID uuid.UUID) []RoleTerm
+	GetRoleHolders(teamID uuid.UUID, role Role) []uuid.UUID
+	Impeach(teamID uuid.UUID, role Role, holderID uuid.UUID, proposerID uuid.UUID) error
+
+	// Game clock
+	GetIterationNumber() int
+	GetTurnNumber() int
 
 	// Debug functions
 	LogAgentStatus()
@@ -11,6 +11,7 @@ type TeamFormationMessage struct {
 	Message   string
 }
 
+// Sent by an agent to its team after rolling, declaring its turn score (see ScoreReport)
 type ScoreReportMessage struct {
 	message.BaseMessage
 	TurnScore int
@@ -40,6 +41,18 @@ type AgentOpinionResponseMessage struct {
 	AgentOpinion int
 }
 
+// Sent by an agent to its team when it accuses a teammate (see Accusation)
+type AccusationMessage struct {
+	message.BaseMessage
+	Accusation Accusation
+}
+
+// Sent by the server (so from uuid.Nil) to every member of a team after one of its audits
+type AuditVerdictMessage struct {
+	message.BaseMessage
+	Entry AuditEntry
+}
+
 func (msg *TeamFormationMessage) InvokeMessageHandler(agent IExtendedAgent) {
 	agent.HandleTeamFormationMessage(msg)
 }
@@ -63,3 +76,11 @@ func (msg *AgentOpinionRequestMessage) InvokeMessageHandler(agent IExtendedAgent
 func (msg *AgentOpinionResponseMessage) InvokeMessageHandler(agent IExtendedAgent) {
 	agent.HandleAgentOpinionResponseMessage(msg)
 }
+
+func (msg *AccusationMessage) InvokeMessageHandler(agent IExtendedAgent) {
+	agent.HandleAccusationMessage(msg)
+}
+
+func (msg *AuditVerdictMessage) InvokeMessageHandler(agent IExtendedAgent) {
+	agent.HandleAuditVerdictMessage(msg)
+}
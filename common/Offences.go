@@ -0,0 +1,139 @@
+package common
+
+import "github.com/google/uuid"
+
+/*
+* Sanctions that escalate with repeated offences: the first offence gets the
+* first step, the second the second and so on, and every offence after the
+* last step gets the last step again.
+ */
+type SanctionLadder []Sanction
+
+// Warning, fine, loss of withdrawal rights, loss of vote, expulsion
+func DefaultSanctionLadder() SanctionLadder {
+	return SanctionLadderFromParams(nil)
+}
+
+/*
+* The default ladder with the size of its steps from the parameters "fine",
+* "withdrawalBan" and "voteBan". A step whose parameter is 0 is left out.
+ */
+func SanctionLadderFromParams(params AoAParams) SanctionLadder {
+	ladder := SanctionLadder{{Warning: true}}
+	if fine := int(params.Get("fine", 5)); fine > 0 {
+		ladder = append(ladder, Sanction{Fine: fine})
+	}
+	if turns := int(params.Get("withdrawalBan", 2)); turns > 0 {
+		ladder = append(ladder, Sanction{WithdrawalBan: turns})
+	}
+	if turns := int(params.Get("voteBan", 3)); turns > 0 {
+		ladder = append(ladder, Sanction{VoteBan: turns})
+	}
+	return append(ladder, Sanction{Expel: true})
+}
+
+// The sanction for an agent with this many offences
+func (l SanctionLadder) Step(offences int) Sanction {
+	if len(l) == 0 || offences <= 0 {
+		return Sanction{}
+	}
+	return l[min(offences, len(l))-1]
+}
+
+// The ladder as a sanction rule, which counts failed audits as offences
+func (l SanctionLadder) Rule() SanctionRule {
+	return func(failedAudits int, commonPool int) Sanction { return l.Step(failedAudits) }
+}
+
+/*
+* AoAs that keep count of the offences of their members. The server tells
+* them when a turn has passed, so that offences can be forgiven.
+ */
+type OffenceKeeper interface {
+	Sanctioner
+	GetOffences(agentId uuid.UUID) int
+	SetOffences(agentId uuid.UUID, offences int)
+	PassTurn()
+	Pardon(agentId uuid.UUID)
+}
+
+/*
+* Counts the offences of each agent and sanctions them by a rule, usually a
+* SanctionLadder. With a forgiveness of N, an offence is forgotten after every
+* N turns the agent goes without a new one; with 0 offences are never
+* forgotten. AoAs can embed it to become an OffenceKeeper.
+ */
+type OffenceTracker struct {
+	rule        SanctionRule
+	forgiveness int
+	offences    map[uuid.UUID]int
+	cleanTurns  map[uuid.UUID]int
+}
+
+func NewOffenceTracker(rule SanctionRule, forgiveness int) *OffenceTracker {
+	return &OffenceTracker{
+		rule:        rule,
+		forgiveness: max(forgiveness, 0),
+		offences:    make(map[uuid.UUID]int),
+		cleanTurns:  make(map[uuid.UUID]int),
+	}
+}
+
+// Asked once for every audit the agent is found to fail, which counts as an offence
+func (o *OffenceTracker) GetSanction(agentId uuid.UUID, commonPool int) Sanction {
+	return o.rule(o.RecordOffence(agentId), commonPool)
+}
+
+// Count an offence, returning how many the agent now has
+func (o *OffenceTracker) RecordOffence(agentId uuid.UUID) int {
+	o.offences[agentId]++
+	// the turn of the offence is not a clean one
+	o.cleanTurns[agentId] = -1
+	return o.offences[agentId]
+}
+
+func (o *OffenceTracker) GetOffences(agentId uuid.UUID) int {
+	return o.offences[agentId]
+}
+
+// Set the agent's offence count, e.g. carried over from the team's previous AoA
+func (o *OffenceTracker) SetOffences(agentId uuid.UUID, offences int) {
+	if offences <= 0 {
+		o.Forget(agentId)
+		return
+	}
+	o.offences[agentId] = offences
+	o.cleanTurns[agentId] = 0
+}
+
+// Take back the agent's latest offence, e.g. when its verdict is overturned
+func (o *OffenceTracker) Pardon(agentId uuid.UUID) {
+	if o.offences[agentId] <= 1 {
+		o.Forget(agentId)
+		return
+	}
+	o.offences[agentId]--
+}
+
+// Forgive an offence of every agent that has gone long enough without one
+func (o *OffenceTracker) PassTurn() {
+	if o.forgiveness == 0 {
+		return
+	}
+	for agentId := range o.offences {
+		o.cleanTurns[agentId]++
+		if o.cleanTurns[agentId] < o.forgiveness {
+			continue
+		}
+		o.cleanTurns[agentId] = 0
+		if o.offences[agentId]--; o.offences[agentId] == 0 {
+			o.Forget(agentId)
+		}
+	}
+}
+
+// Forget the offences of an agent, e.g. one that left the team
+func (o *OffenceTracker) Forget(agentId uuid.UUID) {
+	delete(o.offences, agentId)
+	delete(o.cleanTurns, agentId)
+}
@@ -0,0 +1,105 @@
+package common
+
+import "github.com/google/uuid"
+
+/*
+* Team roles. An AoA that gives some members a say or a share the others do
+* not have declares the roles it needs (see RoleHolder); the server elects
+* the holders with the method each role asks for, re-elects them when their
+* term is up or a seat falls vacant, lets the team impeach them, and tells the
+* AoA who holds what at the start of every turn. AoA authors can then write
+* "the leader decides" without running elections of their own.
+ */
+type Role string
+
+const (
+	RoleLeader    Role = "leader"
+	RoleChair     Role = "chair"
+	RoleAuditor   Role = "auditor"
+	RoleTreasurer Role = "treasurer"
+)
+
+type RoleSpec struct {
+	Role                Role
+	Seats               int     // holders at a time, 1 if left out
+	Method              string  // how holders are elected (see the server's ElectionMethods), "vote" if left out
+	Term                int     // turns a holder serves before the next election, 1 if left out
+	ImpeachmentMajority float64 // share of the team that can remove a holder early, a simple majority if left out
+}
+
+func (spec RoleSpec) SeatCount() int {
+	return max(spec.Seats, 1)
+}
+
+func (spec RoleSpec) TermLength() int {
+	return max(spec.Term, 1)
+}
+
+func (spec RoleSpec) ElectionMethod() string {
+	if spec.Method == "" {
+		return "vote"
+	}
+	return spec.Method
+}
+
+func (spec RoleSpec) Majority() float64 {
+	if spec.ImpeachmentMajority <= 0 {
+		return 0.5
+	}
+	return spec.ImpeachmentMajority
+}
+
+// Implemented by AoAs that give roles to some of their members
+type RoleHolder interface {
+	GetRoles() []RoleSpec
+	SetRoleHolders(role Role, holders []uuid.UUID)
+}
+
+/*
+* AoAs that rank their members. Ranks start at 1; the server weights the
+* "rank-lottery" election method by them and records their changes.
+ */
+type MemberRanker interface {
+	GetMemberRanks() map[uuid.UUID]int
+}
+
+/*
+* The roles of an AoA and who holds them. AoAs can embed it to implement
+* RoleHolder and look holders up with Holders and Holds.
+ */
+type TeamRoles struct {
+	specs   []RoleSpec
+	holders map[Role][]uuid.UUID
+}
+
+func NewTeamRoles(specs ...RoleSpec) *TeamRoles {
+	return &TeamRoles{specs: specs, holders: make(map[Role][]uuid.UUID)}
+}
+
+func (r *TeamRoles) GetRoles() []RoleSpec {
+	return append([]RoleSpec{}, r.specs...)
+}
+
+func (r *TeamRoles) SetRoleHolders(role Role, holders []uuid.UUID) {
+	r.holders[role] = append([]uuid.UUID{}, holders...)
+}
+
+func (r *TeamRoles) Holders(role Role) []uuid.UUID {
+	return append([]uuid.UUID{}, r.holders[role]...)
+}
+
+func (r *TeamRoles) Holds(agentID uuid.UUID, role Role) bool {
+	for _, holder := range r.holders[role] {
+		if holder == agentID {
+			return true
+		}
+	}
+	return false
+}
+
+// A role as the server reports it to agents
+type RoleTerm struct {
+	RoleSpec
+	Holders []uuid.UUID
+	Served  int // turns served of the current term
+}
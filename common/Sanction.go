@@ -0,0 +1,25 @@
+package common
+
+import "github.com/google/uuid"
+
+// What happens to an agent that failed an audit
+type Sanction struct {
+	Warning       bool `json:"warning,omitempty"`       // the team lets the agent off with a warning
+	Fine          int  `json:"fine,omitempty"`          // paid out of the agent's score into the common pool
+	WithdrawalBan int  `json:"withdrawalBan,omitempty"` // withdrawal phases the agent sits out
+	VoteBan       int  `json:"voteBan,omitempty"`       // turns after this one the agent's votes do not count
+	Expel         bool `json:"expel,omitempty"`         // the agent leaves the team and becomes an orphan
+}
+
+func (s Sanction) IsZero() bool {
+	return !s.Warning && s.Fine == 0 && s.WithdrawalBan == 0 && s.VoteBan == 0 && !s.Expel
+}
+
+/*
+* AoAs that sanction agents that fail an audit. The server asks for the
+* sanction straight after a failed audit and carries it out. AoAs that do not
+* implement it leave the sanctioning to the agents themselves.
+ */
+type Sanctioner interface {
+	GetSanction(agentId uuid.UUID, commonPool int) Sanction
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+/*
+* The turn score an agent declared to its team after rolling the dice, kept by
+* the server against what it really rolled. Teammates only ever see the
+* declaration, in the agent's ScoreReportMessage.
+ */
+type ScoreReport struct {
+	AgentID  uuid.UUID
+	Declared int
+	Rolled   int
+}
+
+func (r ScoreReport) Misreported() bool {
+	return r.Declared != r.Rolled
+}
+
+// The score an agent that has the given true score would have had it rolled what it declared
+func (r ScoreReport) DeclaredScore(trueScore int) int {
+	return trueScore - r.Rolled + r.Declared
+}
+
+// Which scores an AoA bases its expected contributions on
+type ScoreBasis string
+
+const (
+	TrueScores     ScoreBasis = "true"
+	DeclaredScores ScoreBasis = "declared"
+)
+
+func (b ScoreBasis) Validate() error {
+	switch b {
+	case "", TrueScores, DeclaredScores:
+		return nil
+	}
+	return fmt.Errorf("unknown score basis %q (use %s or %s)", b, TrueScores, DeclaredScores)
+}
+
+/*
+* AoAs told what their members declared and rolled. Each turn the reports of
+* the last turn are reset first, then every member's report is set once all
+* have declared.
+ */
+type ScoreReportPolicy interface {
+	ResetScoreReports()
+	SetScoreReport(report ScoreReport)
+}
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Numeric parameters of an agent's strategy, e.g. {"lieRate": 0.3}. Strategies
+// read them with Get so that a missing parameter falls back to its default.
+type StrategyParams map[string]float64
+
+func (p StrategyParams) Get(key string, fallback float64) float64 {
+	if value, ok := p[key]; ok {
+		return value
+	}
+	return fallback
+}
+
+func (p StrategyParams) Copy() StrategyParams {
+	copied := make(StrategyParams, len(p))
+	for key, value := range p {
+		copied[key] = value
+	}
+	return copied
+}
+
+// Sorted key=value pairs, e.g. "greed=0.5,lieRate=0.3"
+func (p StrategyParams) String() string {
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%g", key, p[key]))
+	}
+	return strings.Join(pairs, ",")
+}
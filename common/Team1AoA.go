@@ -3,16 +3,19 @@ package common
 import (
 	"container/list"
 	// "errors"
-	"log"
-	"math/rand"
 	"sort"
 
+	"github.com/ADimoska/SOMASExtended/logging"
+
 	// "github.com/ADimoska/SOMASExtended/agents"
 	// "github.com/MattSScott/basePlatformSOMAS/v2/pkg/server"
 	"github.com/google/uuid"
 )
 
+var aoaLog = logging.For(logging.ComponentAoA)
+
 type Team1AoA struct {
+	*TeamRoles
 	auditResult      map[uuid.UUID]*list.List
 	ranking          map[uuid.UUID]int
 	rankBoundary     [5]int
@@ -107,35 +110,27 @@ func (t *Team1AoA) GetAuditCost(commonPool int) int {
 	return 5
 }
 
-func (t *Team1AoA) GetVoteResult(votes []Vote) uuid.UUID {
-	// Count total votes
-	totalVotes := 0
-	voteMap := make(map[uuid.UUID]int)
-	highestVotes := -1
-	highestVotedID := uuid.Nil
-	for _, vote := range votes {
-		totalVotes += vote.IsVote
-		if vote.IsVote == 1 { // Should agents who didnt want to vote, get a vote if majority wants to?
-			voteMap[vote.VotedForID]++
-		}
-		// Check if this ID has the highest votes
-		if voteMap[vote.VotedForID] > highestVotes {
-			highestVotedID = vote.VotedForID
-			highestVotes = voteMap[vote.VotedForID]
-		}
-	}
-	if totalVotes <= 0 {
-		return uuid.Nil // Majority does not want to vote
+// The agent with the most votes is audited if anyone voted for an audit
+func (t *Team1AoA) GetVoteResult(ballot BallotResult) uuid.UUID {
+	agentID, _ := ballot.MostAuditVotes()
+	return agentID
+}
+
+// true means the agent cheated in its latest contribution or withdrawal
+func (t *Team1AoA) latestAuditResult(agentId uuid.UUID) bool {
+	results := t.auditResult[agentId]
+	if results == nil || results.Len() == 0 {
+		return false
 	}
-	return highestVotedID
+	return results.Back().Value.(bool)
 }
 
 func (t *Team1AoA) GetContributionAuditResult(agentId uuid.UUID) bool {
-	return t.auditResult[agentId].Back().Value.(int) == 1
+	return t.latestAuditResult(agentId)
 }
 
 func (t *Team1AoA) GetWithdrawalAuditResult(agentId uuid.UUID) bool {
-	return t.auditResult[agentId].Back().Value.(int) == 1
+	return t.latestAuditResult(agentId)
 }
 
 func (t *Team1AoA) GetWithdrawalOrder(agentIDs []uuid.UUID) []uuid.UUID {
@@ -146,118 +141,103 @@ func (t *Team1AoA) GetWithdrawalOrder(agentIDs []uuid.UUID) []uuid.UUID {
 	return agentIDs
 }
 
-// WeightedRandomSelection selects one agent based on weights derived from ranks.
-func (t *Team1AoA) WeightedRandomSelection(agentIds []uuid.UUID) uuid.UUID {
-	if len(agentIds) == 0 {
-		log.Fatal("No agents to select from")
-	}
+/*
+* Team 1's governance, run by the server after the contribution audit of every
+* turn. Members first vote on the rank boundaries and the median vote for each
+* boundary is adopted. The chairs then propose new ranks, starting from the
+* guideline ranks the boundaries give for each member's recent contributions.
+* If the chairs agree their ranks are adopted; if not, the ranks stay as they
+* are and each chair drops a rank.
+ */
+func (t *Team1AoA) RunPostContributionAoaLogic(team *Team, agentMap map[uuid.UUID]IExtendedAgent) {
+	t.voteOnRankBoundaries(team, agentMap)
 
-	totalWeight := 0
-	for _, agentId := range agentIds {
-		totalWeight += t.ranking[agentId]
+	guideline := make(map[uuid.UUID]int, len(t.ranking))
+	for agentId := range t.ranking {
+		guideline[agentId] = t.GetAgentNewRank(agentId)
 	}
-	if totalWeight == 0 {
-		log.Fatal("All agents have 0 weight")
+	chairs := []uuid.UUID{}
+	var agreed map[uuid.UUID]int
+	chairsAgree := true
+	for _, chairId := range t.Holders(RoleChair) {
+		chair, ok := agentMap[chairId]
+		if !ok {
+			continue
+		}
+		chairs = append(chairs, chairId)
+		proposal := t.cleanRanks(chair.Team1_ChairUpdateRanks(copyRanks(t.ranking), copyRanks(guideline)))
+		if agreed == nil {
+			agreed = proposal
+		} else if !mapsEqual(agreed, proposal) {
+			chairsAgree = false
+		}
 	}
-
-	randomNumber := rand.Intn(totalWeight) + 1
-	cumulativeWeight := 0
-	for _, agentId := range agentIds {
-		cumulativeWeight += t.ranking[agentId]
-		if cumulativeWeight >= randomNumber {
-			return agentId
+	if agreed == nil {
+		return
+	}
+	if !chairsAgree {
+		for _, chairId := range chairs {
+			t.ranking[chairId] = max(t.ranking[chairId]-1, 1)
 		}
+		return
 	}
-
-	log.Fatal("Failed to select an agent")
-	return uuid.Nil // This line will never be reached due to log.Fatal
+	t.ranking = agreed
 }
 
-// SelectNChairs selects n distinct agents to be chairs, with probability of selection based on rank.
-func (t *Team1AoA) SelectNChairs(agentIds []uuid.UUID, n int) []uuid.UUID {
-	if len(agentIds) < n {
-		log.Fatal("not enough agents to select from")
-	}
-
-	selectedChairs := make([]uuid.UUID, 0, n)
-	remainingAgents := make([]uuid.UUID, len(agentIds))
-	copy(remainingAgents, agentIds)
-
-	for i := 0; i < n; i++ {
-		agent := t.WeightedRandomSelection(remainingAgents)
-		selectedChairs = append(selectedChairs, agent)
-
-		// Remove the selected agent from remainingAgents
-		// Find the index of the selected agent
-		index := -1
-		for j, id := range remainingAgents {
-			if id == agent {
-				index = j
-				break
-			}
+// Each member votes on the rank boundaries; the median of the votes for each boundary wins
+func (t *Team1AoA) voteOnRankBoundaries(team *Team, agentMap map[uuid.UUID]IExtendedAgent) {
+	votes := [len(t.rankBoundary)][]int{}
+	for _, agentId := range team.Agents {
+		agent, ok := agentMap[agentId]
+		if !ok {
+			continue
 		}
-
-		if index == -1 {
-			log.Fatal("selected agent not found in remainingAgents")
+		vote := agent.Team1_VoteOnRankBoundaries(t.rankBoundary)
+		for i := range vote {
+			votes[i] = append(votes[i], max(vote[i], 0))
 		}
-
-		// Remove the agent by swapping with the last element and truncating the slice
-		remainingAgents[index] = remainingAgents[len(remainingAgents)-1]
-		remainingAgents = remainingAgents[:len(remainingAgents)-1]
 	}
-
-	return selectedChairs
+	if len(votes[0]) == 0 {
+		return
+	}
+	boundaries := t.rankBoundary
+	for i := range votes {
+		sort.Ints(votes[i])
+		boundaries[i] = votes[i][len(votes[i])/2]
+	}
+	// a higher rank can never be easier to reach than a lower one
+	sort.Ints(boundaries[:])
+	t.rankBoundary = boundaries
 }
 
-func (t *Team1AoA) RunPostContributionAoaLogic(team *Team, agentMap map[uuid.UUID]IExtendedAgent) {
-	// Choose 2 chairs based on rank
-	// call function for agents to vote on ranks
-	// If the chairs decision do not match, then reduce rank by 1 of their score and give to common pool
-	// Then repeat until two agents agree
-
-	var current map[uuid.UUID]int
-	var prev map[uuid.UUID]int
-
-	for i := 0; i < 10; i++ {
-		chairsAgree := false
-		// listOfAgentRankVotes := make([]map[uuid.UUID]int, 0)
-
-		// call function for agents to vote on ranks
-		// for _, agentId := range team.Agents {
-		// 	agent := agentMap[agentId]
-		// 	ranks := agent.Team1_GetTeamRanks()
-		// 	listOfAgentRankVotes = append(listOfAgentRankVotes, ranks)
-		// }
-
-		chairs := t.SelectNChairs(team.Agents, 2)
-		for _, chairId := range chairs {
-			chair := agentMap[chairId]
-			current = chair.Team1_ChairUpdateRanks(t.ranking)
-			if prev != nil {
-				if !mapsEqual(prev, current) {
-					// Reduce rank of both chairs by 1
-					for _, id := range chairs {
-						t.ranking[id]--
-						if t.ranking[id] < 1 {
-							t.ranking[id] = 1
-						}
-					}
-					chairsAgree = false
-					break
-				}
-			} else {
-				prev = current
-			}
-			chairsAgree = true
-		}
-		// Chairs agree
-		if chairsAgree {
-			break
+// The ranks a chair proposed, for current members only and within the ranks there are
+func (t *Team1AoA) cleanRanks(proposal map[uuid.UUID]int) map[uuid.UUID]int {
+	ranks := make(map[uuid.UUID]int, len(t.ranking))
+	for agentId, current := range t.ranking {
+		rank, ok := proposal[agentId]
+		if !ok {
+			rank = current
 		}
+		ranks[agentId] = min(max(rank, 1), len(t.rankBoundary))
+	}
+	return ranks
+}
 
+func copyRanks(ranks map[uuid.UUID]int) map[uuid.UUID]int {
+	copied := make(map[uuid.UUID]int, len(ranks))
+	for agentId, rank := range ranks {
+		copied[agentId] = rank
 	}
-	// chairs agree therefore set the team ranking to the agreed ranking
-	t.ranking = current
+	return copied
+}
+
+// The rank of every member, 1 being the lowest
+func (t *Team1AoA) GetMemberRanks() map[uuid.UUID]int {
+	return copyRanks(t.ranking)
+}
+
+func (t *Team1AoA) GetRankBoundaries() [5]int {
+	return t.rankBoundary
 }
 
 func mapsEqual(a, b map[uuid.UUID]int) bool {
@@ -278,11 +258,12 @@ func (t *Team1AoA) GetAgentNewRank(agentId uuid.UUID) int {
 	agentCurrentRank := t.ranking[agentId]
 	// iterate from the highest rank to the lowest rank
 	// return the rank if the total contribution is greater than or equal to the boundary
-	newRank := 0
+	newRank := 1
 	for rank := len(t.rankBoundary) - 1; rank >= 0; rank-- {
 		boundary := t.rankBoundary[rank]
 		if agentTotalContributions >= boundary {
 			newRank = rank + 1
+			break
 		}
 	}
 	// Speed Limit to climb rank
@@ -300,21 +281,52 @@ func (f *Team1AoA) ResourceAllocation(agentScores map[uuid.UUID]int, remainingRe
 	return make(map[uuid.UUID]int)
 }
 
-func CreateTeam1AoA(team *Team) IArticlesOfAssociation {
-	auditResult := make(map[uuid.UUID]*list.List)
-	ranking := make(map[uuid.UUID]int)
-	agentLQueue := make(map[uuid.UUID]*LeakyQueue)
-	for _, agent := range team.Agents {
-		auditResult[agent] = list.New()
-		ranking[agent] = 1
-		agentLQueue[agent] = NewLeakyQueue(5)
+func (f *Team1AoA) GetAmendmentMajority() float64 {
+	return DefaultAmendmentMajority
+}
+
+// New members start at the lowest rank with no contributions on record
+func (t *Team1AoA) OnMemberJoined(agentId uuid.UUID) {
+	if _, ok := t.ranking[agentId]; ok {
+		return
 	}
+	t.auditResult[agentId] = list.New()
+	t.ranking[agentId] = 1
+	t.agentLQueue[agentId] = NewLeakyQueue(5)
+}
+
+func (t *Team1AoA) OnMemberLeft(agentId uuid.UUID) {
+	delete(t.auditResult, agentId)
+	delete(t.ranking, agentId)
+	delete(t.agentLQueue, agentId)
+}
+
+func (t *Team1AoA) OnMemberDied(agentId uuid.UUID) {
+	t.OnMemberLeft(agentId)
+}
 
-	return &Team1AoA{
-		auditResult:      auditResult,
-		ranking:          ranking,
+func CreateTeam1AoA(team *Team) IArticlesOfAssociation {
+	aoa := &Team1AoA{
+		TeamRoles:        NewTeamRoles(RoleSpec{Role: RoleChair, Seats: 2, Method: "rank-lottery"}),
+		auditResult:      make(map[uuid.UUID]*list.List),
+		ranking:          make(map[uuid.UUID]int),
 		rankBoundary:     [5]int{10, 20, 30, 40, 50},
-		agentLQueue:      agentLQueue,
+		agentLQueue:      make(map[uuid.UUID]*LeakyQueue),
 		commonPoolWeight: 5,
 	}
+	for _, agent := range team.Agents {
+		aoa.OnMemberJoined(agent)
+	}
+	return aoa
+}
+
+func init() {
+	RegisterAoA(AoAInfo{
+		ID:          1,
+		Name:        "team1",
+		Description: "Team 1 rank-based AoA; members vote on rank boundaries and two chairs, drawn by rank, rank members by contribution",
+		Constructor: func(team *Team, params AoAParams) IArticlesOfAssociation {
+			return CreateTeam1AoA(team)
+		},
+	})
 }
@@ -3,8 +3,6 @@ package common
 // import "github.com/google/uuid"
 import (
 	"container/list"
-	"math/rand"
-	"time"
 
 	"github.com/google/uuid"
 )
@@ -22,6 +20,9 @@ func NewAuditQueue(length int) *AuditQueue {
 }
 
 func (aq *AuditQueue) AddToQueue(auditResult bool) {
+	if aq.length <= 0 {
+		return
+	}
 	if aq.length == aq.rounds.Len() {
 		aq.rounds.Remove(aq.rounds.Front())
 	}
@@ -31,15 +32,19 @@ func (aq *AuditQueue) AddToQueue(auditResult bool) {
 func (aq *AuditQueue) GetWarnings() int {
 	warnings := 0
 	for e := aq.rounds.Front(); e != nil; e = e.Next() {
-		warnings += e.Value.(int)
+		if e.Value.(bool) {
+			warnings++
+		}
 	}
 	return warnings
 }
 
 type Team2AoA struct {
-	AuditMap   map[uuid.UUID]*AuditQueue
-	OffenceMap map[uuid.UUID]int
-	Leader     uuid.UUID
+	*TeamRoles
+	*OffenceTracker
+	AuditMap      map[uuid.UUID]*AuditQueue
+	Leader        uuid.UUID // elected by the team, see SetRoleHolders
+	auditDuration int       // rounds of audit results remembered for each agent
 }
 
 func (t *Team2AoA) ResetAuditMap() {
@@ -59,7 +64,7 @@ func (t *Team2AoA) SetContributionAuditResult(agentId uuid.UUID, agentScore int,
 	// ignore agentStatedContribution
 	// check if agent actually contributed it's entire score
 	if t.AuditMap[agentId] == nil {
-		t.AuditMap[agentId] = NewAuditQueue(5)
+		t.AuditMap[agentId] = NewAuditQueue(t.auditDuration)
 	}
 	t.AuditMap[agentId].AddToQueue(agentActualContribution != agentScore)
 }
@@ -77,7 +82,7 @@ func (t *Team2AoA) GetExpectedWithdrawal(agentId uuid.UUID, agentScore int, comm
 
 func (t *Team2AoA) SetWithdrawalAuditResult(agentId uuid.UUID, agentScore int, agentActualWithdrawal int, agentStatedWithdrawal int, commonPool int) {
 	if t.AuditMap[agentId] == nil {
-		t.AuditMap[agentId] = NewAuditQueue(5)
+		t.AuditMap[agentId] = NewAuditQueue(t.auditDuration)
 	}
 	if agentId == t.Leader {
 		t.AuditMap[agentId].AddToQueue(float64(agentScore)*0.25 != float64(agentActualWithdrawal))
@@ -86,6 +91,10 @@ func (t *Team2AoA) SetWithdrawalAuditResult(agentId uuid.UUID, agentScore int, a
 	}
 }
 
+func (t *Team2AoA) GetAuditDuration() int {
+	return t.auditDuration
+}
+
 func (t *Team2AoA) GetAuditCost(commonPool int) int {
 	if commonPool < 5 {
 		return 2
@@ -93,37 +102,24 @@ func (t *Team2AoA) GetAuditCost(commonPool int) int {
 	return 5 + ((commonPool - 5) / 5)
 }
 
-func (t *Team2AoA) GetVoteResult(votes []Vote) uuid.UUID {
-	voteMap := make(map[uuid.UUID]int)
-	for _, vote := range votes {
-		if vote.IsVote == 1 {
-			if vote.VoterID == t.Leader {
-				voteMap[vote.VotedForID] += 2
-			} else {
-				voteMap[vote.VotedForID]++
-			}
-		}
-		if voteMap[vote.VotedForID] > 4 {
-			return vote.VotedForID
-		}
+// An agent is audited once it has more than 4 votes
+func (t *Team2AoA) GetVoteResult(ballot BallotResult) uuid.UUID {
+	if agentID, votes := ballot.MostAuditVotes(); votes > 4 {
+		return agentID
 	}
 	return uuid.Nil // Explicitly return uuid.Nil for "no result"
 }
 
-func (t *Team2AoA) GetWithdrawalOrder(agentIDs []uuid.UUID) []uuid.UUID {
-	// Seed the random number generator to ensure different shuffles each time
-	rand.NewSource(time.Now().UnixNano())
-
-	// Create a copy of the agentIDs to avoid modifying the original list
-	shuffledAgents := make([]uuid.UUID, len(agentIDs))
-	copy(shuffledAgents, agentIDs)
-
-	// Shuffle the agent list
-	rand.Shuffle(len(shuffledAgents), func(i, j int) {
-		shuffledAgents[i], shuffledAgents[j] = shuffledAgents[j], shuffledAgents[i]
-	})
+// The leader's vote counts twice
+func (t *Team2AoA) GetAuditVoteWeights() map[uuid.UUID]int {
+	if t.Leader == uuid.Nil {
+		return nil
+	}
+	return map[uuid.UUID]int{t.Leader: 2}
+}
 
-	return shuffledAgents
+func (t *Team2AoA) GetWithdrawalOrder(agentIDs []uuid.UUID) []uuid.UUID {
+	return shuffledOrder(agentIDs)
 }
 
 func (t *Team2AoA) RunPostContributionAoaLogic(team *Team, agentMap map[uuid.UUID]IExtendedAgent) {}
@@ -132,9 +128,82 @@ func (f *Team2AoA) ResourceAllocation(agentScores map[uuid.UUID]int, remainingRe
 	return make(map[uuid.UUID]int)
 }
 
-func CreateTeam2AoA(auditDuration int) IArticlesOfAssociation {
+func (f *Team2AoA) GetAmendmentMajority() float64 {
+	return DefaultAmendmentMajority
+}
+
+func (t *Team2AoA) OnMemberJoined(agentId uuid.UUID) {
+	if t.AuditMap[agentId] == nil {
+		t.AuditMap[agentId] = NewAuditQueue(t.auditDuration)
+	}
+}
+
+func (t *Team2AoA) OnMemberLeft(agentId uuid.UUID) {
+	delete(t.AuditMap, agentId)
+	t.Forget(agentId)
+	if agentId == t.Leader {
+		t.Leader = uuid.Nil
+	}
+}
+
+// The leader withdraws more and its audit votes count double
+func (t *Team2AoA) SetRoleHolders(role Role, holders []uuid.UUID) {
+	t.TeamRoles.SetRoleHolders(role, holders)
+	if role == RoleLeader {
+		t.Leader = uuid.Nil
+		if len(holders) > 0 {
+			t.Leader = holders[0]
+		}
+	}
+}
+
+func (t *Team2AoA) OnMemberDied(agentId uuid.UUID) {
+	t.OnMemberLeft(agentId)
+}
+
+// The audit queues as infractions, 1 for every failed audit
+func (t *Team2AoA) GetAuditHistory() map[uuid.UUID][]int {
+	history := make(map[uuid.UUID][]int, len(t.AuditMap))
+	for agentID, queue := range t.AuditMap {
+		for e := queue.rounds.Front(); e != nil; e = e.Next() {
+			infraction := 0
+			if e.Value.(bool) {
+				infraction = 1
+			}
+			history[agentID] = append(history[agentID], infraction)
+		}
+	}
+	return history
+}
+
+// Refill the audit queues; only the last auditDuration rounds are kept
+func (t *Team2AoA) SetAuditHistory(history map[uuid.UUID][]int) {
+	for agentID, infractions := range history {
+		t.AuditMap[agentID] = NewAuditQueue(t.auditDuration)
+		for _, infraction := range infractions {
+			t.AuditMap[agentID].AddToQueue(infraction > 0)
+		}
+	}
+}
+
+func CreateTeam2AoA(auditDuration int, leaderTerm int) IArticlesOfAssociation {
 	return &Team2AoA{
-		AuditMap:   make(map[uuid.UUID]*AuditQueue),
-		OffenceMap: make(map[uuid.UUID]int),
+		TeamRoles: NewTeamRoles(RoleSpec{Role: RoleLeader, Method: "vote", Term: leaderTerm}),
+		// an offence is forgiven once it would have left the audit window
+		OffenceTracker: NewOffenceTracker(DefaultSanctionLadder().Rule(), auditDuration),
+		AuditMap:       make(map[uuid.UUID]*AuditQueue),
+		auditDuration:  auditDuration,
 	}
 }
+
+func init() {
+	RegisterAoA(AoAInfo{
+		ID:          2,
+		Name:        "team2",
+		Description: "Team 2 leader-based AoA; remembers auditDuration rounds of audit results, elects a leader every leaderTerm turns, escalating sanctions",
+		Params:      AoAParams{"auditDuration": 5, "leaderTerm": 3},
+		Constructor: func(team *Team, params AoAParams) IArticlesOfAssociation {
+			return CreateTeam2AoA(int(params.Get("auditDuration", 5)), int(params.Get("leaderTerm", 3)))
+		},
+	})
+}
@@ -3,13 +3,12 @@ package common
 import (
 	// environmentServer "SOMAS_Extended/server"
 	"container/list"
-	"math/rand"
-	"time"
 
 	"github.com/google/uuid"
 )
 
 type Team5AOA struct {
+	*OffenceTracker
 	ContributionAuditMap map[uuid.UUID]*list.List
 	WithdrawalAuditMap   map[uuid.UUID]bool
 	ContributionRoundMap map[uuid.UUID]int // Tracks the number of successful contribution rounds for each agent
@@ -96,48 +95,17 @@ func (f *Team5AOA) GetAuditCost(commonPool int) int {
 
 // GetVoteResult determines the agent to be audited based on votes
 // MUST return UUID nil if audit should not be executed
-func (f *Team5AOA) GetVoteResult(votes []Vote) uuid.UUID {
-	voteCount := make(map[uuid.UUID]int)
-	// Count the votes for each agent
-	for _, vote := range votes {
-		if vote.IsVote != 0 {
-			voteCount[vote.VotedForID]++
-		}
-	}
-
-	// Find the agent with the majority of votes
-	var maxVotes int
-	var selectedAgent uuid.UUID
-	for agentID, count := range voteCount {
-		if count > maxVotes {
-			maxVotes = count
-			selectedAgent = agentID
-		}
-	}
-
+func (f *Team5AOA) GetVoteResult(ballot BallotResult) uuid.UUID {
 	// If no agent has more than 50% of the votes, return nil
-	totalVotes := len(votes)
-	if maxVotes > totalVotes/2 {
-		return selectedAgent
+	if agentID, votes := ballot.MostAuditVotes(); votes > float64(ballot.Turnout/2) {
+		return agentID
 	}
 	return uuid.Nil
 }
 
 // GetWithdrawalOrder returns a shuffled order of agents for withdrawal
 func (t *Team5AOA) GetWithdrawalOrder(agentIDs []uuid.UUID) []uuid.UUID {
-	// Seed the random number generator to ensure different shuffles each time
-	rand.Seed(time.Now().UnixNano())
-
-	// Create a copy of the agentIDs to avoid modifying the original list
-	shuffledAgents := make([]uuid.UUID, len(agentIDs))
-	copy(shuffledAgents, agentIDs)
-
-	// Shuffle the agent list
-	rand.Shuffle(len(shuffledAgents), func(i, j int) {
-		shuffledAgents[i], shuffledAgents[j] = shuffledAgents[j], shuffledAgents[i]
-	})
-
-	return shuffledAgents
+	return shuffledOrder(agentIDs)
 }
 
 // GetBonusContribution returns the bonus for contributing correctly for three consecutive rounds
@@ -157,21 +125,12 @@ func (f *Team5AOA) ApplyPunishment(agentId uuid.UUID) bool {
 	return false
 }
 
+// Agents are warned for their first two failed audits and kicked out at the third
+var team5Ladder = SanctionLadder{{Warning: true}, {Warning: true}, {Expel: true}}
+
 // KickOutAgent checks if an agent should be kicked out based on audit failures
 func (f *Team5AOA) KickOutAgent(agentId uuid.UUID) bool {
-	// If an agent fails three audits in a row, they are kicked out
-	failCount := 0
-	if f.ContributionAuditMap[agentId] != nil {
-		for e := f.ContributionAuditMap[agentId].Front(); e != nil; e = e.Next() {
-			if e.Value.(bool) {
-				failCount++
-			}
-			if failCount >= 3 {
-				return true
-			}
-		}
-	}
-	return false
+	return f.GetOffences(agentId) >= len(team5Ladder)
 }
 
 func (t *Team5AOA) RunPostContributionAoaLogic(team *Team, agentMap map[uuid.UUID]IExtendedAgent) {}
@@ -278,12 +237,42 @@ func min(a, b int) int {
 	return b
 }
 
+func (f *Team5AOA) OnMemberJoined(agentId uuid.UUID) {}
+
+func (f *Team5AOA) OnMemberLeft(agentId uuid.UUID) {
+	delete(f.ContributionAuditMap, agentId)
+	delete(f.WithdrawalAuditMap, agentId)
+	delete(f.ContributionRoundMap, agentId)
+	delete(f.Allocation, agentId)
+	f.Forget(agentId)
+}
+
+func (f *Team5AOA) OnMemberDied(agentId uuid.UUID) {
+	f.OnMemberLeft(agentId)
+}
+
+func (f *Team5AOA) GetAmendmentMajority() float64 {
+	return DefaultAmendmentMajority
+}
+
 // CreateFixedAoA creates a new instance of Team5AOA
 func CreateTeam5AoA() IArticlesOfAssociation {
 	return &Team5AOA{
+		OffenceTracker:       NewOffenceTracker(team5Ladder.Rule(), 0),
 		ContributionAuditMap: make(map[uuid.UUID]*list.List),
 		WithdrawalAuditMap:   make(map[uuid.UUID]bool),
 		ContributionRoundMap: make(map[uuid.UUID]int),
 		Allocation:           make(map[uuid.UUID]int),
 	}
 }
+
+func init() {
+	RegisterAoA(AoAInfo{
+		ID:          5,
+		Name:        "team5",
+		Description: "Team 5 need-based AoA; contributions of 75%, allocation by need and expulsion at the third failed audit",
+		Constructor: func(team *Team, params AoAParams) IArticlesOfAssociation {
+			return CreateTeam5AoA()
+		},
+	})
+}
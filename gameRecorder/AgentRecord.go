@@ -1,8 +1,6 @@
 package gameRecorder
 
 import (
-	"log"
-
 	"github.com/google/uuid"
 )
 
@@ -12,7 +10,8 @@ type AgentRecord struct {
 	TurnNumber      int
 	IterationNumber int
 	AgentID         uuid.UUID
-	TrueSomasTeamID int // SOMAS team number, e.g. Team 4
+	TrueSomasTeamID int    // SOMAS team number, e.g. Team 4
+	Strategy        string // name the agent's strategy is registered under
 
 	// turn-specific fields
 	IsAlive            bool
@@ -45,13 +44,14 @@ func NewTeamRecord(teamID uuid.UUID) TeamRecord {
 }
 
 func (ar *AgentRecord) DebugPrint() {
-	// log.Printf("Agent ID: %v\n", ar.AgentID)
-	if !ar.IsAlive {
-		log.Printf("[DEAD] ")
-	}
-	log.Printf("Agent Score: %v\n", ar.Score)
-	// log.Printf("Agent Contribution: %v\n", ar.agent.GetActualContribution(ar.agent))
-	// log.Printf("Agent Stated Contribution: %v\n", ar.agent.GetStatedContribution(ar.agent))
-	// log.Printf("Agent Withdrawal: %v\n", ar.agent.GetActualWithdrawal(ar.agent))
-	// log.Printf("Agent Stated Withdrawal: %v\n", ar.agent.GetStatedWithdrawal(ar.agent))
+	recorderLog.Debug("Agent record",
+		"recordIteration", ar.IterationNumber,
+		"recordTurn", ar.TurnNumber,
+		"agent", ar.AgentID,
+		"alive", ar.IsAlive,
+		"score", ar.Score,
+		"contribution", ar.Contribution,
+		"statedContribution", ar.StatedContribution,
+		"withdrawal", ar.Withdrawal,
+		"statedWithdrawal", ar.StatedWithdrawal)
 }
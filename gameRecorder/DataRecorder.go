@@ -1,13 +1,16 @@
 package gameRecorder
 
 import (
-	"log"
 	"sort"
+
+	"github.com/ADimoska/SOMASExtended/logging"
 )
 
+var recorderLog = logging.For(logging.ComponentRecorder)
+
 // --------- General External Functions ---------
 func Log(message string) {
-	log.Println(message)
+	recorderLog.Info(message)
 }
 
 type TurnRecord struct {
@@ -97,15 +100,13 @@ func (sdr *ServerDataRecorder) RecordNewTurn(agentRecords []AgentRecord, teamRec
 }
 
 func (sdr *ServerDataRecorder) GamePlaybackSummary() {
-	log.Printf("\n\nGamePlaybackSummary - playing %v turn records\n", len(sdr.TurnRecords))
+	recorderLog.Info("GamePlaybackSummary", "turnRecords", len(sdr.TurnRecords))
 	for _, turnRecord := range sdr.TurnRecords {
-		log.Printf("\nIteration %v, Turn %v:\n", turnRecord.IterationNumber, turnRecord.TurnNumber)
 		// Sort agent records by ID for consistent ordering
 		sort.Slice(turnRecord.AgentRecords, func(i, j int) bool {
 			return turnRecord.AgentRecords[i].AgentID.String() < turnRecord.AgentRecords[j].AgentID.String()
 		})
 		for _, agentRecord := range turnRecord.AgentRecords {
-			log.Printf("Agent %v: ", agentRecord.AgentID)
 			agentRecord.DebugPrint()
 		}
 	}
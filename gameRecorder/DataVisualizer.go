@@ -2,7 +2,6 @@ package gameRecorder
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
@@ -10,6 +9,7 @@ import (
 	"github.com/go-echarts/go-echarts/v2/charts"
 	"github.com/go-echarts/go-echarts/v2/components"
 	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/google/uuid"
 )
 
 // Add these constants at the top of the file
@@ -23,11 +23,15 @@ const (
 
 // CreatePlaybackHTML generates visualizations for the recorded game data
 func CreatePlaybackHTML(recorder *ServerDataRecorder) {
+	CreatePlaybackHTMLIn(recorder, "visualization_output")
+}
+
+// CreatePlaybackHTMLIn writes the visualizations to game_visualization.html in outputDir
+func CreatePlaybackHTMLIn(recorder *ServerDataRecorder, outputDir string) {
 	// Create output directory if it doesn't exist
-	outputDir := "visualization_output"
 	err := os.MkdirAll(outputDir, 0755)
 	if err != nil {
-		log.Printf("Error creating output directory: %v\n", err)
+		recorderLog.Error("Error creating output directory", "error", err)
 		return
 	}
 
@@ -67,13 +71,18 @@ func CreatePlaybackHTML(recorder *ServerDataRecorder) {
 
 		// Add both charts to the page
 		page.AddCharts(scoreChart, contributionChart)
+
+		// Teams whose AoA ranks its members also get a chart of the ranks
+		if rankChart := createRankChart(iteration, turns); rankChart != nil {
+			page.AddCharts(rankChart)
+		}
 	}
 
 	// Create the output file
 	filepath := filepath.Join(outputDir, "game_visualization.html")
 	f, err := os.Create(filepath)
 	if err != nil {
-		log.Printf("Error creating visualization file: %v\n", err)
+		recorderLog.Error("Error creating visualization file", "error", err)
 		return
 	}
 	defer f.Close()
@@ -102,7 +111,7 @@ func createScoreChart(iteration int, turns []TurnRecord) *charts.Line {
 	}
 
 	if len(initialAgentRecords) == 0 {
-		log.Printf("Warning: No agent records found in iteration %d\n", iteration)
+		recorderLog.Warn("No agent records found", "iteration", iteration)
 		return nil
 	}
 
@@ -238,7 +247,7 @@ func createContributionChart(iteration int, turns []TurnRecord) *charts.Line {
 	}
 
 	if len(initialAgentRecords) == 0 {
-		log.Printf("Warning: No agent records found in iteration %d\n", iteration)
+		recorderLog.Warn("No agent records found", "iteration", iteration)
 		return nil
 	}
 
@@ -363,6 +372,96 @@ func createContributionChart(iteration int, turns []TurnRecord) *charts.Line {
 	return line
 }
 
+// Ranks of the members of teams whose AoA ranks them, nil if no team does
+func createRankChart(iteration int, turns []TurnRecord) *charts.Line {
+	sort.Slice(turns, func(i, j int) bool {
+		return turns[i].TurnNumber < turns[j].TurnNumber
+	})
+
+	// Every ranked agent, in the order they first appear
+	agentIDs := []uuid.UUID{}
+	seen := make(map[uuid.UUID]bool)
+	for _, turn := range turns {
+		for _, team := range turn.TeamRecords {
+			ids := make([]uuid.UUID, 0, len(team.MemberRanks))
+			for agentID := range team.MemberRanks {
+				if !seen[agentID] {
+					seen[agentID] = true
+					ids = append(ids, agentID)
+				}
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+			agentIDs = append(agentIDs, ids...)
+		}
+	}
+	if len(agentIDs) == 0 {
+		return nil
+	}
+
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title: fmt.Sprintf("Iteration %d - Member Ranks over Time", iteration),
+			Top:   "5%",
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show: opts.Bool(true),
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show: opts.Bool(showLegends),
+			Top:  "15%",
+		}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Name:    "Turn Number",
+			NameGap: 30,
+			AxisLabel: &opts.AxisLabel{
+				Show: opts.Bool(showAxisLabels),
+			},
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name:    "Rank",
+			NameGap: 30,
+			AxisLabel: &opts.AxisLabel{
+				Show: opts.Bool(showAxisLabels),
+			},
+		}),
+		charts.WithGridOpts(opts.Grid{
+			Top:          "25%",
+			Right:        "5%",
+			Left:         "10%",
+			Bottom:       "15%",
+			ContainLabel: opts.Bool(true),
+		}),
+		charts.WithInitializationOpts(opts.Initialization{
+			Width:  chartWidth,
+			Height: chartHeight,
+		}),
+	)
+
+	xAxis := make([]int, len(turns))
+	for i, turn := range turns {
+		xAxis[i] = turn.TurnNumber
+	}
+
+	// One stepped line per agent, with gaps for the turns it had no rank
+	for _, agentID := range agentIDs {
+		items := make([]opts.LineData, len(turns))
+		for i, turn := range turns {
+			for _, team := range turn.TeamRecords {
+				if rank, ok := team.MemberRanks[agentID]; ok {
+					items[i] = opts.LineData{Value: rank}
+				}
+			}
+		}
+		line.AddSeries(agentID.String(), items,
+			charts.WithLineChartOpts(opts.LineChart{Step: "end"}),
+		)
+	}
+
+	line.SetXAxis(xAxis)
+	return line
+}
+
 // Helper function to get team-based colors
 func getTeamColor(teamID int) string {
 	// Define a color palette for teams
@@ -383,7 +482,7 @@ func getTeamColor(teamID int) string {
 func createScorePlots(recorder *ServerDataRecorder, outputDir string) {
 	// Add safety check at the start
 	if len(recorder.TurnRecords) == 0 {
-		log.Println("Warning: No turn records to visualize")
+		recorderLog.Warn("No turn records to visualize")
 		return
 	}
 
@@ -414,7 +513,7 @@ func createScorePlots(recorder *ServerDataRecorder, outputDir string) {
 		}
 
 		if len(initialAgentRecords) == 0 {
-			log.Printf("Warning: No agent records found in iteration %d\n", iteration)
+			recorderLog.Warn("No agent records found", "iteration", iteration)
 			continue
 		}
 
@@ -544,7 +643,7 @@ func createScorePlots(recorder *ServerDataRecorder, outputDir string) {
 		filepath := filepath.Join(outputDir, "agent_scores.html")
 		f, err := os.Create(filepath)
 		if err != nil {
-			log.Printf("Error creating score plots file: %v\n", err)
+			recorderLog.Error("Error creating score plots file", "error", err)
 			return
 		}
 		defer f.Close()
@@ -560,7 +659,7 @@ func createScorePlots(recorder *ServerDataRecorder, outputDir string) {
 // New function to create contribution visualization
 func createContributionPlots(recorder *ServerDataRecorder, outputDir string) {
 	if len(recorder.TurnRecords) == 0 {
-		log.Println("Warning: No turn records to visualize")
+		recorderLog.Warn("No turn records to visualize")
 		return
 	}
 
@@ -589,7 +688,7 @@ func createContributionPlots(recorder *ServerDataRecorder, outputDir string) {
 		}
 
 		if len(initialAgentRecords) == 0 {
-			log.Printf("Warning: No agent records found in iteration %d\n", iteration)
+			recorderLog.Warn("No agent records found", "iteration", iteration)
 			continue
 		}
 
@@ -712,7 +811,7 @@ func createContributionPlots(recorder *ServerDataRecorder, outputDir string) {
 		filepath := filepath.Join(outputDir, "agent_contributions.html")
 		f, err := os.Create(filepath)
 		if err != nil {
-			log.Printf("Error creating contribution plots file: %v\n", err)
+			recorderLog.Error("Error creating contribution plots file", "error", err)
 			return
 		}
 		defer f.Close()
@@ -14,6 +14,8 @@ type TeamRecord struct {
 
 	// turn-specific fields
 	TeamCommonPool int
+	AoAID          int // the AoA the team runs
 	AgentsAlive    []uuid.UUID
 	AgentsDead     []uuid.UUID
+	MemberRanks    map[uuid.UUID]int // nil unless the AoA ranks its members
 }
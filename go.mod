@@ -5,6 +5,7 @@ go 1.23.2
 require (
 	github.com/MattSScott/basePlatformSOMAS/v2 v2.1.0
 	github.com/google/uuid v1.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/go-echarts/go-echarts/v2 v2.4.5 // indirect
@@ -13,5 +14,4 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,264 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+* Structured, levelled logging for the game, built on log/slog. Every logger
+* belongs to a component (server, team, agent, aoa, orphan, ...) whose level
+* can be set on its own, so one part of the game can be traced without
+* drowning in the rest. Loggers can be created at any time: the configuration
+* is looked up when a record is logged, so loggers held by agents and package
+* variables follow later calls to Configure.
+ */
+
+// Components used by the game
+const (
+	ComponentServer   = "server"
+	ComponentTeam     = "team"
+	ComponentAgent    = "agent"
+	ComponentAoA      = "aoa"
+	ComponentOrphan   = "orphan"
+	ComponentRecorder = "recorder"
+	ComponentPlatform = "platform" // anything logged through the standard log package
+)
+
+// Levels on top of the slog ones
+const (
+	LevelTrace = slog.LevelDebug - 4 // message by message detail
+	LevelOff   = slog.Level(100)     // nothing is logged
+)
+
+var levelNames = map[string]slog.Level{
+	"trace": LevelTrace,
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+	"off":   LevelOff,
+}
+
+type Config struct {
+	Level  slog.Level            // level of every component not in Levels
+	Levels map[string]slog.Level // per component levels
+	JSON   bool                  // one JSON object per line instead of key=value text
+	Output io.Writer
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Level:  slog.LevelInfo,
+		Levels: map[string]slog.Level{},
+		Output: os.Stderr,
+	}
+}
+
+// The active configuration, shared by every logger
+type state struct {
+	config  Config
+	handler slog.Handler
+	clock   func() (iteration int, turn int)
+}
+
+var (
+	mu      sync.RWMutex
+	current = newState(DefaultConfig(), nil)
+)
+
+func newState(config Config, clock func() (int, int)) *state {
+	options := &slog.HandlerOptions{
+		Level:       LevelTrace, // filtering is done per component
+		ReplaceAttr: replaceLevel,
+	}
+	var handler slog.Handler
+	if config.JSON {
+		handler = slog.NewJSONHandler(config.Output, options)
+	} else {
+		handler = slog.NewTextHandler(config.Output, options)
+	}
+	return &state{config: config, handler: handler, clock: clock}
+}
+
+func active() *state {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+/*
+* Replace the logging configuration. The standard log package is sent through
+* the platform component, so output from code that still uses it (such as the
+* base platform) obeys the same levels and format.
+ */
+func Configure(config Config) {
+	if config.Output == nil {
+		config.Output = os.Stderr
+	}
+	if config.Levels == nil {
+		config.Levels = map[string]slog.Level{}
+	}
+	mu.Lock()
+	current = newState(config, current.clock)
+	mu.Unlock()
+
+	log.SetFlags(0)
+	log.SetOutput(&platformWriter{})
+}
+
+// Log nothing at all, which is what batch runs want
+func Disable() {
+	Configure(Config{Level: LevelOff, Output: io.Discard})
+}
+
+/*
+* Set the function giving the current iteration and turn of the game, which
+* are added to every record. The server sets this when it starts.
+ */
+func SetClock(clock func() (iteration int, turn int)) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = &state{config: current.config, handler: current.handler, clock: clock}
+}
+
+// Get the logger of a component
+func For(component string) *slog.Logger {
+	return slog.New(&componentHandler{component: component})
+}
+
+// Whether anything would be logged by the component at the given level
+func Enabled(component string, level slog.Level) bool {
+	return level >= active().levelOf(component)
+}
+
+// Log at trace level, which has no method of its own on slog.Logger
+func Trace(logger *slog.Logger, msg string, args ...any) {
+	logger.Log(context.Background(), LevelTrace, msg, args...)
+}
+
+// Log an error and stop the program, replacing log.Fatal
+func Fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+func (s *state) levelOf(component string) slog.Level {
+	if level, ok := s.config.Levels[component]; ok {
+		return level
+	}
+	return s.config.Level
+}
+
+/*
+* Parse a level specification: a default level followed by any number of
+* component=level pairs, e.g. "warn,agent=debug,aoa=trace". Either part can
+* be left out.
+ */
+func ParseLevels(spec string) (slog.Level, map[string]slog.Level, error) {
+	level := slog.LevelInfo
+	levels := map[string]slog.Level{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		component, name, isComponent := strings.Cut(part, "=")
+		if !isComponent {
+			name = component
+		}
+		parsed, ok := levelNames[strings.ToLower(name)]
+		if !ok {
+			return level, levels, fmt.Errorf("unknown log level %q (known levels: %s)", name, strings.Join(LevelNames(), ", "))
+		}
+		if isComponent {
+			levels[component] = parsed
+		} else {
+			level = parsed
+		}
+	}
+	return level, levels, nil
+}
+
+// Names of the levels, from the most to the least verbose
+func LevelNames() []string {
+	names := make([]string, 0, len(levelNames))
+	for name := range levelNames {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return levelNames[names[i]] < levelNames[names[j]] })
+	return names
+}
+
+// Print the custom levels by name rather than as DEBUG-4 and ERROR+92
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.LevelKey || len(groups) > 0 {
+		return a
+	}
+	if level, ok := a.Value.Any().(slog.Level); ok && level == LevelTrace {
+		return slog.String(slog.LevelKey, "TRACE")
+	}
+	return a
+}
+
+// --------- Handler ---------
+
+/*
+* The handler behind every component logger. It keeps the attributes and
+* groups added with With and WithGroup, and applies them to the active
+* handler when a record is logged.
+ */
+type componentHandler struct {
+	component string
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func (h *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return Enabled(h.component, level)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	s := active()
+	handler := s.handler.WithAttrs([]slog.Attr{slog.String("component", h.component)})
+	if s.clock != nil {
+		iteration, turn := s.clock()
+		handler = handler.WithAttrs([]slog.Attr{slog.Int("iteration", iteration), slog.Int("turn", turn)})
+	}
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+func (h *componentHandler) with(op func(slog.Handler) slog.Handler) *componentHandler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &componentHandler{component: h.component, ops: append(ops, op)}
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+// Sends each line written through the standard log package to the platform logger
+type platformWriter struct{}
+
+var platformLogger = For(ComponentPlatform)
+
+func (platformWriter) Write(p []byte) (int, error) {
+	if Enabled(ComponentPlatform, slog.LevelInfo) {
+		platformLogger.Info(strings.TrimSpace(string(p)))
+	}
+	return len(p), nil
+}
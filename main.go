@@ -1,21 +1,83 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
-
-	baseServer "github.com/MattSScott/basePlatformSOMAS/v2/pkg/server"
+	"github.com/ADimoska/SOMASExtended/logging"
+)
 
-	agents "github.com/ADimoska/SOMASExtended/agents"
-	common "github.com/ADimoska/SOMASExtended/common"
-	envServer "github.com/ADimoska/SOMASExtended/server"
+/*
+* Command line entry point. The first argument picks a subcommand (run, batch,
+* sweep, tournament, replay, report, list, aoa); with no subcommand a single game is
+* run, as the program always did. See usage() for the list of commands.
+ */
+
+// Exit codes, so that scripts and CI can tell failures apart
+const (
+	exitOK               = 0
+	exitInvariantFailure = 1 // the game ran but broke one of its invariants
+	exitUsage            = 2 // bad flags or scenario
+	exitError            = 3 // anything else went wrong
 )
 
+func usage() {
+	fmt.Fprint(os.Stderr, `usage: SOMASExtended <command> [flags]
+
+commands:
+  run      run one scenario (the default when no command is given)
+  batch    run a scenario with several seeds
+  sweep    run a scenario over a grid of parameter values
+  tournament
+           play registered strategies against each other and rank them
+  replay   replay a game from its event log
+  report   generate a markdown or HTML report from saved results
+  list     list the available agents, AoAs and threshold policies
+  aoa      check AoA definition files and show the rules they define
+
+Run 'SOMASExtended <command> -h' for the flags of a command.
+`)
+}
+
 func main() {
+	args := os.Args[1:]
+	command := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	commands := map[string]func([]string) int{
+		"run":        cmdRun,
+		"batch":      cmdBatch,
+		"sweep":      cmdSweep,
+		"tournament": cmdTournament,
+		"replay":     cmdReplay,
+		"report":     cmdReport,
+		"list":       cmdList,
+		"aoa":        cmdAoA,
+	}
+	if command == "help" {
+		usage()
+		os.Exit(exitOK)
+	}
+	run, ok := commands[command]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		usage()
+		os.Exit(exitUsage)
+	}
+	os.Exit(run(args))
+}
+
+/*
+* Send the game log to stdout and to a timestamped file in the logs directory.
+* The returned function closes the file.
+ */
+func setupLogging(config logging.Config) func() {
 	// Create logs directory if it doesn't exist
 	if err := os.MkdirAll("logs", 0755); err != nil {
 		log.Fatalf("Failed to create logs directory: %v", err)
@@ -27,59 +89,10 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to open log file: %v", err)
 	}
-	defer logFile.Close()
 
 	// Create a MultiWriter to write to both the log file and stdout
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
-
-	// Set log output to multiWriter
-	log.SetOutput(multiWriter)
-
-	// Remove date and time prefix from log entries
-	log.SetFlags(0)
-
-	log.Println("main function started.")
-
-	// agent configuration
-	agentConfig := agents.AgentConfig{
-		InitScore:    0,
-		VerboseLevel: 10,
-	}
-
-	serv := &envServer.EnvironmentServer{
-		// note: the zero turn is used for team forming
-		BaseServer: baseServer.CreateBaseServer[common.IExtendedAgent](
-			2,                    //  iterations
-			12,                   //  turns per iteration
-			100*time.Millisecond, //  max duration
-			10),                  //  message bandwidth
-		Teams: make(map[uuid.UUID]*common.Team),
-	}
-	serv.Init(
-		3, // turns to apply threshold once
-	)
-	serv.SetGameRunner(serv)
-
-	const numAgents int = 2
-
-	agentPopulation := []common.IExtendedAgent{}
-	for i := 0; i < numAgents; i++ {
-		agentPopulation = append(agentPopulation, agents.Team4_CreateAgent(serv, agentConfig))
-		agentPopulation = append(agentPopulation, agents.GetBaseAgents(serv, agentConfig))
-		// Add other teams' agents here
-	}
-
-	for _, agent := range agentPopulation {
-		serv.AddAgent(agent)
-	}
-
-	//serv.ReportMessagingDiagnostics()
-	serv.Start()
-
-	// custom function to see agent result
-	serv.LogAgentStatus()
-	serv.LogTeamStatus()
+	config.Output = io.MultiWriter(os.Stdout, logFile)
+	logging.Configure(config)
 
-	// // record data
-	serv.DataRecorder.GamePlaybackSummary()
+	return func() { logFile.Close() }
 }
@@ -0,0 +1,146 @@
+package environmentServer
+
+import (
+	"fmt"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+)
+
+/*
+* File an accusation against a teammate for a phase of the current turn. The
+* accuser and the accused must be living members of the same team, and an
+* agent can only accuse a teammate once per phase.
+ */
+func (cs *EnvironmentServer) FileAccusation(accusation common.Accusation) error {
+	if accusation.AccuserID == accusation.AccusedID {
+		return fmt.Errorf("an agent cannot accuse itself")
+	}
+	if accusation.Phase != common.ContributionAudit && accusation.Phase != common.WithdrawalAudit {
+		return fmt.Errorf("unknown audit phase %q (use %s or %s)", accusation.Phase, common.ContributionAudit, common.WithdrawalAudit)
+	}
+	accuser, ok := cs.GetAgentMap()[accusation.AccuserID]
+	if !ok || cs.IsAgentDead(accusation.AccuserID) || accuser.GetTeamID() == uuid.Nil {
+		return fmt.Errorf("agent %s is not a living member of a team", accusation.AccuserID)
+	}
+	teamID := accuser.GetTeamID()
+	team, ok := cs.Teams[teamID]
+	if !ok || !containsID(team.Agents, accusation.AccusedID) || cs.IsAgentDead(accusation.AccusedID) {
+		return fmt.Errorf("agent %s is not a living member of the accuser's team", accusation.AccusedID)
+	}
+	for _, filed := range cs.accusations[teamID] {
+		if filed.AccuserID == accusation.AccuserID && filed.AccusedID == accusation.AccusedID && filed.Phase == accusation.Phase {
+			return fmt.Errorf("agent %s has already accused agent %s this %s phase", accusation.AccuserID, accusation.AccusedID, accusation.Phase)
+		}
+	}
+
+	accusation.TeamID = teamID
+	accusation.Iteration = cs.iteration
+	accusation.Turn = cs.turn
+	if cs.accusations == nil {
+		cs.accusations = make(map[uuid.UUID][]common.Accusation)
+	}
+	cs.accusations[teamID] = append(cs.accusations[teamID], accusation)
+	teamLog.Debug("Accusation filed", "team", teamID, "accuser", accusation.AccuserID, "accused", accusation.AccusedID, "phase", accusation.Phase)
+	return nil
+}
+
+// The team's accusations that are not settled yet
+func (cs *EnvironmentServer) GetAccusations(teamID uuid.UUID) []common.Accusation {
+	return append([]common.Accusation{}, cs.accusations[teamID]...)
+}
+
+func (cs *EnvironmentServer) accusationsFor(teamID uuid.UUID, phase common.AuditPhase) []common.Accusation {
+	accusations := []common.Accusation{}
+	for _, accusation := range cs.accusations[teamID] {
+		if accusation.Phase == phase {
+			accusations = append(accusations, accusation)
+		}
+	}
+	return accusations
+}
+
+/*
+* The agent to audit in a phase: the one the team's audit vote calls for, or
+* if it calls for none, the agent the AoA's whistleblower terms shortlist from
+* the accusations.
+ */
+func (cs *EnvironmentServer) auditTarget(team *common.Team, phase string, votes []common.Vote) uuid.UUID {
+	agentToAudit := cs.holdAuditBallot(team, phase, votes)
+	if agentToAudit != uuid.Nil {
+		return agentToAudit
+	}
+	policy, ok := team.TeamAoA.(common.AccusationPolicy)
+	if !ok {
+		return uuid.Nil
+	}
+	for _, accusedID := range policy.GetWhistleblowerTerms().Shortlist(cs.accusationsFor(team.TeamID, common.AuditPhase(phase))) {
+		if containsID(team.Agents, accusedID) && !cs.IsAgentDead(accusedID) {
+			teamLog.Info("Accusations called an audit", "team", team.TeamID, "agent", accusedID, "phase", phase)
+			return accusedID
+		}
+	}
+	return uuid.Nil
+}
+
+/*
+* Settle the accusations against an agent audited in a phase: under the AoA's
+* whistleblower terms accusers are rewarded out of the common pool if the
+* agent was found to have cheated, and pay a penalty into it if not.
+ */
+func (cs *EnvironmentServer) settleAccusations(team *common.Team, entry common.AuditEntry) {
+	terms := common.WhistleblowerTerms{}
+	if policy, ok := team.TeamAoA.(common.AccusationPolicy); ok {
+		terms = policy.GetWhistleblowerTerms()
+	}
+	if len(cs.accusations[team.TeamID]) == 0 {
+		return
+	}
+	cheated := entry.Verdict && !entry.Overturned
+	remaining := []common.Accusation{}
+	for _, accusation := range cs.accusations[team.TeamID] {
+		if accusation.AccusedID != entry.AgentID || accusation.Phase != entry.Phase {
+			remaining = append(remaining, accusation)
+			continue
+		}
+		accuser := cs.GetAgentMap()[accusation.AccuserID]
+		reward, penalty := 0, 0
+		if cheated {
+			reward = min(terms.Reward, max(team.GetCommonPool(), 0))
+			team.SetCommonPool(team.GetCommonPool() - reward)
+			accuser.SetTrueScore(accuser.GetTrueScore() + reward)
+		} else {
+			penalty = min(terms.Penalty, max(accuser.GetTrueScore(), 0))
+			accuser.SetTrueScore(accuser.GetTrueScore() - penalty)
+			team.SetCommonPool(team.GetCommonPool() + penalty)
+		}
+		outcome := AccusationFalse
+		if cheated {
+			outcome = AccusationUpheld
+		}
+		cs.publishAccusation(accusation, outcome, reward, penalty)
+	}
+	cs.accusations[team.TeamID] = remaining
+}
+
+// Accusations against agents that were not audited lapse at the end of the turn
+func (cs *EnvironmentServer) expireAccusations(team *common.Team) {
+	for _, accusation := range cs.accusations[team.TeamID] {
+		cs.publishAccusation(accusation, AccusationUnaudited, 0, 0)
+	}
+	delete(cs.accusations, team.TeamID)
+}
+
+func (cs *EnvironmentServer) publishAccusation(accusation common.Accusation, outcome string, reward int, penalty int) {
+	cs.publish(AccusationEvent{
+		EventContext: cs.eventContext(),
+		TeamID:       accusation.TeamID,
+		AccuserID:    accusation.AccuserID,
+		AccusedID:    accusation.AccusedID,
+		Phase:        string(accusation.Phase),
+		Evidence:     accusation.Evidence,
+		Outcome:      outcome,
+		Reward:       reward,
+		Penalty:      penalty,
+	})
+}
@@ -0,0 +1,147 @@
+package environmentServer
+
+import (
+	"fmt"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+)
+
+/*
+* Constitutional amendments. At the end of each team's turn, members may
+* propose moving the team to another AoA (or changing the parameters of the
+* current one). The first proposal, in member order, is put to a ballot of the
+* whole team; it passes if the share of members voting for it reaches the
+* majority the current AoA requires. At most one amendment is voted on per
+* team per turn.
+*
+* A proposal for an AoA that is not registered, or with parameters the AoA
+* does not take, is rejected without a vote and the next member's is taken.
+*
+* A team that amends its AoA keeps its members and its common pool, and keeps
+* its audit history if the proposal asks for it and both AoAs can hand it over
+* (see common.AuditHistoryHolder). The offence counts of its members carry over
+* if both AoAs keep them (see common.OffenceKeeper), so that sanctions keep
+* escalating. The roles of the old AoA end and the new AoA elects its own at
+* the start of the next turn, and accusations still pending lapse.
+ */
+func (cs *EnvironmentServer) runAmendments(team *common.Team) {
+	var proposal *common.AoAAmendment
+	for _, agentID := range team.Agents {
+		agent, ok := cs.GetAgentMap()[agentID]
+		if !ok || cs.IsAgentDead(agentID) {
+			continue
+		}
+		proposal = agent.ProposeAoAAmendment(agent)
+		if proposal == nil {
+			continue
+		}
+		if err := checkAmendment(*proposal); err != nil {
+			aoaLog.Warn("Rejected AoA amendment", "team", team.TeamID, "proposer", agentID, "err", err)
+			proposal = nil
+			continue
+		}
+		proposal.ProposerID = agentID
+		break
+	}
+	if proposal == nil {
+		return
+	}
+
+	cs.publishPhase(PhaseAmendment, team.TeamID)
+	voters := []uuid.UUID{}
+	for _, agentID := range team.Agents {
+		if _, ok := cs.GetAgentMap()[agentID]; ok && !cs.IsAgentDead(agentID) {
+			voters = append(voters, agentID)
+		}
+	}
+	majority := team.TeamAoA.GetAmendmentMajority()
+	result := cs.holdBallot(common.BallotSpec{
+		TeamID:    team.TeamID,
+		Question:  fmt.Sprintf("amend AoA %d to %d", team.TeamAoAID, proposal.AoAID),
+		Options:   []string{"amend", "keep"},
+		Voters:    voters,
+		Rule:      common.BallotRuleThreshold,
+		Threshold: majority,
+	}, func(voterID uuid.UUID) []int {
+		if cs.GetAgentMap()[voterID].VoteOnAoAAmendment(*proposal) {
+			return []int{0}
+		}
+		return []int{1}
+	})
+	votes, yes := result.Eligible, int(result.Scores[0])
+	passed := result.Winner == 0
+
+	event := AmendmentEvent{
+		EventContext: cs.eventContext(),
+		TeamID:       team.TeamID,
+		ProposerID:   proposal.ProposerID,
+		FromAoAID:    team.TeamAoAID,
+		ToAoAID:      proposal.AoAID,
+		Params:       proposal.Params,
+		Votes:        votes,
+		VotesFor:     yes,
+		Majority:     majority,
+		Passed:       passed,
+		CommonPool:   team.GetCommonPool(),
+	}
+	if passed {
+		event.ToAoAID, event.AuditHistoryKept, event.OffencesKept = cs.amendAoA(team, *proposal)
+	}
+	aoaLog.Info("AoA amendment voted on", "team", team.TeamID, "proposer", proposal.ProposerID,
+		"from", event.FromAoAID, "to", event.ToAoAID, "for", yes, "votes", votes, "passed", passed)
+	cs.publish(event)
+}
+
+// An amendment can only move a team to a registered AoA, with parameters it takes
+func checkAmendment(amendment common.AoAAmendment) error {
+	info, ok := common.LookupAoA(amendment.AoAID)
+	if !ok {
+		return fmt.Errorf("no AoA is registered with ID %d", amendment.AoAID)
+	}
+	_, err := common.ResolveAoAParams(info.Name, amendment.Params)
+	return err
+}
+
+/*
+* Move a team to the AoA of an amendment, returning the AoA it now runs and
+* whether the audit history and the offence counts were carried over
+ */
+func (cs *EnvironmentServer) amendAoA(team *common.Team, amendment common.AoAAmendment) (int, bool, bool) {
+	params := common.AoAParams{}
+	for key, value := range cs.aoaParams[amendment.AoAID] {
+		params[key] = value
+	}
+	for key, value := range amendment.Params {
+		params[key] = value
+	}
+	previous := team.TeamAoA
+	team.TeamAoA, team.TeamAoAID = common.CreateAoA(amendment.AoAID, team, params)
+
+	kept := false
+	if amendment.KeepAuditHistory {
+		from, fromOK := previous.(common.AuditHistoryHolder)
+		to, toOK := team.TeamAoA.(common.AuditHistoryHolder)
+		if fromOK && toOK {
+			to.SetAuditHistory(from.GetAuditHistory())
+			kept = true
+		}
+	}
+
+	offencesKept := false
+	from, fromOK := previous.(common.OffenceKeeper)
+	to, toOK := team.TeamAoA.(common.OffenceKeeper)
+	if fromOK && toOK {
+		for _, agentID := range team.Agents {
+			to.SetOffences(agentID, from.GetOffences(agentID))
+		}
+		offencesKept = true
+	}
+	delete(cs.teamRoles, team.TeamID)
+	cs.expireAccusations(team)
+
+	cs.teamsMutex.Lock()
+	cs.Teams[team.TeamID] = team
+	cs.teamsMutex.Unlock()
+	return team.TeamAoAID, kept, offencesKept
+}
@@ -0,0 +1,129 @@
+package environmentServer
+
+import (
+	"fmt"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+)
+
+/*
+* Offer an agent just sanctioned after an audit the chance to appeal, if its
+* team's AoA hears appeals (see common.AppealCourt). An appellant pays the fee
+* into the common pool and is heard either by a second audit, paid for by the
+* pool and more thorough than the first, or by a vote of the rest of the team.
+* An overturned verdict refunds the fee and reverses the sanction. Returns the
+* entry with the outcome of the appeal.
+ */
+func (cs *EnvironmentServer) hearAppeal(team *common.Team, entry common.AuditEntry, infraction bool) common.AuditEntry {
+	court, ok := team.TeamAoA.(common.AppealCourt)
+	if !ok || !entry.Verdict {
+		return entry
+	}
+	terms := court.GetAppealTerms()
+	agent, alive := cs.GetAgentMap()[entry.AgentID]
+	if !terms.Offered() || !alive || cs.IsAgentDead(entry.AgentID) {
+		return entry
+	}
+	fee := max(terms.Fee, 0)
+	if agent.GetTrueScore() < fee || !agent.AppealVerdict(entry, terms) {
+		return entry
+	}
+
+	cost := 0
+	overturned := false
+	switch terms.Method {
+	case common.AppealByAudit:
+		cost = terms.AuditCost(team.TeamAoA.GetAuditCost(team.GetCommonPool()))
+		if cost > team.GetCommonPool()+fee {
+			teamLog.Info("Not enough resources in the common pool to hear the appeal", "team", team.TeamID, "agent", entry.AgentID, "cost", cost)
+			return entry
+		}
+		agent.SetTrueScore(agent.GetTrueScore() - fee)
+		team.SetCommonPool(team.GetCommonPool() + fee - cost)
+		overturned = !cs.auditVerdict(team, infraction, cost)
+	case common.AppealByJury:
+		agent.SetTrueScore(agent.GetTrueScore() - fee)
+		team.SetCommonPool(team.GetCommonPool() + fee)
+		jury := []uuid.UUID{}
+		for _, memberID := range team.Agents {
+			if memberID != entry.AgentID {
+				jury = append(jury, memberID)
+			}
+		}
+		result := cs.holdBallot(common.BallotSpec{
+			TeamID:    team.TeamID,
+			Question:  fmt.Sprintf("appeal of %s against its %s audit", entry.AgentID, entry.Phase),
+			Options:   []string{"overturn", "uphold"},
+			Voters:    jury,
+			Rule:      common.BallotRuleThreshold,
+			Threshold: terms.JuryMajority(),
+		}, func(voterID uuid.UUID) []int {
+			if cs.GetAgentMap()[voterID].VoteOnAppeal(entry) {
+				return []int{0}
+			}
+			return []int{1}
+		})
+		overturned = result.Winner == 0
+	}
+
+	entry.Appealed = true
+	entry.Overturned = overturned
+	refund, readmitted := 0, false
+	if overturned {
+		refund, readmitted = cs.reverseSanction(team, entry, fee)
+	}
+	teamLog.Info("Appeal heard", "team", team.TeamID, "agent", entry.AgentID, "method", terms.Method, "fee", fee, "cost", cost, "overturned", overturned)
+	cs.publish(AppealEvent{
+		EventContext: cs.eventContext(),
+		TeamID:       team.TeamID,
+		AgentID:      entry.AgentID,
+		Phase:        string(entry.Phase),
+		Method:       terms.Method,
+		Fee:          fee,
+		Cost:         cost,
+		Overturned:   overturned,
+		Refund:       refund,
+		Readmitted:   readmitted,
+	})
+	return entry
+}
+
+/*
+* Undo the sanction of an overturned verdict: the fee and fine are paid back
+* out of the common pool as far as it goes, bans are lifted, an expelled agent
+* rejoins the team and the AoA pardons the offence. The team is told the agent
+* passed the audit after all. Returns what was paid back and whether the agent
+* was readmitted.
+ */
+func (cs *EnvironmentServer) reverseSanction(team *common.Team, entry common.AuditEntry, fee int) (int, bool) {
+	agent := cs.GetAgentMap()[entry.AgentID]
+	refund := min(fee+entry.Sanction.Fine, max(team.GetCommonPool(), 0))
+	team.SetCommonPool(team.GetCommonPool() - refund)
+	agent.SetTrueScore(agent.GetTrueScore() + refund)
+
+	delete(cs.withdrawalBans, entry.AgentID)
+	delete(cs.voteBans, entry.AgentID)
+
+	readmitted := false
+	if entry.Sanction.Expel && agent.GetTeamID() == uuid.Nil {
+		team.Agents = append(team.Agents, entry.AgentID)
+		team.TeamAoA.OnMemberJoined(entry.AgentID)
+		agent.SetTeamID(team.TeamID)
+		delete(cs.orphanPool, entry.AgentID)
+		readmitted = true
+	}
+	if keeper, ok := team.TeamAoA.(common.OffenceKeeper); ok {
+		keeper.Pardon(entry.AgentID)
+	}
+
+	for _, memberID := range team.Agents {
+		member := cs.GetAgentMap()[memberID]
+		if entry.Phase == common.WithdrawalAudit {
+			member.SetAgentWithdrawalAuditResult(entry.AgentID, false)
+		} else {
+			member.SetAgentContributionAuditResult(entry.AgentID, false)
+		}
+	}
+	return refund, readmitted
+}
@@ -0,0 +1,115 @@
+package environmentServer
+
+import (
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+)
+
+/*
+* Finish an audit once its verdict is known: publish it, carry out any
+* sanction, hear any appeal, settle the accusations against the agent, add it
+* to the team's audit history and tell every member of the team about it.
+ */
+func (cs *EnvironmentServer) concludeAudit(team *common.Team, agentID uuid.UUID, phase string, votes []common.Vote, cost int, infraction bool, verdict bool) {
+	cs.publishAudit(team, agentID, phase, cost, infraction, verdict)
+	sanction := common.Sanction{}
+	if verdict {
+		sanction = cs.applySanction(team, agentID, phase)
+	}
+
+	entry := common.AuditEntry{
+		TeamID:    team.TeamID,
+		AgentID:   agentID,
+		Iteration: cs.iteration,
+		Turn:      cs.turn,
+		Phase:     common.AuditPhase(phase),
+		Voters:    common.AuditVoters(votes, agentID),
+		Cost:      cost,
+		Verdict:   verdict,
+		Sanction:  sanction,
+	}
+	entry = cs.hearAppeal(team, entry, infraction)
+	cs.settleAccusations(team, entry)
+	if cs.auditHistory == nil {
+		cs.auditHistory = make(map[uuid.UUID][]common.AuditEntry)
+	}
+	cs.auditHistory[team.TeamID] = append(cs.auditHistory[team.TeamID], entry)
+
+	// an expelled agent still hears the verdict that expelled it
+	recipients := append([]uuid.UUID{}, team.Agents...)
+	if sanction.Expel && !entry.Overturned {
+		recipients = append(recipients, agentID)
+	}
+	for _, recipientID := range recipients {
+		if agent, ok := cs.GetAgentMap()[recipientID]; ok {
+			msg := &common.AuditVerdictMessage{Entry: entry}
+			msg.InvokeMessageHandler(agent)
+		}
+	}
+}
+
+// Every audit the team has held, oldest first
+func (cs *EnvironmentServer) GetAuditHistory(teamID uuid.UUID) []common.AuditEntry {
+	return append([]common.AuditEntry{}, cs.auditHistory[teamID]...)
+}
+
+/*
+* Put the team's audit votes to a ballot (see common.NoAudit) and let the AoA
+* decide who to audit from the result.
+ */
+func (cs *EnvironmentServer) holdAuditBallot(team *common.Team, phase string, votes []common.Vote) uuid.UUID {
+	options := []string{common.NoAudit}
+	for _, agentID := range team.Agents {
+		options = append(options, agentID.String())
+	}
+	voters := []uuid.UUID{}
+	durations := []int{}
+	for _, vote := range votes {
+		voters = append(voters, vote.VoterID)
+		durations = append(durations, vote.AuditDuration)
+	}
+	if voter, ok := team.TeamAoA.(common.AuditDurationVoter); ok {
+		voter.SetAuditDurationVotes(durations)
+	}
+	var weights map[uuid.UUID]int
+	if policy, ok := team.TeamAoA.(common.AuditBallotPolicy); ok {
+		weights = policy.GetAuditVoteWeights()
+	}
+
+	result := cs.holdBallot(common.BallotSpec{
+		TeamID:   team.TeamID,
+		Question: phase + " audit",
+		Options:  options,
+		Voters:   voters,
+		Weights:  weights,
+	}, func(voterID uuid.UUID) []int {
+		for _, vote := range votes {
+			if vote.VoterID == voterID {
+				return auditRanking(options, vote)
+			}
+		}
+		return nil
+	})
+	return team.TeamAoA.GetVoteResult(result)
+}
+
+// A vote for an audit ranks the agent it names, a vote against ranks no audit
+func auditRanking(options []string, vote common.Vote) []int {
+	if vote.IsVote != 1 {
+		return []int{0}
+	}
+	if i := optionIndex(options, vote.VotedForID); i > 0 {
+		return []int{i}
+	}
+	return nil
+}
+
+// Index of an agent among the options of an audit ballot, 0 (no audit) for anyone else
+func optionIndex(options []string, agentID uuid.UUID) int {
+	for i, option := range options {
+		if i > 0 && option == agentID.String() {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,29 @@
+package environmentServer
+
+import common "github.com/ADimoska/SOMASExtended/common"
+
+// Make audits unreliable for this game; the zero value makes them perfect again
+func (cs *EnvironmentServer) SetAuditReliability(reliability common.AuditReliability) error {
+	if err := reliability.Validate(); err != nil {
+		return err
+	}
+	cs.auditReliability = reliability
+	return nil
+}
+
+/*
+* What an audit finds, given whether the team's AoA shows an infraction and
+* what the team spent on the audit. Agents, sanctions and the audit event all
+* go by the verdict; the event also records the infraction so the two can be
+* compared afterwards.
+ */
+func (cs *EnvironmentServer) auditVerdict(team *common.Team, infraction bool, spend int) bool {
+	if cs.auditReliability.IsPerfect() {
+		return infraction
+	}
+	duration := 1
+	if holder, ok := team.TeamAoA.(common.AuditDurationHolder); ok {
+		duration = holder.GetAuditDuration()
+	}
+	return cs.auditReliability.Verdict(infraction, spend, duration)
+}
@@ -0,0 +1,281 @@
+package environmentServer
+
+import (
+	"fmt"
+	"strings"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/ADimoska/SOMASExtended/logging"
+	"github.com/ADimoska/SOMASExtended/voting"
+	"github.com/google/uuid"
+)
+
+/*
+* The ballot service (see common.Ballot). Ballots the server holds itself, for
+* audits, orphan admission and amendments, are cast and tallied on the spot.
+* Ballots opened by agents stay open for the turns they ask for: voters can
+* cast or change their ranking with CastBallot until then, and those that have
+* not voted by the end of the closing turn are asked with VoteOnBallot. Every
+* result is kept by team and published as a BallotEvent.
+ */
+
+// Open a ballot for a team, returning its ID
+func (cs *EnvironmentServer) OpenBallot(spec common.BallotSpec) (uuid.UUID, error) {
+	team, ok := cs.Teams[spec.TeamID]
+	if !ok {
+		return uuid.Nil, fmt.Errorf("no team %s to hold a ballot", spec.TeamID)
+	}
+	if len(spec.Options) == 0 {
+		return uuid.Nil, fmt.Errorf("a ballot needs at least one option")
+	}
+	if _, ok := voting.LookupRule(spec.Rule); !ok && spec.Rule != "" && spec.Rule != common.BallotRuleThreshold {
+		return uuid.Nil, fmt.Errorf("unknown ballot rule %q (known rules: %s, %s)", spec.Rule, strings.Join(voting.RuleNames(), ", "), common.BallotRuleThreshold)
+	}
+	if spec.Quorum < 0 || spec.Quorum > 1 || spec.Threshold < 0 || spec.Threshold > 1 {
+		return uuid.Nil, fmt.Errorf("ballot quorum and threshold must be between 0 and 1")
+	}
+	if spec.Turns < 0 {
+		return uuid.Nil, fmt.Errorf("a ballot cannot close before the turn it is opened in")
+	}
+	for _, weight := range spec.Weights {
+		if weight < 0 {
+			return uuid.Nil, fmt.Errorf("ballot weights cannot be negative")
+		}
+	}
+	if len(spec.Voters) == 0 {
+		spec.Voters = append([]uuid.UUID{}, team.Agents...)
+	}
+
+	ballot := &common.Ballot{
+		BallotSpec: spec,
+		ID:         uuid.New(),
+		Iteration:  cs.iteration,
+		Turn:       cs.turn,
+		CloseTurn:  cs.turn + spec.Turns,
+		Votes:      map[uuid.UUID][]int{},
+	}
+	if cs.openBallots == nil {
+		cs.openBallots = make(map[uuid.UUID][]*common.Ballot)
+	}
+	cs.openBallots[spec.TeamID] = append(cs.openBallots[spec.TeamID], ballot)
+	teamLog.Debug("Ballot opened", "team", spec.TeamID, "ballot", ballot.ID, "question", spec.Question, "closes", ballot.CloseTurn)
+	return ballot.ID, nil
+}
+
+// Cast or change a vote on an open ballot; an empty ranking withdraws it
+func (cs *EnvironmentServer) CastBallot(ballotID uuid.UUID, voterID uuid.UUID, ranking []int) error {
+	ballot := cs.findOpenBallot(ballotID)
+	if ballot == nil {
+		return fmt.Errorf("no open ballot %s", ballotID)
+	}
+	if !containsID(ballot.Voters, voterID) {
+		return fmt.Errorf("agent %s may not vote on ballot %s", voterID, ballotID)
+	}
+	if len(ranking) == 0 {
+		delete(ballot.Votes, voterID)
+		return nil
+	}
+	if err := voting.Ballot(ranking).Validate(optionIndices(ballot.Options)); err != nil {
+		return err
+	}
+	ballot.Votes[voterID] = append([]int{}, ranking...)
+	return nil
+}
+
+// The team's open ballots, without the votes cast on secret ones
+func (cs *EnvironmentServer) GetOpenBallots(teamID uuid.UUID) []common.Ballot {
+	ballots := []common.Ballot{}
+	for _, ballot := range cs.openBallots[teamID] {
+		view := *ballot
+		view.Votes = nil
+		if !ballot.Secret {
+			view.Votes = copyVotes(ballot.Votes)
+		}
+		ballots = append(ballots, view)
+	}
+	return ballots
+}
+
+// Every ballot the team has closed, oldest first
+func (cs *EnvironmentServer) GetBallotResults(teamID uuid.UUID) []common.BallotResult {
+	return append([]common.BallotResult{}, cs.ballotResults[teamID]...)
+}
+
+// Close the team's ballots that are due at the end of this turn
+func (cs *EnvironmentServer) closeBallots(team *common.Team) {
+	if len(cs.openBallots[team.TeamID]) == 0 {
+		return
+	}
+	open := []*common.Ballot{}
+	for _, ballot := range cs.openBallots[team.TeamID] {
+		if ballot.Iteration == cs.iteration && ballot.CloseTurn > cs.turn {
+			open = append(open, ballot)
+			continue
+		}
+		for _, voterID := range ballot.Voters {
+			agent, ok := cs.GetAgentMap()[voterID]
+			if _, voted := ballot.Votes[voterID]; voted || !ok || cs.IsAgentDead(voterID) {
+				continue
+			}
+			ranking := agent.VoteOnBallot(*ballot)
+			if len(ranking) > 0 && voting.Ballot(ranking).Validate(optionIndices(ballot.Options)) == nil {
+				ballot.Votes[voterID] = ranking
+			}
+		}
+		result := cs.tallyBallot(ballot.ID, ballot.BallotSpec, ballot.Votes)
+		cs.recordBallot(result)
+		if hook, ok := cs.ballotHooks[ballot.ID]; ok {
+			delete(cs.ballotHooks, ballot.ID)
+			hook(result)
+		}
+	}
+	cs.openBallots[team.TeamID] = open
+}
+
+// Act on the result of an open ballot when it closes
+func (cs *EnvironmentServer) onBallotClosed(ballotID uuid.UUID, hook func(result common.BallotResult)) {
+	if cs.ballotHooks == nil {
+		cs.ballotHooks = make(map[uuid.UUID]func(common.BallotResult))
+	}
+	cs.ballotHooks[ballotID] = hook
+}
+
+/*
+* Hold a ballot on the spot, asking every eligible voter for its ranking with
+* cast (nil abstains).
+ */
+func (cs *EnvironmentServer) holdBallot(spec common.BallotSpec, cast func(voterID uuid.UUID) []int) common.BallotResult {
+	votes := map[uuid.UUID][]int{}
+	for _, voterID := range spec.Voters {
+		if _, ok := cs.GetAgentMap()[voterID]; !ok || cs.IsAgentDead(voterID) {
+			continue
+		}
+		if ranking := cast(voterID); len(ranking) > 0 {
+			votes[voterID] = ranking
+		}
+	}
+	result := cs.tallyBallot(uuid.New(), spec, votes)
+	cs.recordBallot(result)
+	return result
+}
+
+/*
+* Count the votes. Voters cast as many copies of their ranking as their
+* weight, which every voting rule counts as that many voters. Ties go to the
+* earliest option, so the status quo should come first.
+ */
+func (cs *EnvironmentServer) tallyBallot(ballotID uuid.UUID, spec common.BallotSpec, votes map[uuid.UUID][]int) common.BallotResult {
+	result := common.BallotResult{
+		BallotSpec: spec,
+		BallotID:   ballotID,
+		Iteration:  cs.iteration,
+		Turn:       cs.turn,
+		Scores:     map[int]float64{},
+		Winner:     -1,
+	}
+	if !spec.Secret {
+		result.Votes = copyVotes(votes)
+	}
+
+	options := optionIndices(spec.Options)
+	ballots := []voting.Ballot{}
+	for _, voterID := range spec.Voters {
+		weight := spec.WeightOf(voterID)
+		if cs.IsVoteBanned(voterID) {
+			weight = 0
+		}
+		result.Eligible += weight
+		ranking, voted := votes[voterID]
+		if !voted {
+			continue
+		}
+		result.Turnout += weight
+		for i := 0; i < weight; i++ {
+			ballots = append(ballots, voting.Ballot(ranking))
+		}
+	}
+	if result.Turnout == 0 || float64(result.Turnout) < spec.Quorum*float64(result.Eligible)-1e-9 {
+		return result
+	}
+
+	switch spec.Rule {
+	case common.BallotRuleThreshold:
+		tally := voting.Plurality(options, voting.Restrict(ballots, options))
+		result.Scores = tally.Scores
+		if tally.Scores[0] >= spec.Threshold*float64(result.Eligible)-1e-9 {
+			result.Winner = 0
+		} else if len(options) > 1 {
+			result.Winner = 1
+		}
+	default:
+		rule := spec.Rule
+		if _, ok := voting.LookupRule(rule); !ok {
+			rule = "plurality"
+		}
+		tally, _ := voting.Elect(rule, options, ballots)
+		result.Scores = tally.Scores
+		if len(tally.Winners) > 0 {
+			result.Winner = tally.Winners[0]
+		}
+	}
+	return result
+}
+
+func (cs *EnvironmentServer) recordBallot(result common.BallotResult) {
+	if cs.ballotResults == nil {
+		cs.ballotResults = make(map[uuid.UUID][]common.BallotResult)
+	}
+	cs.ballotResults[result.TeamID] = append(cs.ballotResults[result.TeamID], result)
+	logging.Trace(teamLog, "Ballot closed", "team", result.TeamID, "question", result.Question,
+		"chosen", result.Chosen(), "turnout", result.Turnout, "eligible", result.Eligible)
+	cs.publish(BallotEvent{
+		EventContext: cs.eventContext(),
+		TeamID:       result.TeamID,
+		BallotID:     result.BallotID,
+		Question:     result.Question,
+		Options:      result.Options,
+		Rule:         result.Rule,
+		Secret:       result.Secret,
+		Votes:        result.Votes,
+		Eligible:     result.Eligible,
+		Turnout:      result.Turnout,
+		Scores:       result.Scores,
+		Winner:       result.Winner,
+	})
+}
+
+func (cs *EnvironmentServer) findOpenBallot(ballotID uuid.UUID) *common.Ballot {
+	for _, ballots := range cs.openBallots {
+		for _, ballot := range ballots {
+			if ballot.ID == ballotID {
+				return ballot
+			}
+		}
+	}
+	return nil
+}
+
+func optionIndices(options []string) []int {
+	indices := make([]int, len(options))
+	for i := range options {
+		indices[i] = i
+	}
+	return indices
+}
+
+func copyVotes(votes map[uuid.UUID][]int) map[uuid.UUID][]int {
+	copied := make(map[uuid.UUID][]int, len(votes))
+	for voterID, ranking := range votes {
+		copied[voterID] = append([]int{}, ranking...)
+	}
+	return copied
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,532 @@
+package environmentServer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+)
+
+/*
+* Interactive step-through debugger. The debugger subscribes to every server
+* event, and when an event matches one of its breakpoints it pauses the game
+* and reads commands from its input until told to continue. Since event
+* handlers run on the server goroutine, the game is genuinely paused while the
+* prompt is open and all the views show the state at that exact point.
+ */
+
+// Breakpoint pauses the game when all of its set fields match an event. A
+// negative iteration / turn or an empty string matches anything.
+type Breakpoint struct {
+	Iteration   int
+	Turn        int
+	Phase       string    // only matches phase events
+	AgentPrefix string    // matches events involving an agent whose ID starts with this
+	EventType   EventType // matches events of this type
+}
+
+// NewBreakpoint returns a breakpoint that matches every event
+func NewBreakpoint() Breakpoint {
+	return Breakpoint{Iteration: -1, Turn: -1}
+}
+
+/*
+* Parse a breakpoint from a comma separated list of key=value pairs, e.g.
+* "iteration=2,turn=9,phase=withdrawal" or "event=Audit,agent=3f2a".
+ */
+func ParseBreakpoint(spec string) (Breakpoint, error) {
+	bp := NewBreakpoint()
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			return bp, fmt.Errorf("breakpoint field %q is not of the form key=value", field)
+		}
+		switch key {
+		case "iteration", "turn":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return bp, fmt.Errorf("breakpoint %s must be a number, got %q", key, value)
+			}
+			if key == "iteration" {
+				bp.Iteration = n
+			} else {
+				bp.Turn = n
+			}
+		case "phase":
+			bp.Phase = value
+		case "agent":
+			bp.AgentPrefix = value
+		case "event":
+			bp.EventType = EventType(value)
+		default:
+			return bp, fmt.Errorf("unknown breakpoint field %q", key)
+		}
+	}
+	return bp, nil
+}
+
+func (bp Breakpoint) String() string {
+	fields := []string{}
+	if bp.Iteration >= 0 {
+		fields = append(fields, fmt.Sprintf("iteration=%d", bp.Iteration))
+	}
+	if bp.Turn >= 0 {
+		fields = append(fields, fmt.Sprintf("turn=%d", bp.Turn))
+	}
+	if bp.Phase != "" {
+		fields = append(fields, "phase="+bp.Phase)
+	}
+	if bp.AgentPrefix != "" {
+		fields = append(fields, "agent="+bp.AgentPrefix)
+	}
+	if bp.EventType != "" {
+		fields = append(fields, "event="+string(bp.EventType))
+	}
+	if len(fields) == 0 {
+		return "<every event>"
+	}
+	return strings.Join(fields, ",")
+}
+
+func (bp Breakpoint) Matches(event Event) bool {
+	when := event.When()
+	if bp.Iteration >= 0 && bp.Iteration != when.Iteration {
+		return false
+	}
+	if bp.Turn >= 0 && bp.Turn != when.Turn {
+		return false
+	}
+	if bp.EventType != "" && bp.EventType != event.Type() {
+		return false
+	}
+	if bp.Phase != "" {
+		phaseEvent, ok := event.(PhaseEvent)
+		if !ok || phaseEvent.Phase != bp.Phase {
+			return false
+		}
+	}
+	if bp.AgentPrefix != "" {
+		involved := false
+		for _, agentID := range eventAgents(event) {
+			if strings.HasPrefix(agentID.String(), bp.AgentPrefix) {
+				involved = true
+			}
+		}
+		if !involved {
+			return false
+		}
+	}
+	return true
+}
+
+// The agents an event is about, used to match agent breakpoints
+func eventAgents(event Event) []uuid.UUID {
+	switch e := event.(type) {
+	case TeamFormedEvent:
+		return e.Agents
+	case ScoreReportEvent:
+		return []uuid.UUID{e.AgentID}
+	case ContributionEvent:
+		return []uuid.UUID{e.AgentID}
+	case WithdrawalEvent:
+		return []uuid.UUID{e.AgentID}
+	case AuditEvent:
+		return []uuid.UUID{e.AuditedAgentID}
+	case DeathEvent:
+		return []uuid.UUID{e.AgentID}
+	case RevivalEvent:
+		return []uuid.UUID{e.AgentID}
+	case OrphanAllocatedEvent:
+		return []uuid.UUID{e.AgentID}
+	case TeamJoinedEvent:
+		return []uuid.UUID{e.AgentID}
+	case AmendmentEvent:
+		return []uuid.UUID{e.ProposerID}
+	case SanctionEvent:
+		return []uuid.UUID{e.AgentID}
+	case AppealEvent:
+		return []uuid.UUID{e.AgentID}
+	case AccusationEvent:
+		return []uuid.UUID{e.AccuserID, e.AccusedID}
+	case BallotEvent:
+		voters := []uuid.UUID{}
+		for voterID := range e.Votes {
+			voters = append(voters, voterID)
+		}
+		return voters
+	case ElectionEvent:
+		return append(append([]uuid.UUID{}, e.Previous...), e.Holders...)
+	case RankChangeEvent:
+		return []uuid.UUID{e.AgentID}
+	case OffspringEvent:
+		return []uuid.UUID{e.AgentID, e.ReplacedAgentID, e.ParentID}
+	}
+	return nil
+}
+
+type Debugger struct {
+	server      *EnvironmentServer
+	in          *bufio.Scanner
+	out         io.Writer
+	breakpoints []Breakpoint
+
+	stepping bool // pause at the next phase event regardless of breakpoints
+	detached bool // stop pausing altogether
+
+	// everything the debugger has seen, so that past audits can be inspected
+	audits []AuditEvent
+}
+
+/*
+* Attach an interactive debugger to the server. The prompt reads commands from
+* in and writes all its output to out (normally os.Stdin and os.Stdout).
+ */
+func (cs *EnvironmentServer) AttachDebugger(in io.Reader, out io.Writer, breakpoints []Breakpoint) *Debugger {
+	d := &Debugger{
+		server:      cs,
+		in:          bufio.NewScanner(in),
+		out:         out,
+		breakpoints: breakpoints,
+	}
+	cs.OnAudit(func(e AuditEvent) {
+		d.audits = append(d.audits, e)
+	})
+	cs.OnAnyEvent(d.handleEvent)
+	return d
+}
+
+func (d *Debugger) handleEvent(event Event) {
+	if d.detached {
+		return
+	}
+	_, isPhase := event.(PhaseEvent)
+	if d.stepping && isPhase {
+		d.stepping = false
+		d.pause(event, "step")
+		return
+	}
+	for i, bp := range d.breakpoints {
+		if bp.Matches(event) {
+			d.pause(event, fmt.Sprintf("breakpoint %d (%v)", i, bp))
+			return
+		}
+	}
+}
+
+// Show the prompt until the user asks to resume the game
+func (d *Debugger) pause(event Event, reason string) {
+	when := event.When()
+	fmt.Fprintf(d.out, "\n[debug] paused at iteration %d, turn %d on %s: %s\n", when.Iteration, when.Turn, reason, DescribeEvent(event))
+	for {
+		fmt.Fprint(d.out, "(debug) ")
+		if !d.in.Scan() {
+			// input closed, let the game run to completion
+			d.detached = true
+			return
+		}
+		fields := strings.Fields(d.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if d.runCommand(fields[0], fields[1:]) {
+			return
+		}
+	}
+}
+
+// Run a single prompt command, returning true if the game should resume
+func (d *Debugger) runCommand(command string, args []string) bool {
+	filters := parseFilters(args)
+	switch command {
+	case "c", "continue":
+		return true
+	case "s", "step":
+		d.stepping = true
+		return true
+	case "detach":
+		d.detached = true
+		return true
+	case "teams":
+		d.printTeams(filters)
+	case "pools":
+		d.printPools(filters)
+	case "scores":
+		d.printScores(filters)
+	case "audits":
+		d.printAudits(filters)
+	case "orphans":
+		d.printOrphans(filters)
+	case "break":
+		bp, err := ParseBreakpoint(strings.Join(args, ","))
+		if err != nil {
+			fmt.Fprintf(d.out, "error: %v\n", err)
+			break
+		}
+		d.breakpoints = append(d.breakpoints, bp)
+		fmt.Fprintf(d.out, "breakpoint %d: %v\n", len(d.breakpoints)-1, bp)
+	case "breaks":
+		for i, bp := range d.breakpoints {
+			fmt.Fprintf(d.out, "%d: %v\n", i, bp)
+		}
+	case "delete":
+		if len(args) != 1 {
+			fmt.Fprintln(d.out, "usage: delete <breakpoint number>")
+			break
+		}
+		i, err := strconv.Atoi(args[0])
+		if err != nil || i < 0 || i >= len(d.breakpoints) {
+			fmt.Fprintf(d.out, "no breakpoint %q\n", args[0])
+			break
+		}
+		d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+	case "h", "help":
+		d.printHelp()
+	default:
+		fmt.Fprintf(d.out, "unknown command %q, type 'help' for a list of commands\n", command)
+	}
+	return false
+}
+
+func (d *Debugger) printHelp() {
+	fmt.Fprint(d.out, `commands:
+  continue | c                 resume until the next breakpoint
+  step | s                     resume until the start of the next phase
+  detach                       resume and never pause again
+  teams   [team=..] [agent=..] teams, their members, AoA and common pool
+  pools   [team=..]            common pool of each team
+  scores  [team=..] [agent=..] [alive|dead]
+                               agent scores, highest first
+  audits  [team=..] [agent=..] [phase=..] [failed]
+                               audits run so far
+  orphans [agent=..]           agents waiting in the orphan pool
+  break key=value ...          add a breakpoint (iteration, turn, phase, agent, event)
+  breaks                       list breakpoints
+  delete <n>                   remove breakpoint n
+IDs in filters can be shortened to any prefix.
+`)
+}
+
+// --------- Views ---------
+
+// Filters are given as key=value pairs, or as bare flags (e.g. "failed")
+type filters map[string]string
+
+func parseFilters(args []string) filters {
+	f := make(filters)
+	for _, arg := range args {
+		key, value, _ := strings.Cut(arg, "=")
+		f[key] = value
+	}
+	return f
+}
+
+// Check an ID against the prefix given for a filter key, if any
+func (f filters) matchID(key string, id uuid.UUID) bool {
+	prefix, ok := f[key]
+	return !ok || strings.HasPrefix(id.String(), prefix)
+}
+
+func (f filters) has(key string) bool {
+	_, ok := f[key]
+	return ok
+}
+
+func shortID(id uuid.UUID) string {
+	return id.String()[:8]
+}
+
+func shortIDs(ids []uuid.UUID) string {
+	short := make([]string, 0, len(ids))
+	for _, id := range ids {
+		short = append(short, shortID(id))
+	}
+	return strings.Join(short, " ")
+}
+
+// Teams sorted by ID so that repeated views are stable
+func (d *Debugger) sortedTeamIDs() []uuid.UUID {
+	teamIDs := d.server.GetTeamIDs()
+	sort.Slice(teamIDs, func(i, j int) bool { return teamIDs[i].String() < teamIDs[j].String() })
+	return teamIDs
+}
+
+func (d *Debugger) printTeams(f filters) {
+	w := tabwriter.NewWriter(d.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TEAM\tAOA\tPOOL\tMEMBERS")
+	for _, teamID := range d.sortedTeamIDs() {
+		team := d.server.Teams[teamID]
+		if !f.matchID("team", teamID) {
+			continue
+		}
+		if f.has("agent") {
+			found := false
+			for _, agentID := range team.Agents {
+				found = found || f.matchID("agent", agentID)
+			}
+			if !found {
+				continue
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", shortID(teamID), team.TeamAoAID, team.GetCommonPool(), shortIDs(team.Agents))
+	}
+	w.Flush()
+}
+
+func (d *Debugger) printPools(f filters) {
+	w := tabwriter.NewWriter(d.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TEAM\tPOOL")
+	for _, teamID := range d.sortedTeamIDs() {
+		if f.matchID("team", teamID) {
+			fmt.Fprintf(w, "%s\t%d\n", shortID(teamID), d.server.Teams[teamID].GetCommonPool())
+		}
+	}
+	w.Flush()
+}
+
+func (d *Debugger) printScores(f filters) {
+	type row struct {
+		agentID uuid.UUID
+		teamID  uuid.UUID
+		somasID int
+		score   int
+		alive   bool
+	}
+	rows := []row{}
+	for _, agent := range d.server.GetAgentMap() {
+		rows = append(rows, row{agent.GetID(), agent.GetTeamID(), agent.GetTrueSomasTeamID(), agent.GetTrueScore(), true})
+	}
+	for _, agent := range d.server.deadAgents {
+		rows = append(rows, row{agent.GetID(), agent.GetLastTeamID(), agent.GetTrueSomasTeamID(), agent.GetTrueScore(), false})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].score > rows[j].score })
+
+	w := tabwriter.NewWriter(d.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "AGENT\tSOMAS TEAM\tTEAM\tSCORE\tSTATUS")
+	for _, r := range rows {
+		if !f.matchID("agent", r.agentID) || !f.matchID("team", r.teamID) {
+			continue
+		}
+		if (f.has("alive") && !r.alive) || (f.has("dead") && r.alive) {
+			continue
+		}
+		status := "alive"
+		if !r.alive {
+			status = "dead"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\n", shortID(r.agentID), r.somasID, shortID(r.teamID), r.score, status)
+	}
+	w.Flush()
+}
+
+func (d *Debugger) printAudits(f filters) {
+	w := tabwriter.NewWriter(d.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ITER\tTURN\tTEAM\tAGENT\tPHASE\tCOST\tCHEATED")
+	for _, audit := range d.audits {
+		if !f.matchID("team", audit.TeamID) || !f.matchID("agent", audit.AuditedAgentID) {
+			continue
+		}
+		if phase, ok := f["phase"]; ok && phase != audit.Phase {
+			continue
+		}
+		if f.has("failed") && !audit.Result {
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%d\t%v\n", audit.Iteration, audit.Turn, shortID(audit.TeamID), shortID(audit.AuditedAgentID), audit.Phase, audit.Cost, audit.Result)
+	}
+	w.Flush()
+}
+
+func (d *Debugger) printOrphans(f filters) {
+	orphanIDs := make([]uuid.UUID, 0, len(d.server.orphanPool))
+	for orphanID := range d.server.orphanPool {
+		orphanIDs = append(orphanIDs, orphanID)
+	}
+	sort.Slice(orphanIDs, func(i, j int) bool { return orphanIDs[i].String() < orphanIDs[j].String() })
+
+	w := tabwriter.NewWriter(d.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "AGENT\tWANTS TO JOIN")
+	for _, orphanID := range orphanIDs {
+		if f.matchID("agent", orphanID) {
+			fmt.Fprintf(w, "%s\t%s\n", shortID(orphanID), shortIDs(d.server.orphanPool[orphanID]))
+		}
+	}
+	w.Flush()
+}
+
+// DescribeEvent gives a one line summary of an event, for prompts and timelines
+func DescribeEvent(event Event) string {
+	switch e := event.(type) {
+	case PhaseEvent:
+		if e.TeamID == uuid.Nil {
+			return fmt.Sprintf("start of %s phase", e.Phase)
+		}
+		return fmt.Sprintf("start of %s phase for team %s", e.Phase, shortID(e.TeamID))
+	case ScoreReportEvent:
+		if e.Declared != e.Rolled {
+			return fmt.Sprintf("agent %s declared a turn score of %d (rolled %d)", shortID(e.AgentID), e.Declared, e.Rolled)
+		}
+		return fmt.Sprintf("agent %s declared a turn score of %d", shortID(e.AgentID), e.Declared)
+	case ContributionEvent:
+		return fmt.Sprintf("agent %s contributed %d (stated %d)", shortID(e.AgentID), e.ActualContribution, e.StatedContribution)
+	case WithdrawalEvent:
+		return fmt.Sprintf("agent %s withdrew %d (stated %d), pool now %d", shortID(e.AgentID), e.ActualWithdrawal, e.StatedWithdrawal, e.CommonPoolAfter)
+	case AuditEvent:
+		if e.Infraction != e.Result {
+			return fmt.Sprintf("%s audit of agent %s, cheated: %v (records show %v)", e.Phase, shortID(e.AuditedAgentID), e.Result, e.Infraction)
+		}
+		return fmt.Sprintf("%s audit of agent %s, cheated: %v", e.Phase, shortID(e.AuditedAgentID), e.Result)
+	case DeathEvent:
+		return fmt.Sprintf("agent %s died with score %d", shortID(e.AgentID), e.Score)
+	case TeamJoinedEvent:
+		return fmt.Sprintf("agent %s joined team %s", shortID(e.AgentID), shortID(e.TeamID))
+	case AoASelectedEvent:
+		return fmt.Sprintf("team %s chose AoA %d (voted %d) by %s vote (scores %v, winners by rule %v)",
+			shortID(e.TeamID), e.AoAID, e.VotedAoAID, e.Rule, e.Scores, e.WinnersByRule)
+	case AmendmentEvent:
+		outcome := "rejected"
+		if e.Passed {
+			outcome = "passed"
+		}
+		return fmt.Sprintf("team %s amendment from AoA %d to %d %s, %d/%d for (needs %.0f%%)",
+			shortID(e.TeamID), e.FromAoAID, e.ToAoAID, outcome, e.VotesFor, e.Votes, 100*e.Majority)
+	case SanctionEvent:
+		return fmt.Sprintf("agent %s sanctioned after failing its %s audit (offence %d): warned: %v, fined %d, banned from %d withdrawals and %d turns of votes, expelled: %v",
+			shortID(e.AgentID), e.Phase, e.Offences, e.Warning, e.Fine, e.WithdrawalBan, e.VoteBan, e.Expelled)
+	case AppealEvent:
+		outcome := "upheld"
+		if e.Overturned {
+			outcome = fmt.Sprintf("overturned, %d refunded, readmitted: %v", e.Refund, e.Readmitted)
+		}
+		return fmt.Sprintf("agent %s appealed its %s audit by %s for a fee of %d: %s", shortID(e.AgentID), e.Phase, e.Method, e.Fee, outcome)
+	case AccusationEvent:
+		return fmt.Sprintf("agent %s's accusation of agent %s in the %s phase was %s: rewarded %d, penalised %d",
+			shortID(e.AccuserID), shortID(e.AccusedID), e.Phase, e.Outcome, e.Reward, e.Penalty)
+	case BallotEvent:
+		chosen := "nothing"
+		if e.Winner >= 0 && e.Winner < len(e.Options) {
+			chosen = fmt.Sprintf("%q", e.Options[e.Winner])
+		}
+		return fmt.Sprintf("team %s ballot on %q chose %s, turnout %d/%d", shortID(e.TeamID), e.Question, chosen, e.Turnout, e.Eligible)
+	case ElectionEvent:
+		holders := []string{}
+		for _, id := range e.Holders {
+			holders = append(holders, shortID(id))
+		}
+		return fmt.Sprintf("team %s elected %s %v by %s (%s)", shortID(e.TeamID), e.Role, holders, e.Method, e.Reason)
+	case RankChangeEvent:
+		return fmt.Sprintf("team %s moved agent %s from rank %d to %d", shortID(e.TeamID), shortID(e.AgentID), e.From, e.To)
+	case OffspringEvent:
+		return fmt.Sprintf("agent %s (%s %s) replaced dead agent %s, parent %s",
+			shortID(e.AgentID), e.Strategy, e.Params, shortID(e.ReplacedAgentID), shortID(e.ParentID))
+	}
+	return fmt.Sprintf("%s event", event.Type())
+}
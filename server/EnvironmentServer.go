@@ -2,12 +2,14 @@ package environmentServer
 
 import (
 	"fmt"
-	"log"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
 	gameRecorder "github.com/ADimoska/SOMASExtended/gameRecorder"
+	"github.com/ADimoska/SOMASExtended/logging"
+	"github.com/ADimoska/SOMASExtended/voting"
 	"github.com/google/uuid"
 
 	"github.com/MattSScott/basePlatformSOMAS/v2/pkg/server"
@@ -29,47 +31,102 @@ type EnvironmentServer struct {
 	// data recorder
 	DataRecorder *gameRecorder.ServerDataRecorder
 
+	// subscribers to game events
+	eventBus *EventBus
+
 	// server internal state
 	turn           int
 	iteration      int
 	thresholdTurns int
+	turnsPlayed    int // turns over all iterations, which bans are counted in
+
+	// rule teams vote on their AoA with, and the parameters of each AoA by ID
+	votingRule string
+	aoaParams  map[int]common.AoAParams
+
+	// how the round score threshold is chosen each iteration
+	thresholdPolicy ThresholdPolicy
+	thresholdValue  int
+
+	// how dead agents come back at the start of an iteration (nil revives them)
+	evolution *evolution
+
+	// how often audits miss cheats or convict honest agents (zero value is perfect)
+	auditReliability common.AuditReliability
+
+	// every audit held by each team, by team ID
+	auditHistory map[uuid.UUID][]common.AuditEntry
+
+	// ballots still open and the results of closed ones, by team ID
+	openBallots   map[uuid.UUID][]*common.Ballot
+	ballotResults map[uuid.UUID][]common.BallotResult
+	ballotHooks   map[uuid.UUID]func(common.BallotResult)
+
+	// roles of each team's AoA and who holds them, by team ID
+	teamRoles map[uuid.UUID]map[common.Role]*roleState
+
+	// accusations of the current turn not settled yet, by team ID
+	accusations map[uuid.UUID][]common.Accusation
+
+	// the turn scores declared in the current turn against those rolled, by team ID
+	scoreReports map[uuid.UUID][]common.ScoreReport
+
+	// the last turn, by turnsPlayed, of each agent's withdrawal and vote bans
+	withdrawalBans map[uuid.UUID]int
+	voteBans       map[uuid.UUID]int
 }
 
+// loggers of the parts of the game run by the server
+var (
+	serverLog = logging.For(logging.ComponentServer)
+	teamLog   = logging.For(logging.ComponentTeam)
+	aoaLog    = logging.For(logging.ComponentAoA)
+	orphanLog = logging.For(logging.ComponentOrphan)
+)
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
 func (cs *EnvironmentServer) RunTurn(i, j int) {
-	log.Printf("\n\nIteration %v, Turn %v, current agent count: %v\n", i, j, len(cs.GetAgentMap()))
+	cs.turn = j
+	cs.turnsPlayed++
+	serverLog.Info("Start of turn", "agentCount", len(cs.GetAgentMap()))
+	cs.publish(TurnStartEvent{EventContext: cs.eventContext(), AgentCount: len(cs.GetAgentMap())})
 
 	// Go over the list of all agents and add orphans to the orphan pool if
 	// they are not already there
+	cs.publishPhase(PhaseOrphanAllocation, uuid.Nil)
 	cs.PickUpOrphans()
 
 	// Attempt to allocate the orphans to their preferred teams
 	cs.AllocateOrphans()
 
-	cs.turn = j
+	// The lock is only held to take the teams, so that event handlers called
+	// during the turn can call back into the server
+	cs.teamsMutex.RLock()
+	teams := make([]*common.Team, 0, len(cs.Teams))
+	for _, team := range cs.Teams {
+		teams = append(teams, team)
+	}
+	cs.teamsMutex.RUnlock()
 
-	cs.teamsMutex.Lock()
-	// defer cs.teamsMutex.Unlock()
+	for _, team := range teams {
+		teamLog.Debug("Running turn", "team", team.TeamID)
+		cs.runElections(team)
+		cs.runScoreReports(team)
 
-	for _, team := range cs.Teams {
-		log.Println("\nRunning turn for team ", team.TeamID)
 		// Sum of contributions from all agents in the team for this turn
 		if team.TeamAoAID == 5 {
 			cs.Team5_RunTurn(team)
 		} else {
+			cs.publishPhase(PhaseContribution, team.TeamID)
 			agentContributionsTotal := 0
 			for _, agentID := range team.Agents {
 				agent := cs.GetAgentMap()[agentID]
 				if agent.GetTeamID() == uuid.Nil || cs.IsAgentDead(agentID) {
 					continue
 				}
-				// Override agent rolls for testing purposes
-				// agentList := []uuid.UUID{agentID}
-				// cs.OverrideAgentRolls(agentID, agentList, 1)
-				agent.StartRollingDice(agent)
 				agentActualContribution := agent.GetActualContribution(agent)
 				agentContributionsTotal += agentActualContribution
 				agentStatedContribution := agent.GetStatedContribution(agent)
@@ -79,6 +136,13 @@ func (cs *EnvironmentServer) RunTurn(i, j int) {
 				// Update audit result for this agent
 				team.TeamAoA.SetContributionAuditResult(agentID, agentScore, agentActualContribution, agentStatedContribution)
 				agent.SetTrueScore(agentScore - agentActualContribution)
+				cs.publish(ContributionEvent{
+					EventContext:       cs.eventContext(),
+					TeamID:             team.TeamID,
+					AgentID:            agentID,
+					ActualContribution: agentActualContribution,
+					StatedContribution: agentStatedContribution,
+				})
 			}
 
 			// Update common pool with total contribution from this team
@@ -87,22 +151,40 @@ func (cs *EnvironmentServer) RunTurn(i, j int) {
 			team.SetCommonPool(team.GetCommonPool() + agentContributionsTotal)
 
 			// Initiate Contribution Audit vote
+			cs.publishPhase(PhaseContributionAudit, team.TeamID)
 			contributionAuditVotes := []common.Vote{}
 			for _, agentID := range team.Agents {
+				if cs.IsVoteBanned(agentID) {
+					continue
+				}
 				agent := cs.GetAgentMap()[agentID]
 				vote := agent.GetContributionAuditVote()
 				contributionAuditVotes = append(contributionAuditVotes, vote)
 			}
 
 			// Execute Contribution Audit if necessary
-			if agentToAudit := team.TeamAoA.GetVoteResult(contributionAuditVotes); agentToAudit != uuid.Nil {
-				auditResult := team.TeamAoA.GetContributionAuditResult(agentToAudit)
-				for _, agentID := range team.Agents {
-					agent := cs.GetAgentMap()[agentID]
-					agent.SetAgentContributionAuditResult(agentToAudit, auditResult)
+			if agentToAudit := cs.auditTarget(team, "contribution", contributionAuditVotes); agentToAudit != uuid.Nil {
+				auditCost := team.TeamAoA.GetAuditCost(team.GetCommonPool())
+				if auditCost <= team.GetCommonPool() {
+					// Deduct the audit cost from the common pool
+					team.SetCommonPool(team.GetCommonPool() - auditCost)
+					teamLog.Debug("Audit cost deducted", "team", team.TeamID, "cost", auditCost, "pool", team.GetCommonPool())
+
+					infraction := team.TeamAoA.GetContributionAuditResult(agentToAudit)
+					auditResult := cs.auditVerdict(team, infraction, auditCost)
+					for _, agentID := range team.Agents {
+						agent := cs.GetAgentMap()[agentID]
+						agent.SetAgentContributionAuditResult(agentToAudit, auditResult)
+					}
+					cs.concludeAudit(team, agentToAudit, "contribution", contributionAuditVotes, auditCost, infraction, auditResult)
+				} else {
+					teamLog.Info("Not enough resources in the common pool to cover the audit cost, skipping audit", "team", team.TeamID, "cost", auditCost)
 				}
 			}
 
+			cs.runGovernance(team)
+
+			cs.publishPhase(PhaseWithdrawal, team.TeamID)
 			orderedAgents := team.TeamAoA.GetWithdrawalOrder(team.Agents)
 			commonPoolBefore := team.GetCommonPool()
 			for _, agentID := range orderedAgents {
@@ -110,6 +192,10 @@ func (cs *EnvironmentServer) RunTurn(i, j int) {
 				if agent.GetTeamID() == uuid.Nil || cs.IsAgentDead(agentID) {
 					continue
 				}
+				if cs.IsWithdrawalBanned(agentID) {
+					teamLog.Debug("Agent is banned from withdrawing", "team", team.TeamID, "agent", agentID)
+					continue
+				}
 
 				// Pass the current pool value to agent's methods
 				currentPool := team.GetCommonPool()
@@ -127,7 +213,8 @@ func (cs *EnvironmentServer) RunTurn(i, j int) {
 				// Update the common pool after each withdrawal so agents can see the updated pool before deciding their withdrawal.
 				//  Different to the contribution phase!
 				team.SetCommonPool(currentPool - agentActualWithdrawal)
-				log.Printf("[server] Agent %v withdrew %v. Remaining pool: %v\n", agentID, agentActualWithdrawal, team.GetCommonPool())
+				teamLog.Debug("Agent withdrew", "team", team.TeamID, "agent", agentID, "amount", agentActualWithdrawal, "pool", team.GetCommonPool())
+				cs.publishWithdrawal(team, agentID, agentActualWithdrawal, agentStatedWithdrawal)
 			}
 
 			stateWithdrawOrder := make([]uuid.UUID, len(team.Agents))
@@ -146,251 +233,206 @@ func (cs *EnvironmentServer) RunTurn(i, j int) {
 			}
 
 			// Initiate Withdrawal Audit vote
+			cs.publishPhase(PhaseWithdrawalAudit, team.TeamID)
 			withdrawalAuditVotes := []common.Vote{}
 			for _, agentID := range team.Agents {
+				if cs.IsVoteBanned(agentID) {
+					continue
+				}
 				agent := cs.GetAgentMap()[agentID]
 				vote := agent.GetWithdrawalAuditVote()
 				withdrawalAuditVotes = append(withdrawalAuditVotes, vote)
 			}
 
 			// Execute Withdrawal Audit if necessary
-			if agentToAudit := team.TeamAoA.GetVoteResult(withdrawalAuditVotes); agentToAudit != uuid.Nil {
-				auditResult := team.TeamAoA.GetWithdrawalAuditResult(agentToAudit)
-				for _, agentID := range team.Agents {
-					agent := cs.GetAgentMap()[agentID]
-					agent.SetAgentWithdrawalAuditResult(agentToAudit, auditResult)
+			if agentToAudit := cs.auditTarget(team, "withdrawal", withdrawalAuditVotes); agentToAudit != uuid.Nil {
+				auditCost := team.TeamAoA.GetAuditCost(team.GetCommonPool())
+				if auditCost <= team.GetCommonPool() {
+					// Deduct the audit cost from the common pool
+					team.SetCommonPool(team.GetCommonPool() - auditCost)
+					teamLog.Debug("Audit cost deducted", "team", team.TeamID, "cost", auditCost, "pool", team.GetCommonPool())
+
+					infraction := team.TeamAoA.GetWithdrawalAuditResult(agentToAudit)
+					auditResult := cs.auditVerdict(team, infraction, auditCost)
+					for _, agentID := range team.Agents {
+						agent := cs.GetAgentMap()[agentID]
+						agent.SetAgentWithdrawalAuditResult(agentToAudit, auditResult)
+					}
+					cs.concludeAudit(team, agentToAudit, "withdrawal", withdrawalAuditVotes, auditCost, infraction, auditResult)
+				} else {
+					teamLog.Info("Not enough resources in the common pool to cover the audit cost, skipping audit", "team", team.TeamID, "cost", auditCost)
 				}
 			}
 		}
+
+		// Members may vote to change the team's AoA before the next turn
+		cs.runAmendments(team)
+		cs.closeBallots(team)
+		cs.expireAccusations(team)
+		cs.passOffenceTurn(team)
 	}
 
 	// TODO: Reallocate agents who left their teams during the turn
 
 	// check if threshold turn
-
-	cs.teamsMutex.Unlock()
-
 	if cs.turn%cs.thresholdTurns == 0 && cs.turn > 1 {
+		cs.publishPhase(PhaseThreshold, uuid.Nil)
 		cs.ApplyThreshold()
 	}
 
+	// notify subscribers (including the data recorder) that the turn is over
 	cs.teamsMutex.Lock()
-
-	// record data
-	cs.RecordTurnInfo()
+	agentRecords, teamRecords := cs.collectTurnRecords()
 	cs.teamsMutex.Unlock()
+	cs.publish(TurnEndEvent{
+		EventContext: cs.eventContext(),
+		AgentCount:   len(cs.GetAgentMap()),
+		Agents:       agentRecords,
+		Teams:        teamRecords,
+	})
 }
 
 func (cs *EnvironmentServer) RunStartOfIteration(iteration int) {
-	log.Printf("--------Start of iteration %v---------\n", iteration)
-
 	cs.iteration = iteration
-
-	// record data
-	cs.DataRecorder.RecordNewIteration()
+	cs.turn = 0
+	serverLog.Info("Start of iteration")
 
 	// Initialise random threshold
 	cs.createNewRoundScoreThreshold()
 
-	// Revive all dead agents
-	cs.reviveDeadAgents()
+	cs.publish(IterationStartEvent{
+		EventContext:        cs.eventContext(),
+		RoundScoreThreshold: cs.roundScoreThreshold,
+		AgentCount:          len(cs.GetAgentMap()),
+	})
+
+	// Revive all dead agents, or replace them with offspring in evolutionary mode
+	if cs.evolution != nil {
+		cs.replaceDeadAgents()
+	} else {
+		cs.reviveDeadAgents()
+	}
 
 	// reset all agents (make sure their score starts at 0)
 	cs.ResetAgents()
 
 	// start team forming
+	cs.publishPhase(PhaseTeamFormation, uuid.Nil)
 	cs.StartAgentTeamForming()
 
 	// take votes at team level and allocate Strategy.
+	cs.publishPhase(PhaseAoAVote, uuid.Nil)
 	cs.allocateAoAs()
 }
 
-func runCopelandVote(team *common.Team, cs *EnvironmentServer) []int {
-
-	pairwiseWins := make(map[string]int)
-	copelandScores := make(map[byte]float64)
-
-	fmt.Printf("Starting Copeland Vote for Team %s with %d members.\n", team.TeamID, len(team.Agents))
-	// Loop through each agent in the team
-
-	for _, agent := range team.Agents {
-
-		agentAoARanking := cs.GetAgentMap()[agent].GetAoARanking()
-
-		fmt.Printf("Agent %s has the following AoA rankings:\n", agent)
-		fmt.Println(agentAoARanking)
-
-		// Loop through each pair of ranked candidates and perform pairwise comparison
-		for i := 0; i < len(agentAoARanking); i++ {
-			for j := i + 1; j < len(agentAoARanking); j++ {
-				if agentAoARanking[i] < agentAoARanking[j] {
-
-					pair := []int{agentAoARanking[i], agentAoARanking[j]}
-
-					pairKey := fmt.Sprintf("%d%d", pair[0], pair[1])
-
-					fmt.Printf("Agent %s: Comparing candidates %d and %d. Winner: %d\n", agent, pair[0], pair[1], pair[0])
-
-					pairwiseWins[pairKey]++
-				} else {
-
-					pair := []int{agentAoARanking[j], agentAoARanking[i]}
-
-					pairKey := fmt.Sprintf("%d%d", pair[0], pair[1])
-
-					fmt.Printf("Agent %s: Comparing candidates %d and %d. Winner: %d\n", agent, pair[1], pair[0], pair[1])
-
-					pairwiseWins[pairKey] -= 1
-				}
-
-			}
-		}
+/*
+* Every team votes on its AoA. Each member hands in its AoA ranking as a
+* ballot and the game's voting rule picks the winners; ties are broken by a
+* Borda count among the tied AoAs, then at random.
+ */
+func (cs *EnvironmentServer) allocateAoAs() {
+	if cs.votingRule == "" {
+		cs.votingRule = voting.DefaultRule
 	}
-
-	fmt.Println(pairwiseWins)
-	for pair, score := range pairwiseWins {
-		// Subtract ASCII value of 0
-		candidate1 := pair[0] - 48
-		candidate2 := pair[1] - 48
-
-		fmt.Printf("Processing pair %s (candidate 1: %d, candidate 2: %d), score: %d\n", pair, candidate1, candidate2, score)
-
-		if score > 0 {
-			copelandScores[candidate1] += 1
-			fmt.Printf("Candidate %d wins, Copeland score updated: %v\n", candidate1, copelandScores[candidate1])
-
-		} else if score < 0 {
-			copelandScores[candidate2] += 1
-			fmt.Printf("Candidate %d wins, Copeland score updated: %v\n", candidate2, copelandScores[candidate2])
-		} else {
-			copelandScores[candidate1] += 0.5
-			copelandScores[candidate2] += 0.5
-			fmt.Printf("It's a tie! Copeland scores updated: %v, %v\n", copelandScores[candidate1], copelandScores[candidate2])
-
+	for _, team := range cs.Teams {
+		ballots := cs.collectAoABallots(team)
+		candidates := voting.CandidatesOf(ballots)
+		if len(candidates) == 0 {
+			// nobody ranked a registered AoA
+			candidates = []int{common.DefaultAoAID}
 		}
-	}
-	fmt.Println(copelandScores)
-
-	var maxScore float64
-	var maxCandidates []int
-	for key, score := range copelandScores {
-		candidate := int(key)
-		if score > maxScore {
-			maxScore = score
-			maxCandidates = []int{candidate}
-		} else if score == maxScore {
-			maxCandidates = append(maxCandidates, candidate)
+		tally, err := voting.Elect(cs.votingRule, candidates, ballots)
+		if err != nil {
+			aoaLog.Error("AoA vote failed", "team", team.TeamID, "err", err)
+			continue
 		}
-	}
+		aoaLog.Debug("AoA vote", "team", team.TeamID, "rule", tally.Rule, "scores", tally.Scores, "winners", tally.Winners)
+		winners := tally.Winners
+		if len(winners) > 1 && tally.Rule != "borda" {
+			aoaLog.Debug("Multiple winners, running Borda vote", "team", team.TeamID)
+			winners = voting.Borda(winners, voting.Restrict(ballots, winners)).Winners
+		}
+		// Select random AoA if still tied, else select 'winner'
+		if len(winners) > 0 {
 
-	fmt.Printf("\nWinning candidates for Team %s: %v\n", team.TeamID, maxCandidates)
+			// Generate random index (uses the global source so seeded games are repeatable)
+			randomI := rand.Intn(len(winners))
+			preference := winners[randomI]
 
-	return maxCandidates
-}
+			// Build the chosen AoA, or the default one if it does not exist
+			team.TeamAoA, team.TeamAoAID = common.CreateAoA(preference, team, cs.aoaParams[preference])
 
-// Aggregates scores for candidates returns all candidates who have the highest score
-func runBordaVote(team *common.Team, aoaCandidates []int, cs *EnvironmentServer) []int {
+			cs.Teams[team.TeamID] = team
+			aoaLog.Info("AoA selected", "team", team.TeamID, "aoa", team.TeamAoAID, "voted", preference)
+			cs.publish(AoASelectedEvent{
+				EventContext:  cs.eventContext(),
+				TeamID:        team.TeamID,
+				AoAID:         team.TeamAoAID,
+				VotedAoAID:    preference,
+				Rule:          tally.Rule,
+				Ballots:       ballots,
+				Scores:        tally.Scores,
+				WinnersByRule: voting.CompareRules(candidates, ballots),
+			})
 
-	aoaCandidatesSet := make(map[int]struct{})
-	for _, candidate := range aoaCandidates {
-		aoaCandidatesSet[candidate] = struct{}{}
+		}
 	}
+}
 
-	voteSum := make(map[int]int) // key = AoA candidate, value = total votes
-	n := len(aoaCandidates)
-	for _, agent := range team.Agents {
-
-		agentRanking := cs.GetAgentMap()[agent].GetAoARanking()
-		fmt.Printf("Agent %s has the following AoA rankings:\n", agent)
-		fmt.Println((agentRanking))
-
-		// Check if the current AoA is a candidate
-		for vote, aoa := range agentRanking {
-			if _, exists := aoaCandidatesSet[aoa]; exists {
-				points := n - vote - 1
-				voteSum[aoa] += points
-				fmt.Printf("Agent %s votes for AoA %d with %d point\n", agent, aoa, points)
-			}
+/*
+* The AoA rankings of a team's members. Only registered AoAs are candidates,
+* and an AoA ranked twice only counts once.
+ */
+func (cs *EnvironmentServer) collectAoABallots(team *common.Team) []voting.Ballot {
+	registered := common.AoAIDs()
+	ballots := []voting.Ballot{}
+	for _, agentID := range team.Agents {
+		agent, ok := cs.GetAgentMap()[agentID]
+		if !ok {
+			continue
+		}
+		ballot := voting.Ballot(agent.GetAoARanking())
+		logging.Trace(aoaLog, "AoA ranking", "team", team.TeamID, "agent", agentID, "ranking", ballot)
+		if err := ballot.Validate(registered); err != nil {
+			aoaLog.Warn("Invalid AoA ballot", "agent", agentID, "err", err)
+			ballot = ballot.Clean(registered)
 		}
+		ballots = append(ballots, ballot)
 	}
+	return ballots
+}
 
-	fmt.Println("\nCandidates scores:")
-	fmt.Println(voteSum)
-	var filtered []int
-
-	if len(voteSum) == 1 {
-		return filtered
+// Override the default parameters of the named AoA for this game
+func (cs *EnvironmentServer) SetAoAParams(name string, params common.AoAParams) error {
+	resolved, err := common.ResolveAoAParams(name, params)
+	if err != nil {
+		return err
 	}
-
-	// Initialize maxVotes to the first candidate's score
-	maxVotes := voteSum[aoaCandidates[0]]
-
-	// Find the max score and filter candidates with the max score
-	for candidate, score := range voteSum {
-		if score > maxVotes {
-			maxVotes = score
-			// Reset filtered list with the new max score
-			filtered = []int{candidate}
-		} else if score == maxVotes {
-			filtered = append(filtered, candidate)
-		}
-
-		fmt.Printf("Processing candidate %d with score %d\n", candidate, score)
+	info, _ := common.LookupAoAByName(name)
+	if cs.aoaParams == nil {
+		cs.aoaParams = make(map[int]common.AoAParams)
 	}
-
-	// Remove candidates below a threshold (check if there are ties)
-	fmt.Println("\nFiltered candidates after tie removal:")
-	fmt.Println(filtered)
-
-	return filtered
+	cs.aoaParams[info.ID] = resolved
+	return nil
 }
 
-func (cs *EnvironmentServer) allocateAoAs() {
-	for _, team := range cs.Teams {
-		winners := runCopelandVote(team, cs)
-		if len(winners) > 1 {
-			fmt.Println("Multiple winners detected. Running Borda Vote.")
-			winners = runBordaVote(team, winners, cs)
-		}
-		// Select random AoA if still tied, else select 'winner'
-		if len(winners) > 0 {
-
-			// Create a random number generator with a seed based on current time
-			r := rand.New(rand.NewSource(time.Now().UnixNano()))
-			// Generate random index
-			randomI := r.Intn(len(winners))
-			preference := winners[randomI]
-
-			// Update the team's strategy
-			switch preference {
-			case 1:
-				team.TeamAoA = common.CreateTeam1AoA(team)
-			case 2:
-				team.TeamAoA = common.CreateTeam2AoA(5)
-			case 3:
-				team.TeamAoA = common.CreateFixedAoA(1)
-			case 4:
-				team.TeamAoA = common.CreateFixedAoA(1)
-			case 5:
-				team.TeamAoA = common.CreateTeam5AoA()
-				team.TeamAoAID = 5
-			case 6:
-				team.TeamAoA = common.CreateFixedAoA(1)
-			default:
-				team.TeamAoA = common.CreateFixedAoA(1)
-			}
-
-			cs.Teams[team.TeamID] = team
-			fmt.Printf("Team %v has AoA: %v\n", team.TeamID, winners[randomI])
-
-		}
+/*
+* Choose the rule teams vote on their AoA with. The default is Copeland, which
+* is what the game has always used.
+ */
+func (cs *EnvironmentServer) SetVotingRule(rule string) error {
+	if _, ok := voting.LookupRule(rule); !ok {
+		return fmt.Errorf("unknown voting rule %q (known rules: %s)", rule, strings.Join(voting.RuleNames(), ", "))
 	}
+	cs.votingRule = rule
+	return nil
 }
 
 func (cs *EnvironmentServer) RunEndOfIteration(int) {
 	// for _, agent := range cs.GetAgentMap() {
 	// 	cs.killAgentBelowThreshold(agent.GetID())
 	// }
+	cs.publish(IterationEndEvent{EventContext: cs.eventContext(), AgentCount: len(cs.GetAgentMap())})
 }
 
 // custom override (what why this is called later then start iteration...)
@@ -403,28 +445,40 @@ func (cs *EnvironmentServer) Start() {
 func (cs *EnvironmentServer) Init(turnsForThreshold int) {
 	cs.DataRecorder = gameRecorder.CreateRecorder()
 	cs.thresholdTurns = turnsForThreshold
+
+	// recording is just another subscriber to the game events
+	cs.attachDataRecorder()
+	cs.trackRoleContributions()
+
+	// stamp every log record with the time in the game
+	logging.SetClock(func() (int, int) { return cs.iteration, cs.turn })
 }
 
 func (cs *EnvironmentServer) reviveDeadAgents() {
 	for _, agent := range cs.deadAgents {
-		log.Printf("[server] Agent %v is being revived\n", agent.GetID())
-		agent.SetTrueScore(0) // new agents start with a score of 0
-		cs.AddAgent(agent)    // re-add the agent to the server map
+		cs.reviveAgent(agent)
 	}
 
 	// Clear the slice
 	cs.deadAgents = cs.deadAgents[:0]
 }
 
+func (cs *EnvironmentServer) reviveAgent(agent common.IExtendedAgent) {
+	serverLog.Debug("Reviving agent", "agent", agent.GetID())
+	agent.SetTrueScore(0) // new agents start with a score of 0
+	cs.AddAgent(agent)    // re-add the agent to the server map
+	cs.publish(RevivalEvent{EventContext: cs.eventContext(), AgentID: agent.GetID()})
+}
+
 // debug log printing
 func (cs *EnvironmentServer) LogAgentStatus() {
 	// log agent count, and their scores
-	log.Printf("Agent count: %v\n", len(cs.GetAgentMap()))
+	serverLog.Info("Agent status", "agentCount", len(cs.GetAgentMap()))
 	for _, agent := range cs.GetAgentMap() {
 		agent.LogSelfInfo()
 	}
 	for _, agent := range cs.deadAgents {
-		log.Printf("Agent %v is dead\n", agent.GetID())
+		serverLog.Info("Agent is dead", "agent", agent.GetID())
 	}
 }
 
@@ -436,32 +490,31 @@ func (cs *EnvironmentServer) PrintOrphanPool() {
 	for i, v := range cs.orphanPool {
 		// truncate the UUIDs to make it easier to read
 		shortAgentId := i.String()[:8]
-		shortTeamIds := make([]string, len(v))
+		shortTeamIds := make([]string, 0, len(v))
 
 		// go over all the teams in the wishlist and add to shortened IDs
 		for _, teamID := range v {
 			shortTeamIds = append(shortTeamIds, teamID.String()[:8])
 		}
 
-		log.Println(shortAgentId, " Wants to join : ", shortTeamIds)
+		orphanLog.Info("Orphan wants to join", "agent", shortAgentId, "teams", shortTeamIds)
 	}
 }
 
 // pretty logging to show all team status
 func (cs *EnvironmentServer) LogTeamStatus() {
-	log.Println("\n------------- [server] Team status -------------")
 	for _, team := range cs.Teams {
-		log.Printf("Team %v: %v\n", team.TeamID, team.Agents)
+		teamLog.Info("Team status", "team", team.TeamID, "agents", team.Agents)
 	}
 	// Log agents with no team
 	for _, agent := range cs.GetAgentMap() {
 		if agent.GetTeamID() == uuid.Nil {
-			log.Printf("Agent %v has no team\n", agent.GetID())
+			teamLog.Info("Agent has no team", "agent", agent.GetID())
 		}
 	}
 	// Log dead agents
 	for _, agent := range cs.deadAgents {
-		log.Printf("Agent %v is dead, last team: %v\n", agent.GetID(), agent.GetLastTeamID())
+		teamLog.Info("Agent is dead", "agent", agent.GetID(), "lastTeam", agent.GetLastTeamID())
 	}
 }
 
@@ -476,9 +529,12 @@ func (cs *EnvironmentServer) UpdateAndGetAgentExposedInfo() []common.ExposedAgen
 
 // create a new round score threshold
 func (cs *EnvironmentServer) createNewRoundScoreThreshold() {
-	// random one between 10 to 20 (TODO)
-	cs.roundScoreThreshold = rand.Intn(10) + 10
-	log.Printf("[server] New round score threshold: %v\n", cs.roundScoreThreshold)
+	// random one between 10 to 20 unless another policy was chosen
+	if cs.thresholdPolicy == nil {
+		cs.SetThresholdPolicy(DefaultThresholdPolicy, 0)
+	}
+	cs.roundScoreThreshold = cs.thresholdPolicy(cs.iteration, cs.thresholdValue)
+	serverLog.Info("New round score threshold", "threshold", cs.roundScoreThreshold)
 }
 
 // check agent score
@@ -494,6 +550,12 @@ func (cs *EnvironmentServer) killAgentBelowThreshold(agentID uuid.UUID) int {
 // kill agent
 func (cs *EnvironmentServer) killAgent(agentID uuid.UUID) {
 	agent := cs.GetAgentMap()[agentID]
+	deathEvent := DeathEvent{
+		EventContext: cs.eventContext(),
+		AgentID:      agentID,
+		TeamID:       agent.GetTeamID(),
+		Score:        agent.GetTrueScore(),
+	}
 
 	// Remove the agent from the team
 	if teamID := agent.GetTeamID(); teamID != uuid.Nil {
@@ -503,12 +565,13 @@ func (cs *EnvironmentServer) killAgent(agentID uuid.UUID) {
 		team := cs.Teams[teamID]
 		// check if team exists (patch fix - TODO check the root of the error)
 		if team == nil {
-			log.Printf("[server] Team %v does not exist\n", teamID)
+			teamLog.Warn("Team does not exist", "team", teamID)
 		} else {
 			for i, id := range team.Agents {
 				if id == agentID {
 					// Remove agent from the team
 					team.Agents = append(team.Agents[:i], team.Agents[i+1:]...)
+					team.TeamAoA.OnMemberDied(agentID)
 					cs.Teams[teamID] = team
 					// Set the team of the agent to Nil
 					agent.SetTeamID(uuid.Nil)
@@ -521,7 +584,8 @@ func (cs *EnvironmentServer) killAgent(agentID uuid.UUID) {
 	// Add the agent to the dead agent list and remove it from the server's agent map
 	cs.deadAgents = append(cs.deadAgents, agent)
 	cs.RemoveAgent(agent)
-	log.Printf("[server] Agent %v killed\n", agentID)
+	serverLog.Info("Agent killed", "agent", agentID, "score", deathEvent.Score)
+	cs.publish(deathEvent)
 }
 
 // is agent dead
@@ -545,7 +609,7 @@ func (cs *EnvironmentServer) StartAgentTeamForming() {
 	// Get updated agent info and let agents form teams
 	agentInfo := cs.UpdateAndGetAgentExposedInfo()
 
-	log.Printf("------------- [server] Starting team formation -------------\n\n")
+	teamLog.Debug("Starting team formation")
 
 	// Launch team formation for each agent
 	for _, agent := range cs.GetAgentMap() {
@@ -560,24 +624,34 @@ func (cs *EnvironmentServer) CreateTeam() {
 	cs.Teams = make(map[uuid.UUID]*common.Team)
 }
 
+// Called by agents joining an existing team
 func (cs *EnvironmentServer) AddAgentToTeam(agentID uuid.UUID, teamID uuid.UUID) {
+	if cs.addAgentToTeam(agentID, teamID) {
+		cs.publish(TeamJoinedEvent{EventContext: cs.eventContext(), AgentID: agentID, TeamID: teamID})
+	}
+}
+
+// Add the agent to the team's list of agents, returning whether it was added
+func (cs *EnvironmentServer) addAgentToTeam(agentID uuid.UUID, teamID uuid.UUID) bool {
 	cs.teamsMutex.Lock()
 	defer cs.teamsMutex.Unlock()
 
 	// Check if agent is already in this team
 	team, exists := cs.Teams[teamID]
 	if !exists {
-		log.Printf("[server] Team %v does not exist\n", teamID)
-		return
+		teamLog.Warn("Team does not exist", "team", teamID)
+		return false
 	}
 
 	for _, existingAgent := range team.Agents {
 		if existingAgent == agentID {
-			return // Skip if agent already exists
+			return false // Skip if agent already exists
 		}
 	}
 
 	team.Agents = append(team.Agents, agentID)
+	team.TeamAoA.OnMemberJoined(agentID)
+	return true
 }
 
 func (cs *EnvironmentServer) GetAgentsInTeam(teamID uuid.UUID) []uuid.UUID {
@@ -609,7 +683,7 @@ func (cs *EnvironmentServer) CreateAndInitTeamWithAgents(agentIDs []uuid.UUID) u
 	// check if any agent is already in a team
 	for _, agentID := range agentIDs {
 		if cs.CheckAgentAlreadyInTeam(agentID) {
-			log.Printf("[server] Agent %v is already in a team\n", agentID)
+			teamLog.Warn("Agent is already in a team", "agent", agentID)
 			return uuid.UUID{}
 		}
 	}
@@ -626,11 +700,12 @@ func (cs *EnvironmentServer) CreateAndInitTeamWithAgents(agentIDs []uuid.UUID) u
 	for _, agentID := range agentIDs {
 		if agent, exists := cs.GetAgentMap()[agentID]; exists {
 			agent.SetTeamID(teamID)
-			cs.AddAgentToTeam(agentID, teamID)
+			cs.addAgentToTeam(agentID, teamID)
 		}
 	}
 
-	log.Printf("[server] Created team %v with agents %v\n", teamID, agentIDs)
+	teamLog.Info("Created team", "team", teamID, "agents", agentIDs)
+	cs.publish(TeamFormedEvent{EventContext: cs.eventContext(), TeamID: teamID, Agents: agentIDs})
 	return teamID
 }
 
@@ -658,11 +733,21 @@ func (cs *EnvironmentServer) GetTeamIDs() []uuid.UUID {
 // Can be used to find the amount in the common pool for a team. If this is used,
 // it should be logged on the server (to prevent cheating)
 func (cs *EnvironmentServer) GetTeamCommonPool(teamID uuid.UUID) int {
-	log.Printf("Get Team Common Pool called! Team ID: %v\n", teamID)
+	teamLog.Debug("Common pool requested", "team", teamID)
 	team := cs.Teams[teamID]
 	return team.GetCommonPool()
 }
 
+// The iteration being played, for agents that keep track of time
+func (cs *EnvironmentServer) GetIterationNumber() int {
+	return cs.iteration
+}
+
+// The turn being played within the current iteration
+func (cs *EnvironmentServer) GetTurnNumber() int {
+	return cs.turn
+}
+
 // reset all agents (preserve memory but clears scores)
 func (cs *EnvironmentServer) ResetAgents() {
 	for _, agent := range cs.GetAgentMap() {
@@ -672,6 +757,7 @@ func (cs *EnvironmentServer) ResetAgents() {
 }
 
 func (cs *EnvironmentServer) ApplyThreshold() {
+	cs.publish(ThresholdEvent{EventContext: cs.eventContext(), RoundScoreThreshold: cs.roundScoreThreshold})
 	for _, team := range cs.Teams {
 		team.SetCommonPool(0)
 		for _, agentID := range team.Agents {
@@ -686,7 +772,12 @@ func (cs *EnvironmentServer) ApplyThreshold() {
 }
 
 func (cs *EnvironmentServer) RecordTurnInfo() {
+	agentRecords, teamRecords := cs.collectTurnRecords()
+	cs.DataRecorder.RecordNewTurn(agentRecords, teamRecords)
+}
 
+// Snapshot every agent (alive and dead) and every team at this point of the game
+func (cs *EnvironmentServer) collectTurnRecords() ([]gameRecorder.AgentRecord, []gameRecorder.TeamRecord) {
 	// agent information
 	agentRecords := []gameRecorder.AgentRecord{}
 	for _, agent := range cs.GetAgentMap() {
@@ -704,16 +795,65 @@ func (cs *EnvironmentServer) RecordTurnInfo() {
 	teamRecords := []gameRecorder.TeamRecord{}
 	for _, team := range cs.Teams {
 		newTeamRecord := gameRecorder.NewTeamRecord(team.TeamID)
+		newTeamRecord.TurnNumber = cs.turn
+		newTeamRecord.IterationNumber = cs.iteration
+		newTeamRecord.TeamCommonPool = team.GetCommonPool()
+		newTeamRecord.AoAID = team.TeamAoAID
+		newTeamRecord.AgentsAlive = append([]uuid.UUID{}, team.Agents...)
+		if ranker, ok := team.TeamAoA.(common.MemberRanker); ok {
+			newTeamRecord.MemberRanks = ranker.GetMemberRanks()
+		}
 		teamRecords = append(teamRecords, newTeamRecord)
 	}
 
-	cs.DataRecorder.RecordNewTurn(agentRecords, teamRecords)
+	return agentRecords, teamRecords
+}
+
+func (cs *EnvironmentServer) attachDataRecorder() {
+	AttachDataRecorder(cs.Events(), cs.DataRecorder)
+}
+
+// Subscribe a data recorder to the events it needs: a new iteration record at
+// the start of each iteration, and a snapshot of every agent and team at the
+// end of each turn. Works on a replayed event log as well as a live game.
+func AttachDataRecorder(bus *EventBus, recorder *gameRecorder.ServerDataRecorder) {
+	bus.Subscribe(EventIterationStart, func(Event) {
+		recorder.RecordNewIteration()
+	})
+	bus.Subscribe(EventTurnEnd, func(e Event) {
+		turnEnd := e.(TurnEndEvent)
+		recorder.RecordNewTurn(turnEnd.Agents, turnEnd.Teams)
+	})
+}
+
+func (cs *EnvironmentServer) publishWithdrawal(team *common.Team, agentID uuid.UUID, actual int, stated int) {
+	cs.publish(WithdrawalEvent{
+		EventContext:     cs.eventContext(),
+		TeamID:           team.TeamID,
+		AgentID:          agentID,
+		ActualWithdrawal: actual,
+		StatedWithdrawal: stated,
+		CommonPoolAfter:  team.GetCommonPool(),
+	})
+}
+
+func (cs *EnvironmentServer) publishAudit(team *common.Team, agentID uuid.UUID, phase string, cost int, infraction bool, result bool) {
+	cs.publish(AuditEvent{
+		EventContext:   cs.eventContext(),
+		TeamID:         team.TeamID,
+		AuditedAgentID: agentID,
+		Phase:          phase,
+		Cost:           cost,
+		Infraction:     infraction,
+		Result:         result,
+	})
 }
 
 func (cs *EnvironmentServer) Team5_RunTurn(team *common.Team) {
-	fmt.Println("\nRunning turn for team ", team.TeamID)
+	teamLog.Debug("Running turn", "team", team.TeamID)
 
 	// Sum of contributions from all agents in the team for this turn
+	cs.publishPhase(PhaseContribution, team.TeamID)
 	agentContributionsTotal := 0
 	for _, agentID := range team.Agents {
 		agent := cs.GetAgentMap()[agentID]
@@ -725,44 +865,61 @@ func (cs *EnvironmentServer) Team5_RunTurn(team *common.Team) {
 
 		// Agents make actual contribution
 		agentActualContribution := agent.GetActualContribution(agent)
+		agentStatedContribution := agent.GetStatedContribution(agent)
 
 		// Update audit result
 		team.TeamAoA.SetContributionAuditResult(agentID, agentScore, agentActualContribution, expectedContribution)
 		agent.SetTrueScore(agentScore - agentActualContribution)
 		agentContributionsTotal += agentActualContribution
+		cs.publish(ContributionEvent{
+			EventContext:       cs.eventContext(),
+			TeamID:             team.TeamID,
+			AgentID:            agentID,
+			ActualContribution: agentActualContribution,
+			StatedContribution: agentStatedContribution,
+		})
 	}
 
 	// Update common pool with total contribution from this team
 	team.SetCommonPool(team.GetCommonPool() + agentContributionsTotal)
 
 	// Initiate Contribution Audit vote
+	cs.publishPhase(PhaseContributionAudit, team.TeamID)
 	contributionAuditVotes := []common.Vote{}
 	for _, agentID := range team.Agents {
+		if cs.IsVoteBanned(agentID) {
+			continue
+		}
 		agent := cs.GetAgentMap()[agentID]
 		vote := agent.GetContributionAuditVote()
 		contributionAuditVotes = append(contributionAuditVotes, vote)
 	}
 
 	// Execute Contribution Audit if necessary
-	if agentToAudit := team.TeamAoA.GetVoteResult(contributionAuditVotes); agentToAudit != uuid.Nil {
+	if agentToAudit := cs.auditTarget(team, "contribution", contributionAuditVotes); agentToAudit != uuid.Nil {
 		auditCost := team.TeamAoA.GetAuditCost(team.GetCommonPool())
 		if auditCost <= team.GetCommonPool() {
 			// Deduct the audit cost from the common pool
 			team.SetCommonPool(team.GetCommonPool() - auditCost)
-			fmt.Printf("[server] Audit cost of %v deducted from the common pool. Remaining pool: %v\n", auditCost, team.GetCommonPool())
+			teamLog.Debug("Audit cost deducted", "team", team.TeamID, "cost", auditCost, "pool", team.GetCommonPool())
 
 			// Proceed with the audit
-			auditResult := team.TeamAoA.GetContributionAuditResult(agentToAudit)
+			infraction := team.TeamAoA.GetContributionAuditResult(agentToAudit)
+			auditResult := cs.auditVerdict(team, infraction, auditCost)
 			for _, agentID := range team.Agents {
 				agent := cs.GetAgentMap()[agentID]
 				agent.SetAgentContributionAuditResult(agentToAudit, auditResult)
 			}
+			cs.concludeAudit(team, agentToAudit, "contribution", contributionAuditVotes, auditCost, infraction, auditResult)
 		} else {
-			fmt.Printf("[server] Not enough resources in the common pool to cover the audit cost. Skipping audit.\n")
+			teamLog.Info("Not enough resources in the common pool to cover the audit cost, skipping audit", "team", team.TeamID, "cost", auditCost)
 		}
 	}
 
+	cs.runGovernance(team)
+
 	// Calculate withdrawal order and allow agents to withdraw
+	cs.publishPhase(PhaseWithdrawal, team.TeamID)
 	remainingResources := team.GetCommonPool()
 	orderedAgents := team.TeamAoA.GetWithdrawalOrder(team.Agents)
 	team.TeamAoA.ResourceAllocation(cs.GetAgentScores(), remainingResources)
@@ -771,6 +928,10 @@ func (cs *EnvironmentServer) Team5_RunTurn(team *common.Team) {
 		if agent.GetTeamID() == uuid.Nil || cs.IsAgentDead(agentID) {
 			continue
 		}
+		if cs.IsWithdrawalBanned(agentID) {
+			teamLog.Debug("Agent is banned from withdrawing", "team", team.TeamID, "agent", agentID)
+			continue
+		}
 
 		// Agents make actual withdrawal
 		agentActualWithdrawal := agent.GetActualWithdrawal(agent)
@@ -788,33 +949,40 @@ func (cs *EnvironmentServer) Team5_RunTurn(team *common.Team) {
 		// Update agent score and common pool
 		agent.SetTrueScore(agentScore + agentActualWithdrawal)
 		team.SetCommonPool(currentPool - agentActualWithdrawal)
-		fmt.Printf("[server] Agent %v withdrew %v. Remaining pool: %v\n", agentID, agentActualWithdrawal, team.GetCommonPool())
+		teamLog.Debug("Agent withdrew", "team", team.TeamID, "agent", agentID, "amount", agentActualWithdrawal, "pool", team.GetCommonPool())
+		cs.publishWithdrawal(team, agentID, agentActualWithdrawal, agentStatedWithdrawal)
 	}
 
 	// Initiate Withdrawal Audit vote
+	cs.publishPhase(PhaseWithdrawalAudit, team.TeamID)
 	withdrawalAuditVotes := []common.Vote{}
 	for _, agentID := range team.Agents {
+		if cs.IsVoteBanned(agentID) {
+			continue
+		}
 		agent := cs.GetAgentMap()[agentID]
 		vote := agent.GetWithdrawalAuditVote()
 		withdrawalAuditVotes = append(withdrawalAuditVotes, vote)
 	}
 
 	// Execute Withdrawal Audit if necessary
-	if agentToAudit := team.TeamAoA.GetVoteResult(withdrawalAuditVotes); agentToAudit != uuid.Nil {
+	if agentToAudit := cs.auditTarget(team, "withdrawal", withdrawalAuditVotes); agentToAudit != uuid.Nil {
 		auditCost := team.TeamAoA.GetAuditCost(team.GetCommonPool())
 		if auditCost <= team.GetCommonPool() {
 			// Deduct the audit cost from the common pool
 			team.SetCommonPool(team.GetCommonPool() - auditCost)
-			fmt.Printf("[server] Withdrawal audit cost of %v deducted from the common pool. Remaining pool: %v\n", auditCost, team.GetCommonPool())
+			teamLog.Debug("Withdrawal audit cost deducted", "team", team.TeamID, "cost", auditCost, "pool", team.GetCommonPool())
 
 			// Proceed with the audit
-			auditResult := team.TeamAoA.GetWithdrawalAuditResult(agentToAudit)
+			infraction := team.TeamAoA.GetWithdrawalAuditResult(agentToAudit)
+			auditResult := cs.auditVerdict(team, infraction, auditCost)
 			for _, agentID := range team.Agents {
 				agent := cs.GetAgentMap()[agentID]
 				agent.SetAgentWithdrawalAuditResult(agentToAudit, auditResult)
 			}
+			cs.concludeAudit(team, agentToAudit, "withdrawal", withdrawalAuditVotes, auditCost, infraction, auditResult)
 		} else {
-			fmt.Printf("[server] Not enough resources in the common pool to cover the audit cost. Skipping withdrawal audit.\n")
+			teamLog.Info("Not enough resources in the common pool to cover the audit cost, skipping withdrawal audit", "team", team.TeamID, "cost", auditCost)
 		}
 	}
 }
@@ -0,0 +1,503 @@
+package environmentServer
+
+import (
+	"sync"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	gameRecorder "github.com/ADimoska/SOMASExtended/gameRecorder"
+	"github.com/ADimoska/SOMASExtended/voting"
+	"github.com/google/uuid"
+)
+
+/*
+* The event bus lets code outside the server react to what happens during the
+* game without editing the server itself. The server publishes an event at
+* every notable point of a turn (contributions, audits, deaths, ...) and every
+* subscriber registered for that event type is called synchronously, in the
+* order in which it subscribed.
+*
+* Handlers run on the server's goroutine, in the middle of the turn, which is
+* what lets the Debugger pause the game at the exact point of an event. The
+* server never holds its team lock while publishing, so handlers may call back
+* into it, e.g. CheckAgentAlreadyInTeam or CreateAndInitTeamWithAgents; a team
+* created during a turn takes part from the next one.
+ */
+
+type EventType string
+
+const (
+	EventIterationStart  EventType = "IterationStart"
+	EventIterationEnd    EventType = "IterationEnd"
+	EventTurnStart       EventType = "TurnStart"
+	EventTurnEnd         EventType = "TurnEnd"
+	EventPhase           EventType = "Phase"
+	EventTeamFormed      EventType = "TeamFormed"
+	EventAoASelected     EventType = "AoASelected"
+	EventScoreReport     EventType = "ScoreReport"
+	EventContribution    EventType = "Contribution"
+	EventWithdrawal      EventType = "Withdrawal"
+	EventAudit           EventType = "Audit"
+	EventThreshold       EventType = "Threshold"
+	EventDeath           EventType = "Death"
+	EventRevival         EventType = "Revival"
+	EventOrphanAllocated EventType = "OrphanAllocated"
+	EventTeamJoined      EventType = "TeamJoined"
+	EventOffspring       EventType = "Offspring"
+	EventAmendment       EventType = "Amendment"
+	EventSanction        EventType = "Sanction"
+	EventBallot          EventType = "Ballot"
+	EventElection        EventType = "Election"
+	EventRankChange      EventType = "RankChange"
+	EventAppeal          EventType = "Appeal"
+	EventAccusation      EventType = "Accusation"
+)
+
+// Event is implemented by every event published on the bus
+type Event interface {
+	Type() EventType
+	When() EventContext
+}
+
+// EventContext records the point in the game at which an event happened
+type EventContext struct {
+	Iteration int
+	Turn      int
+}
+
+func (ec EventContext) When() EventContext {
+	return ec
+}
+
+type IterationStartEvent struct {
+	EventContext
+	RoundScoreThreshold int
+	AgentCount          int
+}
+
+type IterationEndEvent struct {
+	EventContext
+	AgentCount int
+}
+
+type TurnStartEvent struct {
+	EventContext
+	AgentCount int
+}
+
+// TurnEndEvent carries a snapshot of every agent and team, which is what the
+// data recorder stores for each turn
+type TurnEndEvent struct {
+	EventContext
+	AgentCount int
+	Agents     []gameRecorder.AgentRecord
+	Teams      []gameRecorder.TeamRecord
+}
+
+// Phases of the game announced through PhaseEvent
+const (
+	PhaseElection          = "election"
+	PhaseTeamFormation     = "teamFormation"
+	PhaseAoAVote           = "aoaVote"
+	PhaseOrphanAllocation  = "orphanAllocation"
+	PhaseScoreReport       = "scoreReport"
+	PhaseContribution      = "contribution"
+	PhaseContributionAudit = "contributionAudit"
+	PhaseGovernance        = "governance"
+	PhaseWithdrawal        = "withdrawal"
+	PhaseWithdrawalAudit   = "withdrawalAudit"
+	PhaseAmendment         = "amendment"
+	PhaseThreshold         = "threshold"
+)
+
+// PhaseEvent is published when a phase starts. TeamID is nil for phases that
+// are not run per team.
+type PhaseEvent struct {
+	EventContext
+	Phase  string
+	TeamID uuid.UUID
+}
+
+type TeamFormedEvent struct {
+	EventContext
+	TeamID uuid.UUID
+	Agents []uuid.UUID
+}
+
+// The outcome of a team's AoA vote, with the full tally
+type AoASelectedEvent struct {
+	EventContext
+	TeamID        uuid.UUID
+	AoAID         int // the AoA the team runs
+	VotedAoAID    int // the AoA that won the vote, which may not exist
+	Rule          string
+	Ballots       []voting.Ballot
+	Scores        map[int]float64  // the rule's score for each AoA
+	WinnersByRule map[string][]int // who would have won under every other rule
+}
+
+// An agent declared its turn score to its team (see common.ScoreReport)
+type ScoreReportEvent struct {
+	EventContext
+	TeamID   uuid.UUID
+	AgentID  uuid.UUID
+	Declared int
+	Rolled   int
+}
+
+type ContributionEvent struct {
+	EventContext
+	TeamID             uuid.UUID
+	AgentID            uuid.UUID
+	ActualContribution int
+	StatedContribution int
+}
+
+type WithdrawalEvent struct {
+	EventContext
+	TeamID           uuid.UUID
+	AgentID          uuid.UUID
+	ActualWithdrawal int
+	StatedWithdrawal int
+	CommonPoolAfter  int
+}
+
+type AuditEvent struct {
+	EventContext
+	TeamID         uuid.UUID
+	AuditedAgentID uuid.UUID
+	Phase          string // "contribution" or "withdrawal"
+	Cost           int
+	Infraction     bool // whether the AoA's records show the agent cheated
+	Result         bool // true means the agent failed the audit (cheated)
+}
+
+type ThresholdEvent struct {
+	EventContext
+	RoundScoreThreshold int
+}
+
+type DeathEvent struct {
+	EventContext
+	AgentID uuid.UUID
+	TeamID  uuid.UUID
+	Score   int
+}
+
+type RevivalEvent struct {
+	EventContext
+	AgentID uuid.UUID
+}
+
+type OrphanAllocatedEvent struct {
+	EventContext
+	AgentID uuid.UUID
+	TeamID  uuid.UUID
+}
+
+// An agent joined a team that already existed, outside of orphan allocation
+type TeamJoinedEvent struct {
+	EventContext
+	AgentID uuid.UUID
+	TeamID  uuid.UUID
+}
+
+// In evolutionary mode, a dead agent was replaced by the offspring of a living one
+type OffspringEvent struct {
+	EventContext
+	AgentID         uuid.UUID
+	ReplacedAgentID uuid.UUID
+	ParentID        uuid.UUID
+	Strategy        string
+	Params          common.StrategyParams
+}
+
+// A team voted on amending its AoA; if it passed, the team now runs ToAoAID
+type AmendmentEvent struct {
+	EventContext
+	TeamID           uuid.UUID
+	ProposerID       uuid.UUID
+	FromAoAID        int
+	ToAoAID          int
+	Params           common.AoAParams
+	Votes            int
+	VotesFor         int
+	Majority         float64 // share of votes needed, set by the AoA amended
+	Passed           bool
+	AuditHistoryKept bool
+	OffencesKept     bool // members' offence counts carried over to the new AoA
+	CommonPool       int  // carried over to the new AoA
+}
+
+// An agent that failed an audit was sanctioned by its team's AoA
+type SanctionEvent struct {
+	EventContext
+	TeamID        uuid.UUID
+	AgentID       uuid.UUID
+	Phase         string // the audit the agent failed
+	Offences      int    // offences on record, including this one; 0 if the AoA does not count them
+	Warning       bool
+	Fine          int // what the agent actually paid, at most its score
+	WithdrawalBan int // withdrawal phases the agent sits out
+	VoteBan       int // turns after this one the agent's votes do not count
+	Expelled      bool
+}
+
+// An agent appealed the verdict of an audit (see common.AppealTerms)
+type AppealEvent struct {
+	EventContext
+	TeamID     uuid.UUID
+	AgentID    uuid.UUID
+	Phase      string // the audit appealed against
+	Method     string // "audit" or "jury"
+	Fee        int    // paid by the agent to be heard
+	Cost       int    // of the second audit, paid by the common pool
+	Overturned bool
+	Refund     int  // fee and fine paid back to the agent
+	Readmitted bool // an expelled agent rejoined the team
+}
+
+// How an accusation was settled
+const (
+	AccusationUpheld    = "upheld"    // the accused was found to have cheated
+	AccusationFalse     = "false"     // the accused was found honest
+	AccusationUnaudited = "unaudited" // the accused was not audited that turn
+)
+
+// An accusation was settled (see common.Accusation)
+type AccusationEvent struct {
+	EventContext
+	TeamID    uuid.UUID
+	AccuserID uuid.UUID
+	AccusedID uuid.UUID
+	Phase     string
+	Evidence  *common.AccusationEvidence
+	Outcome   string
+	Reward    int // paid to the accuser out of the common pool
+	Penalty   int // paid by the accuser into the common pool
+}
+
+// A team ballot closed (see common.Ballot); Votes is nil for secret ballots
+type BallotEvent struct {
+	EventContext
+	TeamID   uuid.UUID
+	BallotID uuid.UUID
+	Question string
+	Options  []string
+	Rule     string
+	Secret   bool
+	Votes    map[uuid.UUID][]int
+	Eligible int
+	Turnout  int
+	Scores   map[int]float64
+	Winner   int // index of the option chosen, -1 if none was
+}
+
+// A team elected the holders of one of its roles (see common.RoleHolder)
+type ElectionEvent struct {
+	EventContext
+	TeamID   uuid.UUID
+	Role     string
+	Method   string
+	Reason   string // "first election", "end of term", "vacancy" or "impeachment"
+	Previous []uuid.UUID
+	Holders  []uuid.UUID
+}
+
+// The AoA's governance changed the rank of a member (see common.MemberRanker)
+type RankChangeEvent struct {
+	EventContext
+	TeamID  uuid.UUID
+	AgentID uuid.UUID
+	From    int // 0 if the agent had no rank
+	To      int // 0 if the agent no longer has a rank
+}
+
+func (IterationStartEvent) Type() EventType  { return EventIterationStart }
+func (IterationEndEvent) Type() EventType    { return EventIterationEnd }
+func (TurnStartEvent) Type() EventType       { return EventTurnStart }
+func (TurnEndEvent) Type() EventType         { return EventTurnEnd }
+func (PhaseEvent) Type() EventType           { return EventPhase }
+func (TeamFormedEvent) Type() EventType      { return EventTeamFormed }
+func (AoASelectedEvent) Type() EventType     { return EventAoASelected }
+func (ScoreReportEvent) Type() EventType     { return EventScoreReport }
+func (ContributionEvent) Type() EventType    { return EventContribution }
+func (WithdrawalEvent) Type() EventType      { return EventWithdrawal }
+func (AuditEvent) Type() EventType           { return EventAudit }
+func (ThresholdEvent) Type() EventType       { return EventThreshold }
+func (DeathEvent) Type() EventType           { return EventDeath }
+func (RevivalEvent) Type() EventType         { return EventRevival }
+func (OrphanAllocatedEvent) Type() EventType { return EventOrphanAllocated }
+func (TeamJoinedEvent) Type() EventType      { return EventTeamJoined }
+func (OffspringEvent) Type() EventType       { return EventOffspring }
+func (AmendmentEvent) Type() EventType       { return EventAmendment }
+func (SanctionEvent) Type() EventType        { return EventSanction }
+func (BallotEvent) Type() EventType          { return EventBallot }
+func (ElectionEvent) Type() EventType        { return EventElection }
+func (RankChangeEvent) Type() EventType      { return EventRankChange }
+func (AppealEvent) Type() EventType          { return EventAppeal }
+func (AccusationEvent) Type() EventType      { return EventAccusation }
+
+type EventBus struct {
+	mu          sync.RWMutex
+	handlers    map[EventType][]func(Event)
+	allHandlers []func(Event)
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		handlers: make(map[EventType][]func(Event)),
+	}
+}
+
+// Subscribe registers a handler that is called for every event of the given
+// type
+func (eb *EventBus) Subscribe(eventType EventType, handler func(Event)) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	eb.handlers[eventType] = append(eb.handlers[eventType], handler)
+}
+
+// SubscribeAll registers a handler that is called for every event, whatever its type
+func (eb *EventBus) SubscribeAll(handler func(Event)) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	eb.allHandlers = append(eb.allHandlers, handler)
+}
+
+// Publish calls all the handlers subscribed to the event's type, followed by
+// the handlers subscribed to every event
+func (eb *EventBus) Publish(event Event) {
+	eb.mu.RLock()
+	handlers := append([]func(Event){}, eb.handlers[event.Type()]...)
+	handlers = append(handlers, eb.allHandlers...)
+	eb.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// --------- Server subscription API ---------
+
+// Get the server's event bus, creating it if the server was not initialised
+// through Init (e.g. in tests)
+func (cs *EnvironmentServer) Events() *EventBus {
+	if cs.eventBus == nil {
+		cs.eventBus = NewEventBus()
+	}
+	return cs.eventBus
+}
+
+func (cs *EnvironmentServer) publish(event Event) {
+	cs.Events().Publish(event)
+}
+
+// Context of the event currently being published
+func (cs *EnvironmentServer) eventContext() EventContext {
+	return EventContext{Iteration: cs.iteration, Turn: cs.turn}
+}
+
+func (cs *EnvironmentServer) publishPhase(phase string, teamID uuid.UUID) {
+	cs.publish(PhaseEvent{EventContext: cs.eventContext(), Phase: phase, TeamID: teamID})
+}
+
+func (cs *EnvironmentServer) OnIterationStart(handler func(IterationStartEvent)) {
+	cs.Events().Subscribe(EventIterationStart, func(e Event) { handler(e.(IterationStartEvent)) })
+}
+
+func (cs *EnvironmentServer) OnIterationEnd(handler func(IterationEndEvent)) {
+	cs.Events().Subscribe(EventIterationEnd, func(e Event) { handler(e.(IterationEndEvent)) })
+}
+
+func (cs *EnvironmentServer) OnTurnStart(handler func(TurnStartEvent)) {
+	cs.Events().Subscribe(EventTurnStart, func(e Event) { handler(e.(TurnStartEvent)) })
+}
+
+func (cs *EnvironmentServer) OnTurnEnd(handler func(TurnEndEvent)) {
+	cs.Events().Subscribe(EventTurnEnd, func(e Event) { handler(e.(TurnEndEvent)) })
+}
+
+func (cs *EnvironmentServer) OnPhase(handler func(PhaseEvent)) {
+	cs.Events().Subscribe(EventPhase, func(e Event) { handler(e.(PhaseEvent)) })
+}
+
+func (cs *EnvironmentServer) OnTeamFormed(handler func(TeamFormedEvent)) {
+	cs.Events().Subscribe(EventTeamFormed, func(e Event) { handler(e.(TeamFormedEvent)) })
+}
+
+func (cs *EnvironmentServer) OnAoASelected(handler func(AoASelectedEvent)) {
+	cs.Events().Subscribe(EventAoASelected, func(e Event) { handler(e.(AoASelectedEvent)) })
+}
+
+func (cs *EnvironmentServer) OnScoreReport(handler func(ScoreReportEvent)) {
+	cs.Events().Subscribe(EventScoreReport, func(e Event) { handler(e.(ScoreReportEvent)) })
+}
+
+func (cs *EnvironmentServer) OnContribution(handler func(ContributionEvent)) {
+	cs.Events().Subscribe(EventContribution, func(e Event) { handler(e.(ContributionEvent)) })
+}
+
+func (cs *EnvironmentServer) OnWithdrawal(handler func(WithdrawalEvent)) {
+	cs.Events().Subscribe(EventWithdrawal, func(e Event) { handler(e.(WithdrawalEvent)) })
+}
+
+func (cs *EnvironmentServer) OnAudit(handler func(AuditEvent)) {
+	cs.Events().Subscribe(EventAudit, func(e Event) { handler(e.(AuditEvent)) })
+}
+
+func (cs *EnvironmentServer) OnThreshold(handler func(ThresholdEvent)) {
+	cs.Events().Subscribe(EventThreshold, func(e Event) { handler(e.(ThresholdEvent)) })
+}
+
+func (cs *EnvironmentServer) OnDeath(handler func(DeathEvent)) {
+	cs.Events().Subscribe(EventDeath, func(e Event) { handler(e.(DeathEvent)) })
+}
+
+func (cs *EnvironmentServer) OnRevival(handler func(RevivalEvent)) {
+	cs.Events().Subscribe(EventRevival, func(e Event) { handler(e.(RevivalEvent)) })
+}
+
+func (cs *EnvironmentServer) OnAmendment(handler func(AmendmentEvent)) {
+	cs.Events().Subscribe(EventAmendment, func(e Event) { handler(e.(AmendmentEvent)) })
+}
+
+func (cs *EnvironmentServer) OnSanction(handler func(SanctionEvent)) {
+	cs.Events().Subscribe(EventSanction, func(e Event) { handler(e.(SanctionEvent)) })
+}
+
+func (cs *EnvironmentServer) OnAppeal(handler func(AppealEvent)) {
+	cs.Events().Subscribe(EventAppeal, func(e Event) { handler(e.(AppealEvent)) })
+}
+
+func (cs *EnvironmentServer) OnAccusation(handler func(AccusationEvent)) {
+	cs.Events().Subscribe(EventAccusation, func(e Event) { handler(e.(AccusationEvent)) })
+}
+
+func (cs *EnvironmentServer) OnBallot(handler func(BallotEvent)) {
+	cs.Events().Subscribe(EventBallot, func(e Event) { handler(e.(BallotEvent)) })
+}
+
+func (cs *EnvironmentServer) OnElection(handler func(ElectionEvent)) {
+	cs.Events().Subscribe(EventElection, func(e Event) { handler(e.(ElectionEvent)) })
+}
+
+func (cs *EnvironmentServer) OnRankChange(handler func(RankChangeEvent)) {
+	cs.Events().Subscribe(EventRankChange, func(e Event) { handler(e.(RankChangeEvent)) })
+}
+
+func (cs *EnvironmentServer) OnOffspring(handler func(OffspringEvent)) {
+	cs.Events().Subscribe(EventOffspring, func(e Event) { handler(e.(OffspringEvent)) })
+}
+
+func (cs *EnvironmentServer) OnOrphanAllocated(handler func(OrphanAllocatedEvent)) {
+	cs.Events().Subscribe(EventOrphanAllocated, func(e Event) { handler(e.(OrphanAllocatedEvent)) })
+}
+
+func (cs *EnvironmentServer) OnTeamJoined(handler func(TeamJoinedEvent)) {
+	cs.Events().Subscribe(EventTeamJoined, func(e Event) { handler(e.(TeamJoinedEvent)) })
+}
+
+// OnAnyEvent subscribes a handler to every event published by the server
+func (cs *EnvironmentServer) OnAnyEvent(handler func(Event)) {
+	cs.Events().SubscribeAll(handler)
+}
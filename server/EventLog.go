@@ -0,0 +1,118 @@
+package environmentServer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+/*
+* The event log stores every published event as one JSON object per line, so a
+* game can be replayed later without running the agents again. Each line holds
+* the event type and the event itself:
+*
+*	{"type":"Contribution","event":{"Iteration":0,"Turn":1,...}}
+ */
+
+type loggedEvent struct {
+	Type  EventType       `json:"type"`
+	Event json.RawMessage `json:"event"`
+}
+
+// NewEventLogWriter returns an event handler that appends each event to w.
+// Subscribe it to every event with OnAnyEvent / SubscribeAll.
+func NewEventLogWriter(w io.Writer) func(Event) {
+	encoder := json.NewEncoder(w)
+	return func(event Event) {
+		raw, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		encoder.Encode(loggedEvent{Type: event.Type(), Event: raw})
+	}
+}
+
+// ReadEventLog decodes an event log written by NewEventLogWriter
+func ReadEventLog(r io.Reader) ([]Event, error) {
+	events := []Event{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var logged loggedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &logged); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		event, err := decodeEvent(logged.Type, logged.Event)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+func decodeEvent(eventType EventType, raw json.RawMessage) (Event, error) {
+	switch eventType {
+	case EventIterationStart:
+		return decodeAs[IterationStartEvent](raw)
+	case EventIterationEnd:
+		return decodeAs[IterationEndEvent](raw)
+	case EventTurnStart:
+		return decodeAs[TurnStartEvent](raw)
+	case EventTurnEnd:
+		return decodeAs[TurnEndEvent](raw)
+	case EventPhase:
+		return decodeAs[PhaseEvent](raw)
+	case EventTeamFormed:
+		return decodeAs[TeamFormedEvent](raw)
+	case EventAoASelected:
+		return decodeAs[AoASelectedEvent](raw)
+	case EventScoreReport:
+		return decodeAs[ScoreReportEvent](raw)
+	case EventContribution:
+		return decodeAs[ContributionEvent](raw)
+	case EventWithdrawal:
+		return decodeAs[WithdrawalEvent](raw)
+	case EventAudit:
+		return decodeAs[AuditEvent](raw)
+	case EventThreshold:
+		return decodeAs[ThresholdEvent](raw)
+	case EventDeath:
+		return decodeAs[DeathEvent](raw)
+	case EventRevival:
+		return decodeAs[RevivalEvent](raw)
+	case EventOrphanAllocated:
+		return decodeAs[OrphanAllocatedEvent](raw)
+	case EventTeamJoined:
+		return decodeAs[TeamJoinedEvent](raw)
+	case EventAmendment:
+		return decodeAs[AmendmentEvent](raw)
+	case EventSanction:
+		return decodeAs[SanctionEvent](raw)
+	case EventAppeal:
+		return decodeAs[AppealEvent](raw)
+	case EventAccusation:
+		return decodeAs[AccusationEvent](raw)
+	case EventBallot:
+		return decodeAs[BallotEvent](raw)
+	case EventElection:
+		return decodeAs[ElectionEvent](raw)
+	case EventRankChange:
+		return decodeAs[RankChangeEvent](raw)
+	case EventOffspring:
+		return decodeAs[OffspringEvent](raw)
+	}
+	return nil, fmt.Errorf("unknown event type %q", eventType)
+}
+
+func decodeAs[T Event](raw json.RawMessage) (Event, error) {
+	var event T
+	err := json.Unmarshal(raw, &event)
+	return event, err
+}
@@ -0,0 +1,210 @@
+package environmentServer
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+)
+
+/*
+* Evolutionary mode. Instead of reviving the dead agents at the start of an
+* iteration, each dead agent is replaced by a new agent playing the strategy
+* of a successful survivor (its parent), possibly with mutated parameters.
+* Fitness is the score an agent ended the last iteration with. The selection
+* policy decides how parents are picked:
+*
+*   fitness     parents are drawn with probability proportional to fitness
+*   tournament  the fittest of a few random survivors becomes the parent
+*   imitation   the dead agent copies a random survivor that did better than
+*               it did; it is revived as it was if there is none
+*
+* The server cannot build agents itself, so it is given a spawner that builds
+* one from a strategy name and its parameters.
+ */
+
+type EvolutionSelection func(cs *EnvironmentServer, dead common.IExtendedAgent, survivors []common.IExtendedAgent) common.IExtendedAgent
+
+type EvolutionPolicyInfo struct {
+	Name        string
+	Description string
+	Selection   EvolutionSelection // nil keeps the dead agents and revives them
+}
+
+// Builds a new agent of the named strategy
+type AgentSpawner func(strategy string, params common.StrategyParams) (common.IExtendedAgent, error)
+
+type EvolutionConfig struct {
+	Policy         string
+	MutationRate   float64 // chance of mutating each parameter of an offspring
+	MutationScale  float64 // standard deviation of a mutation, relative to the parameter's value
+	TournamentSize int     // survivors that compete to become a parent in "tournament" selection
+}
+
+const DefaultEvolutionPolicy = "none"
+
+type evolution struct {
+	config    EvolutionConfig
+	selection EvolutionSelection
+	spawn     AgentSpawner
+}
+
+var evolutionPolicies = map[string]EvolutionPolicyInfo{
+	"none": {
+		Name:        "none",
+		Description: "dead agents are revived with a score of 0 (the population never changes)",
+	},
+	"fitness": {
+		Name:        "fitness",
+		Description: "dead agents are replaced by offspring of survivors drawn in proportion to their score",
+		Selection:   fitnessProportionalSelection,
+	},
+	"tournament": {
+		Name:        "tournament",
+		Description: "dead agents are replaced by offspring of the best of tournamentSize random survivors",
+		Selection:   tournamentSelection,
+	},
+	"imitation": {
+		Name:        "imitation",
+		Description: "dead agents copy the strategy of a random survivor that scored more than they did",
+		Selection:   imitationSelection,
+	},
+}
+
+// List the available evolution policies, sorted by name
+func EvolutionPolicies() []EvolutionPolicyInfo {
+	policies := make([]EvolutionPolicyInfo, 0, len(evolutionPolicies))
+	for _, info := range evolutionPolicies {
+		policies = append(policies, info)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	return policies
+}
+
+/*
+* Choose what happens to dead agents at the start of each iteration. The
+* spawner is only needed by the policies that replace agents.
+ */
+func (cs *EnvironmentServer) SetEvolution(config EvolutionConfig, spawn AgentSpawner) error {
+	info, ok := evolutionPolicies[config.Policy]
+	if !ok {
+		return fmt.Errorf("unknown evolution policy %q", config.Policy)
+	}
+	if info.Selection == nil {
+		cs.evolution = nil
+		return nil
+	}
+	if spawn == nil {
+		return fmt.Errorf("evolution policy %q needs an agent spawner", config.Policy)
+	}
+	if config.TournamentSize <= 0 {
+		config.TournamentSize = 2
+	}
+	cs.evolution = &evolution{config: config, selection: info.Selection, spawn: spawn}
+	return nil
+}
+
+func (cs *EnvironmentServer) replaceDeadAgents() {
+	survivors := make([]common.IExtendedAgent, 0, len(cs.GetAgentMap()))
+	for _, agent := range cs.GetAgentMap() {
+		survivors = append(survivors, agent)
+	}
+	// map order is random, sort so a seeded game picks the same parents
+	sort.Slice(survivors, func(i, j int) bool { return survivors[i].GetID().String() < survivors[j].GetID().String() })
+
+	for _, dead := range cs.deadAgents {
+		parent := cs.evolution.selection(cs, dead, survivors)
+		if parent == nil {
+			cs.reviveAgent(dead)
+			continue
+		}
+
+		params := cs.evolution.mutate(parent.GetStrategyParams())
+		child, err := cs.evolution.spawn(parent.GetStrategyName(), params)
+		if err != nil {
+			serverLog.Error("Could not spawn offspring, reviving the dead agent instead", "strategy", parent.GetStrategyName(), "err", err)
+			cs.reviveAgent(dead)
+			continue
+		}
+		child.SetTrueScore(0)
+		cs.AddAgent(child)
+		serverLog.Debug("Dead agent replaced by offspring", "dead", dead.GetID(), "agent", child.GetID(),
+			"parent", parent.GetID(), "strategy", child.GetStrategyName(), "params", child.GetStrategyParams())
+		cs.publish(OffspringEvent{
+			EventContext:    cs.eventContext(),
+			AgentID:         child.GetID(),
+			ReplacedAgentID: dead.GetID(),
+			ParentID:        parent.GetID(),
+			Strategy:        child.GetStrategyName(),
+			Params:          child.GetStrategyParams(),
+		})
+	}
+
+	cs.deadAgents = cs.deadAgents[:0]
+}
+
+// Copy the parent's parameters, nudging each one with probability MutationRate
+func (e *evolution) mutate(params common.StrategyParams) common.StrategyParams {
+	mutated := params.Copy()
+	keys := make([]string, 0, len(mutated))
+	for key := range mutated {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // draw the mutations in the same order every run
+	for _, key := range keys {
+		value := mutated[key]
+		if rand.Float64() >= e.config.MutationRate {
+			continue
+		}
+		noise := rand.NormFloat64() * e.config.MutationScale * math.Max(math.Abs(value), 1)
+		mutated[key] = math.Max(0, value+noise) // every strategy parameter is a rate, share or amount
+	}
+	return mutated
+}
+
+// ----------------------- Selection policies -----------------------
+
+func fitnessProportionalSelection(cs *EnvironmentServer, dead common.IExtendedAgent, survivors []common.IExtendedAgent) common.IExtendedAgent {
+	if len(survivors) == 0 {
+		return nil
+	}
+	// +1 so that survivors on a score of 0 can still be picked
+	total := 0
+	for _, agent := range survivors {
+		total += max(agent.GetTrueScore(), 0) + 1
+	}
+	pick := rand.Intn(total)
+	for _, agent := range survivors {
+		pick -= max(agent.GetTrueScore(), 0) + 1
+		if pick < 0 {
+			return agent
+		}
+	}
+	return survivors[len(survivors)-1]
+}
+
+func tournamentSelection(cs *EnvironmentServer, dead common.IExtendedAgent, survivors []common.IExtendedAgent) common.IExtendedAgent {
+	var best common.IExtendedAgent
+	for i := 0; i < cs.evolution.config.TournamentSize && len(survivors) > 0; i++ {
+		contender := survivors[rand.Intn(len(survivors))]
+		if best == nil || contender.GetTrueScore() > best.GetTrueScore() {
+			best = contender
+		}
+	}
+	return best
+}
+
+func imitationSelection(cs *EnvironmentServer, dead common.IExtendedAgent, survivors []common.IExtendedAgent) common.IExtendedAgent {
+	better := []common.IExtendedAgent{}
+	for _, agent := range survivors {
+		if agent.GetTrueScore() > dead.GetTrueScore() {
+			better = append(better, agent)
+		}
+	}
+	if len(better) == 0 {
+		return nil
+	}
+	return better[rand.Intn(len(better))]
+}
@@ -0,0 +1,41 @@
+package environmentServer
+
+import (
+	"github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+)
+
+/*
+* The AoA's own governance, run after the contribution audit of every turn
+* (see IArticlesOfAssociation.RunPostContributionAoaLogic). Changes to the
+* ranks of AoAs that rank their members are published as RankChangeEvents.
+ */
+func (cs *EnvironmentServer) runGovernance(team *common.Team) {
+	cs.publishPhase(PhaseGovernance, team.TeamID)
+	ranker, ranked := team.TeamAoA.(common.MemberRanker)
+	before := map[uuid.UUID]int{}
+	if ranked {
+		before = ranker.GetMemberRanks()
+	}
+
+	team.TeamAoA.RunPostContributionAoaLogic(team, cs.GetAgentMap())
+
+	if !ranked {
+		return
+	}
+	after := ranker.GetMemberRanks()
+	for _, agentID := range team.Agents {
+		if before[agentID] != after[agentID] {
+			cs.publishRankChange(team.TeamID, agentID, before[agentID], after[agentID])
+		}
+	}
+	for agentID, from := range before {
+		if _, ok := after[agentID]; !ok && !containsID(team.Agents, agentID) {
+			cs.publishRankChange(team.TeamID, agentID, from, 0)
+		}
+	}
+}
+
+func (cs *EnvironmentServer) publishRankChange(teamID uuid.UUID, agentID uuid.UUID, from int, to int) {
+	cs.publish(RankChangeEvent{EventContext: cs.eventContext(), TeamID: teamID, AgentID: agentID, From: from, To: to})
+}
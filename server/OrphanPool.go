@@ -1,8 +1,8 @@
 package environmentServer
 
 import (
-	"log"
-
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/ADimoska/SOMASExtended/logging"
 	"github.com/google/uuid"
 )
 
@@ -25,29 +25,29 @@ const MajorityVoteThreshold float32 = 0.7
 * There is no logic in this function to check for the case where the agent is
 * already in the team, this is not the responsibility of this function. It
 * should not happen if the orphan pool is correctly managed.
+*
+* The vote is held as a threshold ballot of the whole team (see holdBallot).
  */
 func (cs *EnvironmentServer) RequestOrphanEntry(orphanID, teamID uuid.UUID, entryThreshold float32) bool {
-	// Get the team and the current number of team members
 	team := cs.GetTeamFromTeamID(teamID)
 	agent_map := cs.GetAgentMap()
 
-	num_members := len(team.Agents)
-	total_votes := 0
-
-	// For each agent in the team
-	for _, agentID := range team.Agents {
-		// Get their vote
-		vote := agent_map[agentID].VoteOnAgentEntry(orphanID)
-		// increment the total votes if they vote 'yes'
-		if vote {
-			total_votes++
+	result := cs.holdBallot(common.BallotSpec{
+		TeamID:    teamID,
+		Question:  "admit orphan " + orphanID.String(),
+		Options:   []string{"admit", "reject"},
+		Voters:    team.Agents,
+		Rule:      common.BallotRuleThreshold,
+		Threshold: float64(entryThreshold),
+	}, func(voterID uuid.UUID) []int {
+		if agent_map[voterID].VoteOnAgentEntry(orphanID) {
+			return []int{0}
 		}
-	}
+		return []int{1}
+	})
 
-	// Calculate the acceptance ratio and return 'yes' only if enough of the
-	// team has voted to accept.
-	acceptance := float32(total_votes) / float32(num_members)
-	return (acceptance >= entryThreshold)
+	// 'yes' only if enough of the team has voted to accept
+	return result.Winner == 0
 }
 
 /*
@@ -65,11 +65,11 @@ func (cs *EnvironmentServer) AllocateOrphans() {
 
 	// for each orphan currently in the pool / shelter
 	for orphanID, teamsList := range cs.orphanPool {
-		log.Printf("allocating %v\n", orphanID)
+		logging.Trace(orphanLog, "Allocating orphan", "agent", orphanID)
 		var accepted = false
 		// for each team that orphan wants to join
 		for _, teamID := range teamsList {
-			log.Printf("team id testing is %v\n", teamID)
+			logging.Trace(orphanLog, "Asking team to accept orphan", "agent", orphanID, "team", teamID)
 			// Skip if already accepted into a team
 			if accepted {
 				break
@@ -80,15 +80,16 @@ func (cs *EnvironmentServer) AllocateOrphans() {
 			// If the team has voted to accept the orphan
 			if accepted {
 				agent_map[orphanID].SetTeamID(teamID) // Update agent's knowledge of its team
-				cs.AddAgentToTeam(orphanID, teamID)   // Update team's knowledge of its agents
-				log.Printf("%v accepted by team %v !!\n", orphanID, teamID)
+				cs.addAgentToTeam(orphanID, teamID)   // Update team's knowledge of its agents
+				orphanLog.Info("Orphan accepted", "agent", orphanID, "team", teamID)
+				cs.publish(OrphanAllocatedEvent{EventContext: cs.eventContext(), AgentID: orphanID, TeamID: teamID})
 			}
 			// Otherwise, continue to the next team in the preference list.
 		}
 
 		if !accepted {
 			unallocated[orphanID] = teamsList // add to unallocated
-			log.Printf("%v remains in the orphan pool after allocation...\n", orphanID)
+			orphanLog.Debug("Orphan remains in the pool after allocation", "agent", orphanID)
 		}
 	}
 
@@ -126,15 +127,11 @@ func (cs *EnvironmentServer) PickUpOrphans() {
 			// this even for orphans that are already in the pool because we want
 			// them to be able to update their preferences on which teams they
 			// would like to join
-			log.Printf("testing %v\n", agentID)
 			cs.orphanPool[agentID] = agent.GetTeamRanking()
-
-			for _, x := range cs.orphanPool[agentID] {
-				log.Printf("wants to join %v\n", x)
-			}
+			logging.Trace(orphanLog, "Orphan preferences", "agent", agentID, "teams", cs.orphanPool[agentID])
 
 			if !exists {
-				log.Printf("%v was added to the orphan pool \n", agentID)
+				orphanLog.Debug("Agent added to the orphan pool", "agent", agentID)
 			}
 		}
 	}
@@ -0,0 +1,340 @@
+package environmentServer
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/ADimoska/SOMASExtended/voting"
+	"github.com/google/uuid"
+)
+
+/*
+* Team roles (see common.RoleHolder). At the start of each team's turn the
+* server holds an election for every role whose term is up or that has an
+* empty seat, and tells the AoA who holds each role. Holders can be impeached
+* by a ballot of the team, and their seat is filled at the next election.
+ */
+type ElectionMethod func(cs *EnvironmentServer, team *common.Team, role *roleState, candidates []uuid.UUID) []uuid.UUID
+
+type ElectionMethodInfo struct {
+	Name        string
+	Description string
+	Method      ElectionMethod
+}
+
+var electionMethods = map[string]ElectionMethodInfo{
+	"vote": {
+		Name:        "vote",
+		Description: "members rank the candidates and the game's voting rule picks the holders",
+		Method:      electByVote,
+	},
+	"rotation": {
+		Name:        "rotation",
+		Description: "the seats pass to the next members in team order",
+		Method:      electByRotation,
+	},
+	"lottery": {
+		Name:        "lottery",
+		Description: "holders are drawn at random from the members",
+		Method: func(cs *EnvironmentServer, team *common.Team, role *roleState, candidates []uuid.UUID) []uuid.UUID {
+			drawn := make([]uuid.UUID, 0, len(candidates))
+			for _, i := range rand.Perm(len(candidates)) {
+				drawn = append(drawn, candidates[i])
+			}
+			return drawn
+		},
+	},
+	"rank-lottery": {
+		Name:        "rank-lottery",
+		Description: "holders are drawn at random, weighted by the ranks the AoA gives its members",
+		Method:      electByRankLottery,
+	},
+	"contribution": {
+		Name:        "contribution",
+		Description: "the members that contributed the most during the last term",
+		Method: func(cs *EnvironmentServer, team *common.Team, role *roleState, candidates []uuid.UUID) []uuid.UUID {
+			ranked := append([]uuid.UUID{}, candidates...)
+			sort.SliceStable(ranked, func(i, j int) bool {
+				return role.contributions[ranked[i]] > role.contributions[ranked[j]]
+			})
+			return ranked
+		},
+	},
+}
+
+// List the available election methods, sorted by name
+func ElectionMethods() []ElectionMethodInfo {
+	methods := make([]ElectionMethodInfo, 0, len(electionMethods))
+	for _, info := range electionMethods {
+		methods = append(methods, info)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+	return methods
+}
+
+// A role of a team as the server tracks it
+type roleState struct {
+	spec          common.RoleSpec
+	holders       []uuid.UUID
+	elected       bool
+	served        int
+	impeached     bool
+	lastHolder    uuid.UUID         // last holder elected, where rotation carries on from
+	contributions map[uuid.UUID]int // by member, since the last election
+}
+
+// Hold the elections that are due and hand the holders to the team's AoA
+func (cs *EnvironmentServer) runElections(team *common.Team) {
+	holder, ok := team.TeamAoA.(common.RoleHolder)
+	if !ok {
+		delete(cs.teamRoles, team.TeamID)
+		return
+	}
+	if cs.teamRoles == nil {
+		cs.teamRoles = make(map[uuid.UUID]map[common.Role]*roleState)
+	}
+	roles := cs.teamRoles[team.TeamID]
+	if roles == nil {
+		roles = make(map[common.Role]*roleState)
+	}
+
+	candidates := cs.livingMembers(team)
+	declared := make(map[common.Role]*roleState)
+	phaseAnnounced := false
+	for _, spec := range holder.GetRoles() {
+		role := roles[spec.Role]
+		if role == nil {
+			role = &roleState{contributions: map[uuid.UUID]int{}}
+		}
+		role.spec = spec
+		declared[spec.Role] = role
+
+		kept := []uuid.UUID{}
+		for _, id := range role.holders {
+			if containsID(candidates, id) {
+				kept = append(kept, id)
+			}
+		}
+		vacant := len(kept) < min(spec.SeatCount(), len(candidates))
+		role.holders = kept
+
+		reason := ""
+		switch {
+		case !role.elected:
+			reason = "first election"
+		case role.impeached && vacant:
+			reason = "impeachment"
+		case vacant:
+			reason = "vacancy"
+		case role.served >= spec.TermLength():
+			reason = "end of term"
+		}
+		if reason != "" && len(candidates) > 0 {
+			if !phaseAnnounced {
+				cs.publishPhase(PhaseElection, team.TeamID)
+				phaseAnnounced = true
+			}
+			cs.elect(team, role, candidates, reason)
+		}
+		role.served++
+		holder.SetRoleHolders(spec.Role, role.holders)
+	}
+	cs.teamRoles[team.TeamID] = declared
+}
+
+func (cs *EnvironmentServer) elect(team *common.Team, role *roleState, candidates []uuid.UUID, reason string) {
+	info, ok := electionMethods[role.spec.ElectionMethod()]
+	if !ok {
+		teamLog.Warn("Unknown election method, electing by vote", "team", team.TeamID, "role", role.spec.Role, "method", role.spec.Method)
+		info = electionMethods["vote"]
+	}
+	previous := role.holders
+	ranked := info.Method(cs, team, role, candidates)
+	role.holders = ranked[:min(role.spec.SeatCount(), len(ranked))]
+	if len(role.holders) > 0 {
+		role.lastHolder = role.holders[len(role.holders)-1]
+	}
+	role.elected, role.served, role.impeached = true, 0, false
+	role.contributions = map[uuid.UUID]int{}
+
+	teamLog.Info("Role elected", "team", team.TeamID, "role", role.spec.Role, "method", info.Name, "reason", reason, "holders", role.holders)
+	cs.publish(ElectionEvent{
+		EventContext: cs.eventContext(),
+		TeamID:       team.TeamID,
+		Role:         string(role.spec.Role),
+		Method:       info.Name,
+		Reason:       reason,
+		Previous:     previous,
+		Holders:      role.holders,
+	})
+}
+
+// Candidates ranked by a ballot of the whole team, under the game's voting rule
+func electByVote(cs *EnvironmentServer, team *common.Team, role *roleState, candidates []uuid.UUID) []uuid.UUID {
+	options := make([]string, len(candidates))
+	for i, id := range candidates {
+		options[i] = id.String()
+	}
+	rule := cs.votingRule
+	if rule == "" {
+		rule = voting.DefaultRule
+	}
+	result := cs.holdBallot(common.BallotSpec{
+		TeamID:   team.TeamID,
+		Question: "elect " + string(role.spec.Role),
+		Options:  options,
+		Voters:   candidates,
+		Rule:     rule,
+	}, func(voterID uuid.UUID) []int {
+		ranking := []int{}
+		for _, id := range cs.GetAgentMap()[voterID].VoteForRole(role.spec, candidates) {
+			for i, candidate := range candidates {
+				if candidate == id {
+					ranking = append(ranking, i)
+				}
+			}
+		}
+		if voting.Ballot(ranking).Validate(optionIndices(options)) != nil {
+			return nil
+		}
+		return ranking
+	})
+
+	// best score first, ties to the earlier member
+	order := optionIndices(options)
+	sort.SliceStable(order, func(i, j int) bool { return result.Scores[order[i]] > result.Scores[order[j]] })
+	ranked := make([]uuid.UUID, len(order))
+	for i, option := range order {
+		ranked[i] = candidates[option]
+	}
+	return ranked
+}
+
+// The members after the last holder, in team order
+func electByRotation(cs *EnvironmentServer, team *common.Team, role *roleState, candidates []uuid.UUID) []uuid.UUID {
+	start := 0
+	for i, id := range candidates {
+		if id == role.lastHolder {
+			start = i + 1
+		}
+	}
+	ranked := make([]uuid.UUID, len(candidates))
+	for i := range candidates {
+		ranked[i] = candidates[(start+i)%len(candidates)]
+	}
+	return ranked
+}
+
+/*
+* Candidates drawn one by one without replacement, each with a chance in
+* proportion to their rank. Members without a rank, or all of them if the AoA
+* does not rank its members, count as rank 1.
+ */
+func electByRankLottery(cs *EnvironmentServer, team *common.Team, role *roleState, candidates []uuid.UUID) []uuid.UUID {
+	ranks := map[uuid.UUID]int{}
+	if ranker, ok := team.TeamAoA.(common.MemberRanker); ok {
+		ranks = ranker.GetMemberRanks()
+	}
+	remaining := append([]uuid.UUID{}, candidates...)
+	drawn := make([]uuid.UUID, 0, len(candidates))
+	for len(remaining) > 0 {
+		total := 0
+		for _, id := range remaining {
+			total += max(ranks[id], 1)
+		}
+		pick := rand.Intn(total)
+		for i, id := range remaining {
+			pick -= max(ranks[id], 1)
+			if pick < 0 {
+				drawn = append(drawn, id)
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return drawn
+}
+
+func (cs *EnvironmentServer) livingMembers(team *common.Team) []uuid.UUID {
+	members := []uuid.UUID{}
+	for _, agentID := range team.Agents {
+		agent, ok := cs.GetAgentMap()[agentID]
+		if ok && !cs.IsAgentDead(agentID) && agent.GetTeamID() == team.TeamID {
+			members = append(members, agentID)
+		}
+	}
+	return members
+}
+
+// Count contributions towards the "contribution" election method
+func (cs *EnvironmentServer) trackRoleContributions() {
+	cs.OnContribution(func(e ContributionEvent) {
+		for _, role := range cs.teamRoles[e.TeamID] {
+			role.contributions[e.AgentID] += e.ActualContribution
+		}
+	})
+}
+
+// The roles of a team and their holders
+func (cs *EnvironmentServer) GetRoles(teamID uuid.UUID) []common.RoleTerm {
+	terms := []common.RoleTerm{}
+	for _, role := range cs.teamRoles[teamID] {
+		terms = append(terms, common.RoleTerm{RoleSpec: role.spec, Holders: append([]uuid.UUID{}, role.holders...), Served: role.served})
+	}
+	sort.Slice(terms, func(i, j int) bool { return terms[i].Role < terms[j].Role })
+	return terms
+}
+
+func (cs *EnvironmentServer) GetRoleHolders(teamID uuid.UUID, role common.Role) []uuid.UUID {
+	if state, ok := cs.teamRoles[teamID][role]; ok {
+		return append([]uuid.UUID{}, state.holders...)
+	}
+	return []uuid.UUID{}
+}
+
+/*
+* Move to impeach a holder. The team votes on it in a threshold ballot that
+* closes at the end of the turn, with the proposer's vote already cast; if
+* the role's impeachment majority is reached the holder loses the seat.
+ */
+func (cs *EnvironmentServer) Impeach(teamID uuid.UUID, role common.Role, holderID uuid.UUID, proposerID uuid.UUID) error {
+	state, ok := cs.teamRoles[teamID][role]
+	if !ok || !containsID(state.holders, holderID) {
+		return fmt.Errorf("agent %s does not hold the %s role in team %s", holderID, role, teamID)
+	}
+	team := cs.Teams[teamID]
+	members := cs.livingMembers(team)
+	if !containsID(members, proposerID) {
+		return fmt.Errorf("only members of team %s can move to impeach", teamID)
+	}
+	ballotID, err := cs.OpenBallot(common.BallotSpec{
+		TeamID:    teamID,
+		Question:  fmt.Sprintf("impeach %s %s", role, holderID),
+		Options:   []string{"remove", "keep"},
+		Voters:    members,
+		Rule:      common.BallotRuleThreshold,
+		Threshold: state.spec.Majority(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := cs.CastBallot(ballotID, proposerID, []int{0}); err != nil {
+		return err
+	}
+	cs.onBallotClosed(ballotID, func(result common.BallotResult) {
+		if result.Winner != 0 {
+			return
+		}
+		kept := []uuid.UUID{}
+		for _, id := range state.holders {
+			if id != holderID {
+				kept = append(kept, id)
+			}
+		}
+		state.holders, state.impeached = kept, true
+		teamLog.Info("Role holder impeached", "team", teamID, "role", role, "agent", holderID)
+	})
+	return nil
+}
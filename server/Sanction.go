@@ -0,0 +1,103 @@
+package environmentServer
+
+import (
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+)
+
+/*
+* Carry out the sanction a team's AoA imposes on an agent that just failed an
+* audit (see common.Sanctioner). A fine moves resources from the agent's score
+* into the common pool, never more than the agent has. A withdrawal ban starts
+* with the next withdrawal phase and a vote ban straight away. An expelled
+* agent leaves the team and is picked up as an orphan at the start of the next
+* turn. Returns the sanction as carried out.
+ */
+func (cs *EnvironmentServer) applySanction(team *common.Team, agentID uuid.UUID, phase string) common.Sanction {
+	sanctioner, ok := team.TeamAoA.(common.Sanctioner)
+	if !ok {
+		return common.Sanction{}
+	}
+	agent, ok := cs.GetAgentMap()[agentID]
+	if !ok || cs.IsAgentDead(agentID) || agent.GetTeamID() != team.TeamID {
+		return common.Sanction{}
+	}
+	sanction := sanctioner.GetSanction(agentID, team.GetCommonPool())
+	if sanction.IsZero() {
+		return sanction
+	}
+
+	fine := min(max(sanction.Fine, 0), max(agent.GetTrueScore(), 0))
+	agent.SetTrueScore(agent.GetTrueScore() - fine)
+	team.SetCommonPool(team.GetCommonPool() + fine)
+
+	withdrawalBan, voteBan := max(sanction.WithdrawalBan, 0), max(sanction.VoteBan, 0)
+	if withdrawalBan > 0 {
+		first := cs.turnsPlayed
+		if phase == string(common.WithdrawalAudit) {
+			first++
+		}
+		cs.banUntil(&cs.withdrawalBans, agentID, first+withdrawalBan-1)
+	}
+	if voteBan > 0 {
+		cs.banUntil(&cs.voteBans, agentID, cs.turnsPlayed+voteBan)
+	}
+
+	// read before an expulsion makes the AoA forget the agent
+	offences := 0
+	if keeper, ok := team.TeamAoA.(common.OffenceKeeper); ok {
+		offences = keeper.GetOffences(agentID)
+	}
+
+	if sanction.Expel {
+		for i, id := range team.Agents {
+			if id == agentID {
+				team.Agents = append(team.Agents[:i], team.Agents[i+1:]...)
+				team.TeamAoA.OnMemberLeft(agentID)
+				break
+			}
+		}
+		agent.SetTeamID(uuid.Nil)
+	}
+
+	teamLog.Info("Agent sanctioned", "team", team.TeamID, "agent", agentID, "offences", offences, "warning", sanction.Warning,
+		"fine", fine, "withdrawalBan", withdrawalBan, "voteBan", voteBan, "expelled", sanction.Expel)
+	cs.publish(SanctionEvent{
+		EventContext:  cs.eventContext(),
+		TeamID:        team.TeamID,
+		AgentID:       agentID,
+		Phase:         phase,
+		Offences:      offences,
+		Warning:       sanction.Warning,
+		Fine:          fine,
+		WithdrawalBan: withdrawalBan,
+		VoteBan:       voteBan,
+		Expelled:      sanction.Expel,
+	})
+	return common.Sanction{Warning: sanction.Warning, Fine: fine, WithdrawalBan: withdrawalBan, VoteBan: voteBan, Expel: sanction.Expel}
+}
+
+// Extend a ban to the given turn, counted by turnsPlayed
+func (cs *EnvironmentServer) banUntil(bans *map[uuid.UUID]int, agentID uuid.UUID, turn int) {
+	if *bans == nil {
+		*bans = make(map[uuid.UUID]int)
+	}
+	(*bans)[agentID] = max((*bans)[agentID], turn)
+}
+
+// Whether the agent has lost its withdrawal rights for this turn
+func (cs *EnvironmentServer) IsWithdrawalBanned(agentID uuid.UUID) bool {
+	return cs.withdrawalBans[agentID] >= cs.turnsPlayed && cs.turnsPlayed > 0
+}
+
+// Whether the agent's votes do not count this turn
+func (cs *EnvironmentServer) IsVoteBanned(agentID uuid.UUID) bool {
+	return cs.voteBans[agentID] >= cs.turnsPlayed && cs.turnsPlayed > 0
+}
+
+// A turn has passed for the team, which may forgive some offences
+func (cs *EnvironmentServer) passOffenceTurn(team *common.Team) {
+	if keeper, ok := team.TeamAoA.(common.OffenceKeeper); ok {
+		keeper.PassTurn()
+	}
+}
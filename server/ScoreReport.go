@@ -0,0 +1,68 @@
+package environmentServer
+
+import (
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+)
+
+/*
+* Every living member of the team rolls the dice, then declares its turn score
+* to the team. The declarations are recorded against the rolls and, once all
+* are in, given to the AoA if it asks for them (see common.ScoreReportPolicy).
+ */
+func (cs *EnvironmentServer) runScoreReports(team *common.Team) {
+	rolled := map[uuid.UUID]int{}
+	for _, agentID := range team.Agents {
+		agent := cs.GetAgentMap()[agentID]
+		if agent.GetTeamID() == uuid.Nil || cs.IsAgentDead(agentID) {
+			continue
+		}
+		// Override agent rolls for testing purposes
+		// agentList := []uuid.UUID{agentID}
+		// cs.OverrideAgentRolls(agentID, agentList, 1)
+		scoreBefore := agent.GetTrueScore()
+		agent.StartRollingDice(agent)
+		rolled[agentID] = agent.GetTrueScore() - scoreBefore
+	}
+
+	cs.publishPhase(PhaseScoreReport, team.TeamID)
+	reports := []common.ScoreReport{}
+	for _, agentID := range team.Agents {
+		if _, ok := rolled[agentID]; !ok {
+			continue
+		}
+		agent := cs.GetAgentMap()[agentID]
+		report := common.ScoreReport{AgentID: agentID, Declared: agent.GetStatedTurnScore(agent), Rolled: rolled[agentID]}
+		agent.BroadcastSyncMessageToTeam(agent.CreateScoreReportMessage(report.Declared))
+		reports = append(reports, report)
+		if report.Misreported() {
+			teamLog.Debug("Agent misreported its turn score", "team", team.TeamID, "agent", agentID, "declared", report.Declared, "rolled", report.Rolled)
+		}
+		cs.publish(ScoreReportEvent{
+			EventContext: cs.eventContext(),
+			TeamID:       team.TeamID,
+			AgentID:      agentID,
+			Declared:     report.Declared,
+			Rolled:       report.Rolled,
+		})
+	}
+
+	if cs.scoreReports == nil {
+		cs.scoreReports = make(map[uuid.UUID][]common.ScoreReport)
+	}
+	cs.scoreReports[team.TeamID] = reports
+	if policy, ok := team.TeamAoA.(common.ScoreReportPolicy); ok {
+		policy.ResetScoreReports()
+		for _, report := range reports {
+			policy.SetScoreReport(report)
+		}
+	}
+}
+
+/*
+* The turn scores the members of a team declared this turn, with what they
+* really rolled. Agents only see the declarations, so this is not in IServer.
+ */
+func (cs *EnvironmentServer) GetScoreReports(teamID uuid.UUID) []common.ScoreReport {
+	return cs.scoreReports[teamID]
+}
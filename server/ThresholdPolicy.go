@@ -0,0 +1,70 @@
+package environmentServer
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+/*
+* A threshold policy decides the score agents must reach at every threshold
+* turn of an iteration. The policy is chosen by name so that it can be set from
+* scenario files and the command line.
+ */
+type ThresholdPolicy func(iteration int, value int) int
+
+type ThresholdPolicyInfo struct {
+	Name        string
+	Description string
+	Policy      ThresholdPolicy
+}
+
+const DefaultThresholdPolicy = "random"
+
+var thresholdPolicies = map[string]ThresholdPolicyInfo{
+	"random": {
+		Name:        "random",
+		Description: "uniformly random threshold between 10 and 19, redrawn every iteration",
+		Policy: func(iteration int, value int) int {
+			return rand.Intn(10) + 10
+		},
+	},
+	"fixed": {
+		Name:        "fixed",
+		Description: "the same threshold (thresholdValue) in every iteration",
+		Policy: func(iteration int, value int) int {
+			return value
+		},
+	},
+	"rising": {
+		Name:        "rising",
+		Description: "starts at thresholdValue and rises by 2 every iteration",
+		Policy: func(iteration int, value int) int {
+			return value + 2*iteration
+		},
+	},
+}
+
+// List the available threshold policies, sorted by name
+func ThresholdPolicies() []ThresholdPolicyInfo {
+	policies := make([]ThresholdPolicyInfo, 0, len(thresholdPolicies))
+	for _, info := range thresholdPolicies {
+		policies = append(policies, info)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	return policies
+}
+
+/*
+* Choose how the round score threshold is set at the start of each iteration.
+* The value is only used by the policies that need one (e.g. "fixed").
+ */
+func (cs *EnvironmentServer) SetThresholdPolicy(name string, value int) error {
+	info, ok := thresholdPolicies[name]
+	if !ok {
+		return fmt.Errorf("unknown threshold policy %q", name)
+	}
+	cs.thresholdPolicy = info.Policy
+	cs.thresholdValue = value
+	return nil
+}
@@ -0,0 +1,102 @@
+package simulation
+
+import (
+	"fmt"
+
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+)
+
+/*
+* The invariant checker subscribes to the game events and records every event
+* that breaks one of the rules the game should always obey. It only looks at
+* events, so it can check a live game as well as a replayed event log.
+ */
+type InvariantChecker struct {
+	Violations []string
+
+	members map[uuid.UUID]uuid.UUID // agent -> team it is currently in
+	dead    map[uuid.UUID]bool
+	last    envServer.EventContext
+}
+
+func NewInvariantChecker() *InvariantChecker {
+	return &InvariantChecker{
+		members: make(map[uuid.UUID]uuid.UUID),
+		dead:    make(map[uuid.UUID]bool),
+	}
+}
+
+func (ic *InvariantChecker) Attach(bus *envServer.EventBus) {
+	bus.SubscribeAll(ic.check)
+}
+
+func (ic *InvariantChecker) fail(event envServer.Event, format string, args ...any) {
+	when := event.When()
+	message := fmt.Sprintf(format, args...)
+	ic.Violations = append(ic.Violations, fmt.Sprintf("iteration %d, turn %d, %s: %s", when.Iteration, when.Turn, event.Type(), message))
+}
+
+// The agent must be alive and a member of the team the event is about
+func (ic *InvariantChecker) checkMember(event envServer.Event, agentID uuid.UUID, teamID uuid.UUID) {
+	if ic.dead[agentID] {
+		ic.fail(event, "dead agent %v took part", agentID)
+	}
+	if ic.members[agentID] != teamID {
+		ic.fail(event, "agent %v is not a member of team %v", agentID, teamID)
+	}
+}
+
+func (ic *InvariantChecker) check(event envServer.Event) {
+	when := event.When()
+	if when.Iteration < ic.last.Iteration || (when.Iteration == ic.last.Iteration && when.Turn < ic.last.Turn) {
+		ic.fail(event, "time went backwards from iteration %d, turn %d", ic.last.Iteration, ic.last.Turn)
+	}
+	ic.last = when
+
+	switch e := event.(type) {
+	case envServer.IterationStartEvent:
+		// teams are formed again at the start of every iteration
+		ic.members = make(map[uuid.UUID]uuid.UUID)
+	case envServer.TeamFormedEvent:
+		for _, agentID := range e.Agents {
+			ic.members[agentID] = e.TeamID
+		}
+	case envServer.OrphanAllocatedEvent:
+		ic.members[e.AgentID] = e.TeamID
+	case envServer.TeamJoinedEvent:
+		ic.members[e.AgentID] = e.TeamID
+	case envServer.ContributionEvent:
+		ic.checkMember(event, e.AgentID, e.TeamID)
+		if e.ActualContribution < 0 {
+			ic.fail(event, "agent %v contributed a negative amount (%d)", e.AgentID, e.ActualContribution)
+		}
+	case envServer.WithdrawalEvent:
+		ic.checkMember(event, e.AgentID, e.TeamID)
+		if e.ActualWithdrawal < 0 {
+			ic.fail(event, "agent %v withdrew a negative amount (%d)", e.AgentID, e.ActualWithdrawal)
+		}
+		if e.CommonPoolAfter < 0 {
+			ic.fail(event, "common pool of team %v went negative (%d)", e.TeamID, e.CommonPoolAfter)
+		}
+	case envServer.AuditEvent:
+		ic.checkMember(event, e.AuditedAgentID, e.TeamID)
+		if e.Cost < 0 {
+			ic.fail(event, "audit had a negative cost (%d)", e.Cost)
+		}
+	case envServer.DeathEvent:
+		if ic.dead[e.AgentID] {
+			ic.fail(event, "agent %v died twice", e.AgentID)
+		}
+		ic.dead[e.AgentID] = true
+		delete(ic.members, e.AgentID)
+	case envServer.RevivalEvent:
+		delete(ic.dead, e.AgentID)
+	case envServer.TurnEndEvent:
+		for _, record := range e.Agents {
+			if record.IsAlive == ic.dead[record.AgentID] {
+				ic.fail(event, "agent %v recorded as alive=%v", record.AgentID, record.IsAlive)
+			}
+		}
+	}
+}
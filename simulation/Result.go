@@ -0,0 +1,283 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	gameRecorder "github.com/ADimoska/SOMASExtended/gameRecorder"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+)
+
+// Result of running one scenario, saved as JSON so reports can be made later
+type Result struct {
+	Scenario    Scenario
+	Duration    string
+	Summary     Summary
+	Violations  []string
+	TurnRecords []gameRecorder.TurnRecord
+}
+
+type Summary struct {
+	TurnsPlayed       int
+	Agents            int
+	Survivors         int
+	Deaths            int
+	MeanFinalScore    float64
+	TotalContributed  int
+	TotalWithdrawn    int
+	Audits            int
+	FailedAudits      int
+	FalseConvictions  int // failed audits of agents whose records were clean
+	MissedInfractions int // passed audits of agents whose records showed cheating
+	TeamsFormed       int
+	OrphansAllocated  int
+	ThresholdsApplied int
+	AoAWins           map[int]int // AoA votes won by each AoA
+	RuleDisagreements int         // AoA votes that another voting rule would have decided differently
+	Amendments        int         // AoA amendments voted on during turns
+	AmendmentsPassed  int
+	Offspring         int            // dead agents replaced in evolutionary mode
+	FinalStrategies   map[string]int // living agents of each strategy at the end
+}
+
+func (r *Result) Failed() bool {
+	return len(r.Violations) > 0
+}
+
+// Builds the summary of a game from its events
+type summaryCollector struct {
+	summary  Summary
+	lastTurn []gameRecorder.AgentRecord
+}
+
+func newSummaryCollector() *summaryCollector {
+	return &summaryCollector{}
+}
+
+func (sc *summaryCollector) attach(bus *envServer.EventBus) {
+	bus.SubscribeAll(func(event envServer.Event) {
+		switch e := event.(type) {
+		case envServer.TurnEndEvent:
+			sc.summary.TurnsPlayed++
+			sc.lastTurn = e.Agents
+		case envServer.ContributionEvent:
+			sc.summary.TotalContributed += e.ActualContribution
+		case envServer.WithdrawalEvent:
+			sc.summary.TotalWithdrawn += e.ActualWithdrawal
+		case envServer.AuditEvent:
+			sc.summary.Audits++
+			if e.Result {
+				sc.summary.FailedAudits++
+			}
+			if e.Result && !e.Infraction {
+				sc.summary.FalseConvictions++
+			}
+			if !e.Result && e.Infraction {
+				sc.summary.MissedInfractions++
+			}
+		case envServer.DeathEvent:
+			sc.summary.Deaths++
+		case envServer.TeamFormedEvent:
+			sc.summary.TeamsFormed++
+		case envServer.OrphanAllocatedEvent:
+			sc.summary.OrphansAllocated++
+		case envServer.ThresholdEvent:
+			sc.summary.ThresholdsApplied++
+		case envServer.AoASelectedEvent:
+			if sc.summary.AoAWins == nil {
+				sc.summary.AoAWins = map[int]int{}
+			}
+			sc.summary.AoAWins[e.AoAID]++
+			for _, winners := range e.WinnersByRule {
+				if !reflect.DeepEqual(winners, e.WinnersByRule[e.Rule]) {
+					sc.summary.RuleDisagreements++
+					break
+				}
+			}
+		case envServer.AmendmentEvent:
+			sc.summary.Amendments++
+			if e.Passed {
+				sc.summary.AmendmentsPassed++
+			}
+		case envServer.OffspringEvent:
+			sc.summary.Offspring++
+		}
+	})
+}
+
+// Fill in the end of game figures from the last recorded turn
+func (sc *summaryCollector) finish() Summary {
+	total := 0
+	sc.summary.Agents = len(sc.lastTurn)
+	sc.summary.FinalStrategies = map[string]int{}
+	for _, record := range sc.lastTurn {
+		if record.IsAlive {
+			sc.summary.Survivors++
+			total += record.Score
+			sc.summary.FinalStrategies[record.Strategy]++
+		}
+	}
+	if sc.summary.Survivors > 0 {
+		sc.summary.MeanFinalScore = float64(total) / float64(sc.summary.Survivors)
+	}
+	return sc.summary
+}
+
+// Summarise a replayed event log in the same way as a live game
+func SummariseEvents(events []envServer.Event) Summary {
+	bus := envServer.NewEventBus()
+	collector := newSummaryCollector()
+	collector.attach(bus)
+	for _, event := range events {
+		bus.Publish(event)
+	}
+	return collector.finish()
+}
+
+// --------- Saving and loading ---------
+
+func SaveResult(result *Result, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+/*
+* Load saved results from a list of paths. A directory loads every .json file
+* inside it (recursively), so the output directory of a batch or sweep can be
+* passed directly.
+ */
+func LoadResults(paths []string) ([]*Result, error) {
+	results := []*Result{}
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(file) != ".json" {
+				return err
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			result := &Result{}
+			if err := json.Unmarshal(data, result); err != nil {
+				return fmt.Errorf("%s: %v", file, err)
+			}
+			results = append(results, result)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Scenario.Name < results[j].Scenario.Name })
+	return results, nil
+}
+
+// --------- Reports ---------
+
+var reportColumns = []string{"Run", "Seed", "Survivors", "Deaths", "Mean score", "Contributed", "Withdrawn", "Audits (failed)", "Offspring", "Final population", "Violations"}
+
+func reportRow(result *Result) []string {
+	s := result.Summary
+	return []string{
+		result.Scenario.Name,
+		fmt.Sprint(result.Scenario.Seed),
+		fmt.Sprintf("%d/%d", s.Survivors, s.Agents),
+		fmt.Sprint(s.Deaths),
+		fmt.Sprintf("%.1f", s.MeanFinalScore),
+		fmt.Sprint(s.TotalContributed),
+		fmt.Sprint(s.TotalWithdrawn),
+		fmt.Sprintf("%d (%d)", s.Audits, s.FailedAudits),
+		fmt.Sprint(s.Offspring),
+		formatStrategyCounts(s.FinalStrategies),
+		fmt.Sprint(len(result.Violations)),
+	}
+}
+
+// e.g. "honest=3,liar=1", sorted by strategy
+func formatStrategyCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, counts[name]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func WriteMarkdownReport(w io.Writer, results []*Result) {
+	fmt.Fprintf(w, "# SOMAS results\n\n%d runs\n\n", len(results))
+	fmt.Fprintf(w, "| %s |\n", strings.Join(reportColumns, " | "))
+	fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(reportColumns)))
+	for _, result := range results {
+		fmt.Fprintf(w, "| %s |\n", strings.Join(reportRow(result), " | "))
+	}
+	for _, result := range results {
+		if !result.Failed() {
+			continue
+		}
+		fmt.Fprintf(w, "\n## Invariant violations in %s\n\n", result.Scenario.Name)
+		for _, violation := range result.Violations {
+			fmt.Fprintf(w, "- %s\n", violation)
+		}
+	}
+}
+
+/*
+* Write an HTML report into outputDir: index.html holds the summary table and
+* links to the playback charts of each run, which are written to a
+* sub-directory per run.
+ */
+func WriteHTMLReport(outputDir string, results []*Result) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(outputDir, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "<html><head><title>SOMAS results</title></head><body>\n<h1>SOMAS results</h1>\n<table border=\"1\">\n<tr>")
+	for _, column := range reportColumns {
+		fmt.Fprintf(f, "<th>%s</th>", html.EscapeString(column))
+	}
+	fmt.Fprintln(f, "<th>Charts</th></tr>")
+	for i, result := range results {
+		runDir := fmt.Sprintf("run_%03d", i)
+		gameRecorder.CreatePlaybackHTMLIn(RecorderFromTurns(result.TurnRecords), filepath.Join(outputDir, runDir))
+		fmt.Fprint(f, "<tr>")
+		for _, cell := range reportRow(result) {
+			fmt.Fprintf(f, "<td>%s</td>", html.EscapeString(cell))
+		}
+		fmt.Fprintf(f, "<td><a href=\"%s/game_visualization.html\">charts</a></td></tr>\n", runDir)
+	}
+	fmt.Fprintln(f, "</table>")
+	for _, result := range results {
+		if !result.Failed() {
+			continue
+		}
+		fmt.Fprintf(f, "<h2>Invariant violations in %s</h2>\n<ul>\n", html.EscapeString(result.Scenario.Name))
+		for _, violation := range result.Violations {
+			fmt.Fprintf(f, "<li>%s</li>\n", html.EscapeString(violation))
+		}
+		fmt.Fprintln(f, "</ul>")
+	}
+	fmt.Fprintln(f, "</body></html>")
+	return nil
+}
@@ -0,0 +1,140 @@
+package simulation
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"time"
+
+	baseServer "github.com/MattSScott/basePlatformSOMAS/v2/pkg/server"
+	"github.com/google/uuid"
+
+	agents "github.com/ADimoska/SOMASExtended/agents"
+	common "github.com/ADimoska/SOMASExtended/common"
+	gameRecorder "github.com/ADimoska/SOMASExtended/gameRecorder"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+)
+
+// Extra things to do while running a scenario, on top of playing the game
+type RunOptions struct {
+	EventLog    io.Writer                          // every event is written here if set
+	Debug       bool                               // open the interactive debugger
+	Breakpoints []envServer.Breakpoint             // breakpoints for the debugger
+	Subscribe   func(*envServer.EnvironmentServer) // attach extra subscribers before the game starts
+	Finish      func(*envServer.EnvironmentServer) // inspect the server once the game is over
+}
+
+// Build the server and population described by a scenario, without starting it
+func NewServer(scenario Scenario) (*envServer.EnvironmentServer, error) {
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+	if err := scenario.LoadAoAs(); err != nil {
+		return nil, err
+	}
+
+	// Seeding the global source makes the dice, thresholds and shuffles
+	// repeatable. Go's randomised map iteration means two runs with the same
+	// seed can still differ in the order agents act.
+	rand.Seed(scenario.Seed)
+
+	agentConfig := agents.AgentConfig{
+		InitScore: scenario.InitScore,
+	}
+
+	serv := &envServer.EnvironmentServer{
+		// note: the zero turn is used for team forming
+		BaseServer: baseServer.CreateBaseServer[common.IExtendedAgent](
+			scenario.Iterations,
+			scenario.Turns,
+			time.Duration(scenario.TurnTimeoutMs)*time.Millisecond,
+			scenario.MessageBandwidth),
+		Teams: make(map[uuid.UUID]*common.Team),
+	}
+	serv.Init(scenario.ThresholdTurns)
+	if err := serv.SetThresholdPolicy(scenario.ThresholdPolicy, scenario.ThresholdValue); err != nil {
+		return nil, err
+	}
+	for name, params := range scenario.AoAParams {
+		if err := serv.SetAoAParams(name, params); err != nil {
+			return nil, err
+		}
+	}
+	if err := serv.SetAuditReliability(scenario.AuditReliability); err != nil {
+		return nil, err
+	}
+	if scenario.VotingRule != "" {
+		if err := serv.SetVotingRule(scenario.VotingRule); err != nil {
+			return nil, err
+		}
+	}
+	if scenario.Evolution != "" {
+		evolution := envServer.EvolutionConfig{
+			Policy:         scenario.Evolution,
+			MutationRate:   scenario.MutationRate,
+			MutationScale:  scenario.MutationScale,
+			TournamentSize: scenario.TournamentSize,
+		}
+		spawn := func(strategy string, params common.StrategyParams) (common.IExtendedAgent, error) {
+			return agents.CreateAgent(strategy, serv, agentConfig, params)
+		}
+		if err := serv.SetEvolution(evolution, spawn); err != nil {
+			return nil, err
+		}
+	}
+	serv.SetGameRunner(serv)
+
+	for _, entry := range scenario.Population {
+		for i := 0; i < entry.Count; i++ {
+			created, err := agents.CreateAgent(entry.Agent, serv, agentConfig, entry.Params)
+			if err != nil {
+				return nil, err
+			}
+			serv.AddAgent(created)
+		}
+	}
+	return serv, nil
+}
+
+// Run a scenario to completion and summarise what happened
+func Run(scenario Scenario, options RunOptions) (*Result, error) {
+	serv, err := NewServer(scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := NewInvariantChecker()
+	checker.Attach(serv.Events())
+	summary := newSummaryCollector()
+	summary.attach(serv.Events())
+	if options.EventLog != nil {
+		serv.OnAnyEvent(envServer.NewEventLogWriter(options.EventLog))
+	}
+	if options.Debug || len(options.Breakpoints) > 0 {
+		serv.AttachDebugger(os.Stdin, os.Stdout, options.Breakpoints)
+	}
+	if options.Subscribe != nil {
+		options.Subscribe(serv)
+	}
+
+	start := time.Now()
+	serv.Start()
+	if options.Finish != nil {
+		options.Finish(serv)
+	}
+
+	return &Result{
+		Scenario:    scenario,
+		Duration:    time.Since(start).String(),
+		Summary:     summary.finish(),
+		Violations:  checker.Violations,
+		TurnRecords: serv.DataRecorder.TurnRecords,
+	}, nil
+}
+
+// Rebuild a data recorder from the turn records of a saved result or event log
+func RecorderFromTurns(turns []gameRecorder.TurnRecord) *gameRecorder.ServerDataRecorder {
+	recorder := gameRecorder.CreateRecorder()
+	recorder.TurnRecords = turns
+	return recorder
+}
@@ -0,0 +1,383 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	agents "github.com/ADimoska/SOMASExtended/agents"
+	common "github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/ADimoska/SOMASExtended/voting"
+)
+
+/*
+* A scenario describes one game: how long it runs, which agents take part and
+* how the score threshold is set. Scenarios are loaded from JSON files and any
+* of their values can then be overridden by name (see Set), which is what the
+* command line flags and parameter sweeps use.
+ */
+type Scenario struct {
+	Name             string                      `json:"name"`
+	Seed             int64                       `json:"seed"`
+	Iterations       int                         `json:"iterations"`
+	Turns            int                         `json:"turns"`
+	TurnTimeoutMs    int                         `json:"turnTimeoutMs"`
+	MessageBandwidth int                         `json:"messageBandwidth"`
+	ThresholdTurns   int                         `json:"thresholdTurns"`
+	ThresholdPolicy  string                      `json:"thresholdPolicy"`
+	ThresholdValue   int                         `json:"thresholdValue"`
+	InitScore        int                         `json:"initScore"`
+	VotingRule       string                      `json:"votingRule"`
+	AoAParams        map[string]common.AoAParams `json:"aoaParams,omitempty"` // by AoA name
+	AoAFiles         []string                    `json:"aoaFiles,omitempty"`  // AoA definitions to load, see common.AoADefinition
+	AuditReliability common.AuditReliability     `json:"auditReliability"`    // perfect audits if left out
+	Population       []PopulationEntry           `json:"population"`
+
+	// evolutionary mode: how dead agents are replaced between iterations
+	Evolution      string  `json:"evolution"`
+	MutationRate   float64 `json:"mutationRate"`
+	MutationScale  float64 `json:"mutationScale"`
+	TournamentSize int     `json:"tournamentSize"`
+}
+
+// PopulationEntry adds Count agents of the named strategy to the game
+type PopulationEntry struct {
+	Agent  string                `json:"agent"`
+	Count  int                   `json:"count"`
+	Params common.StrategyParams `json:"params,omitempty"` // strategy parameters, defaults if left out
+}
+
+// The game that main.go has always run: 2 iterations of 12 turns, with two
+// Team 4 agents and two base agents.
+func DefaultScenario() Scenario {
+	return Scenario{
+		Name:             "default",
+		Seed:             1,
+		Iterations:       2,
+		Turns:            12,
+		TurnTimeoutMs:    100,
+		MessageBandwidth: 10,
+		ThresholdTurns:   3,
+		ThresholdPolicy:  envServer.DefaultThresholdPolicy,
+		ThresholdValue:   15,
+		InitScore:        0,
+		VotingRule:       voting.DefaultRule,
+		Population: []PopulationEntry{
+			{Agent: "team4", Count: 2},
+			{Agent: "base", Count: 2},
+		},
+		Evolution:      envServer.DefaultEvolutionPolicy,
+		MutationRate:   0.1,
+		MutationScale:  0.2,
+		TournamentSize: 2,
+	}
+}
+
+// Load a scenario from a JSON file. Values missing from the file keep their
+// defaults.
+func LoadScenario(path string) (Scenario, error) {
+	scenario := DefaultScenario()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return scenario, err
+	}
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return scenario, fmt.Errorf("%s: %v", path, err)
+	}
+	// AoA files are found relative to the scenario that names them
+	for i, file := range scenario.AoAFiles {
+		if !filepath.IsAbs(file) {
+			scenario.AoAFiles[i] = filepath.Join(filepath.Dir(path), file)
+		}
+	}
+	return scenario, nil
+}
+
+// Names of the values that can be overridden with Set
+var ScenarioKeys = []string{
+	"name", "seed", "iterations", "turns", "turnTimeoutMs", "messageBandwidth",
+	"thresholdTurns", "thresholdPolicy", "thresholdValue", "initScore", "votingRule", "aoaParams", "aoaFiles", "auditReliability", "agents",
+	"evolution", "mutationRate", "mutationScale", "tournamentSize",
+}
+
+/*
+* Override a single scenario value by name. Numbers are parsed from the string,
+* and "agents" takes a population in the form "team4=2,base=3" (see
+* ParsePopulation). "auditReliability" takes "miss=0.2,falsePositive=0.05"
+* and the like (see common.AuditReliability).
+ */
+func (s *Scenario) Set(key string, value string) error {
+	intFields := map[string]*int{
+		"iterations":       &s.Iterations,
+		"turns":            &s.Turns,
+		"turnTimeoutMs":    &s.TurnTimeoutMs,
+		"messageBandwidth": &s.MessageBandwidth,
+		"thresholdTurns":   &s.ThresholdTurns,
+		"thresholdValue":   &s.ThresholdValue,
+		"initScore":        &s.InitScore,
+		"tournamentSize":   &s.TournamentSize,
+	}
+	if field, ok := intFields[key]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number, got %q", key, value)
+		}
+		*field = n
+		return nil
+	}
+	floatFields := map[string]*float64{
+		"mutationRate":  &s.MutationRate,
+		"mutationScale": &s.MutationScale,
+	}
+	if field, ok := floatFields[key]; ok {
+		x, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", key, value)
+		}
+		*field = x
+		return nil
+	}
+
+	switch key {
+	case "name":
+		s.Name = value
+	case "seed":
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("seed must be a whole number, got %q", value)
+		}
+		s.Seed = seed
+	case "thresholdPolicy":
+		s.ThresholdPolicy = value
+	case "evolution":
+		s.Evolution = value
+	case "votingRule":
+		s.VotingRule = value
+	case "aoaFiles":
+		s.AoAFiles = nil
+		for _, file := range strings.Split(value, ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				s.AoAFiles = append(s.AoAFiles, file)
+			}
+		}
+	case "aoaParams":
+		aoaParams, err := ParseAoAParams(value)
+		if err != nil {
+			return err
+		}
+		s.AoAParams = aoaParams
+	case "auditReliability":
+		params, err := parseParams(value)
+		if err != nil {
+			return err
+		}
+		reliability, err := common.AuditReliabilityFromParams(params)
+		if err != nil {
+			return err
+		}
+		s.AuditReliability = reliability
+	case "agents":
+		population, err := ParsePopulation(value)
+		if err != nil {
+			return err
+		}
+		s.Population = population
+	default:
+		return fmt.Errorf("unknown scenario value %q", key)
+	}
+	return nil
+}
+
+/*
+* Parse a population of the form "team4=2,base=3". Strategy parameters go in
+* brackets after the name, e.g. "team4=2,liar(lieRate=0.5)=3". A missing count
+* means one agent.
+ */
+func ParsePopulation(spec string) ([]PopulationEntry, error) {
+	population := []PopulationEntry{}
+	for _, field := range splitOutsideBrackets(spec) {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		entry := PopulationEntry{Count: 1}
+		nameAndParams, countStr := field, ""
+		if i := strings.LastIndex(field, "="); i > strings.LastIndex(field, ")") {
+			nameAndParams, countStr = field[:i], field[i+1:]
+		}
+		entry.Agent = nameAndParams
+		if open := strings.Index(nameAndParams, "("); open >= 0 {
+			if !strings.HasSuffix(nameAndParams, ")") {
+				return nil, fmt.Errorf("unclosed parameters in %q", field)
+			}
+			entry.Agent = nameAndParams[:open]
+			params, err := parseParams(nameAndParams[open+1 : len(nameAndParams)-1])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", entry.Agent, err)
+			}
+			entry.Params = params
+		}
+		if countStr != "" {
+			n, err := strconv.Atoi(countStr)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("agent count for %q must be a whole number, got %q", entry.Agent, countStr)
+			}
+			entry.Count = n
+		}
+		population = append(population, entry)
+	}
+	return population, nil
+}
+
+/*
+* Parse AoA parameters of the form "team2(auditDuration=3),fixed(duration=2)",
+* the same way as the parameters of a population.
+ */
+func ParseAoAParams(spec string) (map[string]common.AoAParams, error) {
+	aoaParams := map[string]common.AoAParams{}
+	for _, field := range splitOutsideBrackets(spec) {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		open := strings.Index(field, "(")
+		if open < 0 || !strings.HasSuffix(field, ")") {
+			return nil, fmt.Errorf("AoA parameters %q must be of the form name(key=number,...)", field)
+		}
+		params, err := parseParams(field[open+1 : len(field)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", field[:open], err)
+		}
+		aoaParams[field[:open]] = params
+	}
+	return aoaParams, nil
+}
+
+// Parse "key=value,key=value" into strategy parameters
+func parseParams(spec string) (common.StrategyParams, error) {
+	params := common.StrategyParams{}
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, valueStr, found := strings.Cut(pair, "=")
+		value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64)
+		if !found || err != nil {
+			return nil, fmt.Errorf("parameter %q must be of the form key=number", pair)
+		}
+		params[strings.TrimSpace(key)] = value
+	}
+	return params, nil
+}
+
+// Split on the commas that are not inside brackets
+func splitOutsideBrackets(spec string) []string {
+	fields := []string{}
+	depth, start := 0, 0
+	for i, c := range spec {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				fields = append(fields, spec[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(fields, spec[start:])
+}
+
+// Check the scenario. AoA files it names are read and checked but not
+// registered (see LoadAoAs), so validating a scenario has no side effects.
+func (s Scenario) Validate() error {
+	if s.Iterations <= 0 || s.Turns <= 0 {
+		return fmt.Errorf("scenario needs at least one iteration and one turn")
+	}
+	if s.ThresholdTurns <= 0 {
+		return fmt.Errorf("thresholdTurns must be positive")
+	}
+	if s.TurnTimeoutMs < 0 {
+		return fmt.Errorf("turnTimeoutMs cannot be negative")
+	}
+	found := false
+	for _, policy := range envServer.ThresholdPolicies() {
+		found = found || policy.Name == s.ThresholdPolicy
+	}
+	if !found {
+		return fmt.Errorf("unknown threshold policy %q", s.ThresholdPolicy)
+	}
+	definitions := map[string]common.AoADefinition{}
+	definedIDs := map[int]string{}
+	for _, file := range s.AoAFiles {
+		definition, err := common.LoadAoADefinition(file)
+		if err != nil {
+			return err
+		}
+		if err := common.CheckAoADefinition(definition); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		if name, taken := definedIDs[definition.ID]; taken && name != definition.Name {
+			return fmt.Errorf("%s: AoA %s: ID %d is already taken by %s", file, definition.Name, definition.ID, name)
+		}
+		if previous, taken := definitions[definition.Name]; taken && previous.ID != definition.ID {
+			return fmt.Errorf("%s: AoA %s: the name is already taken", file, definition.Name)
+		}
+		definitions[definition.Name] = definition
+		definedIDs[definition.ID] = definition.Name
+	}
+	for name, params := range s.AoAParams {
+		if definition, ok := definitions[name]; ok {
+			if _, err := definition.ResolveParams(params); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := common.ResolveAoAParams(name, params); err != nil {
+			return err
+		}
+	}
+	if err := s.AuditReliability.Validate(); err != nil {
+		return err
+	}
+	if _, ok := voting.LookupRule(s.VotingRule); s.VotingRule != "" && !ok {
+		return fmt.Errorf("unknown voting rule %q", s.VotingRule)
+	}
+	found = s.Evolution == ""
+	for _, policy := range envServer.EvolutionPolicies() {
+		found = found || policy.Name == s.Evolution
+	}
+	if !found {
+		return fmt.Errorf("unknown evolution policy %q", s.Evolution)
+	}
+	if s.MutationRate < 0 || s.MutationRate > 1 || s.MutationScale < 0 {
+		return fmt.Errorf("mutationRate must be between 0 and 1 and mutationScale cannot be negative")
+	}
+	total := 0
+	for _, entry := range s.Population {
+		if _, err := agents.ResolveStrategyParams(entry.Agent, entry.Params); err != nil {
+			return err
+		}
+		total += entry.Count
+	}
+	if total < 2 {
+		return fmt.Errorf("the population needs at least two agents to form a team")
+	}
+	return nil
+}
+
+// Register the AoAs defined in the scenario's AoA files, so that teams can vote for them
+func (s Scenario) LoadAoAs() error {
+	for _, file := range s.AoAFiles {
+		if _, err := common.LoadAoAFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package simulation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SweepParameter is one axis of a parameter grid, e.g. turns=8,12,16
+type SweepParameter struct {
+	Key    string
+	Values []string
+}
+
+func ParseSweepParameter(spec string) (SweepParameter, error) {
+	key, values, found := strings.Cut(spec, "=")
+	if !found || values == "" {
+		return SweepParameter{}, fmt.Errorf("sweep parameter %q is not of the form key=value1,value2", spec)
+	}
+	// a population contains commas itself, so its values are separated by ';'
+	separator := ","
+	if key == "agents" {
+		separator = ";"
+	}
+	return SweepParameter{Key: key, Values: strings.Split(values, separator)}, nil
+}
+
+/*
+* Expand a base scenario into one scenario per seed. The seeds are consecutive,
+* starting from the base scenario's seed.
+ */
+func BatchScenarios(base Scenario, runs int) []Scenario {
+	scenarios := make([]Scenario, 0, runs)
+	for i := 0; i < runs; i++ {
+		scenario := base
+		scenario.Seed = base.Seed + int64(i)
+		scenario.Name = fmt.Sprintf("%s_seed%d", base.Name, scenario.Seed)
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios
+}
+
+/*
+* Expand a base scenario into every combination of the sweep parameters, with
+* runsPerPoint seeds for each combination.
+ */
+func GridScenarios(base Scenario, parameters []SweepParameter, runsPerPoint int) ([]Scenario, error) {
+	points := []Scenario{base}
+	for _, parameter := range parameters {
+		expanded := []Scenario{}
+		for _, point := range points {
+			for _, value := range parameter.Values {
+				scenario := point
+				if err := scenario.Set(parameter.Key, value); err != nil {
+					return nil, err
+				}
+				scenario.Name = fmt.Sprintf("%s_%s=%s", point.Name, parameter.Key, value)
+				expanded = append(expanded, scenario)
+			}
+		}
+		points = expanded
+	}
+
+	scenarios := []Scenario{}
+	for _, point := range points {
+		if err := point.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", point.Name, err)
+		}
+		scenarios = append(scenarios, BatchScenarios(point, runsPerPoint)...)
+	}
+	return scenarios, nil
+}
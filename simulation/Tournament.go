@@ -0,0 +1,328 @@
+package simulation
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	agents "github.com/ADimoska/SOMASExtended/agents"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+)
+
+/*
+* Tournament mode: strategies play each other head to head (or in larger
+* subsets), with an equal number of agents each, over the same seeds. Every
+* strategy is then ranked by how many of its agents survive, their mean final
+* score, and how much they gave to their teams' common pools.
+ */
+type TournamentConfig struct {
+	Base              Scenario // game settings; the population is replaced for each matchup
+	Strategies        []string // entrants, all registered strategies if empty
+	MatchupSize       int      // strategies per matchup, 2 for head to head
+	AgentsPerStrategy int
+	Games             int // seeded games per matchup, starting from the base scenario's seed
+}
+
+// How one strategy fared, over a matchup or over the whole tournament
+type StrategyStanding struct {
+	Strategy        string
+	SomasTeam       int
+	Games           int
+	Agents          int // agent-games played
+	Survivors       int
+	TotalFinalScore int // dead agents count as zero
+	NetContribution int // contributed to the common pools minus withdrawn from them
+}
+
+func (s StrategyStanding) SurvivalRate() float64 {
+	return ratio(s.Survivors, s.Agents)
+}
+
+func (s StrategyStanding) MeanFinalScore() float64 {
+	return ratio(s.TotalFinalScore, s.Agents)
+}
+
+// Net contribution to team welfare per agent per game
+func (s StrategyStanding) MeanNetContribution() float64 {
+	return ratio(s.NetContribution, s.Agents)
+}
+
+func (s *StrategyStanding) add(other StrategyStanding) {
+	s.Games += other.Games
+	s.Agents += other.Agents
+	s.Survivors += other.Survivors
+	s.TotalFinalScore += other.TotalFinalScore
+	s.NetContribution += other.NetContribution
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+type MatchupResult struct {
+	Strategies []string
+	Standings  map[string]*StrategyStanding
+	Violations []string
+}
+
+type TournamentResult struct {
+	Config      TournamentConfig
+	Matchups    []MatchupResult
+	Leaderboard []StrategyStanding // best first
+	ByTeam      []StrategyStanding // the leaderboard grouped by SOMAS team
+}
+
+func (c TournamentConfig) Validate() error {
+	if len(c.Strategies) < 2 {
+		return fmt.Errorf("a tournament needs at least two strategies")
+	}
+	seen := map[string]bool{}
+	for _, name := range c.Strategies {
+		if _, ok := agents.LookupStrategy(name); !ok {
+			return fmt.Errorf("unknown agent strategy %q (known strategies: %s)", name, strings.Join(agents.StrategyNames(), ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("strategy %q entered twice", name)
+		}
+		seen[name] = true
+	}
+	if c.MatchupSize < 2 || c.MatchupSize > len(c.Strategies) {
+		return fmt.Errorf("matchup size must be between 2 and the number of strategies (%d)", len(c.Strategies))
+	}
+	if c.AgentsPerStrategy <= 0 || c.Games <= 0 {
+		return fmt.Errorf("agents per strategy and games must be positive")
+	}
+	return nil
+}
+
+// Every subset of the entrants of the configured size, in a stable order
+func (c TournamentConfig) Matchups() [][]string {
+	matchups := [][]string{}
+	var choose func(start int, chosen []string)
+	choose = func(start int, chosen []string) {
+		if len(chosen) == c.MatchupSize {
+			matchups = append(matchups, append([]string{}, chosen...))
+			return
+		}
+		for i := start; i < len(c.Strategies); i++ {
+			choose(i+1, append(chosen, c.Strategies[i]))
+		}
+	}
+	choose(0, []string{})
+	return matchups
+}
+
+// The games of one matchup: the base scenario with an equal share for each strategy
+func (c TournamentConfig) MatchupScenarios(matchup []string) []Scenario {
+	base := c.Base
+	base.Name = strings.Join(matchup, "_vs_")
+	base.Population = []PopulationEntry{}
+	for _, name := range matchup {
+		base.Population = append(base.Population, PopulationEntry{Agent: name, Count: c.AgentsPerStrategy})
+	}
+	return BatchScenarios(base, c.Games)
+}
+
+/*
+* Play every matchup and rank the strategies. progress, if set, is called
+* before each game.
+ */
+func RunTournament(config TournamentConfig, progress func(game int, total int, scenario Scenario)) (*TournamentResult, error) {
+	if len(config.Strategies) == 0 {
+		config.Strategies = agents.StrategyNames()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &TournamentResult{Config: config}
+	matchups := config.Matchups()
+	game, total := 0, len(matchups)*config.Games
+	for _, matchup := range matchups {
+		matchupResult := MatchupResult{Strategies: matchup, Standings: map[string]*StrategyStanding{}}
+		for _, name := range matchup {
+			info, _ := agents.LookupStrategy(name)
+			matchupResult.Standings[name] = &StrategyStanding{Strategy: name, SomasTeam: info.SomasTeam}
+		}
+		for _, scenario := range config.MatchupScenarios(matchup) {
+			game++
+			if progress != nil {
+				progress(game, total, scenario)
+			}
+			standings := map[string]StrategyStanding{}
+			run, err := Run(scenario, RunOptions{
+				Subscribe: func(serv *envServer.EnvironmentServer) {
+					attachStandings(serv.Events(), standings)
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			for name, standing := range standings {
+				if sum, ok := matchupResult.Standings[name]; ok {
+					sum.add(standing)
+				}
+			}
+			for _, violation := range run.Violations {
+				matchupResult.Violations = append(matchupResult.Violations, scenario.Name+": "+violation)
+			}
+		}
+		result.Matchups = append(result.Matchups, matchupResult)
+	}
+	result.rank()
+	return result, nil
+}
+
+/*
+* Fill in each strategy's standing in one game. The welfare figures come from
+* the contribution and withdrawal events; who plays which strategy, who
+* survived and the final scores come from the last turn's agent records.
+ */
+func attachStandings(bus *envServer.EventBus, standings map[string]StrategyStanding) {
+	net := map[uuid.UUID]int{}
+	bus.Subscribe(envServer.EventContribution, func(e envServer.Event) {
+		contribution := e.(envServer.ContributionEvent)
+		net[contribution.AgentID] += contribution.ActualContribution
+	})
+	bus.Subscribe(envServer.EventWithdrawal, func(e envServer.Event) {
+		withdrawal := e.(envServer.WithdrawalEvent)
+		net[withdrawal.AgentID] -= withdrawal.ActualWithdrawal
+	})
+	bus.Subscribe(envServer.EventTurnEnd, func(e envServer.Event) {
+		clear(standings)
+		for _, record := range e.(envServer.TurnEndEvent).Agents {
+			standing := standings[record.Strategy]
+			standing.Strategy, standing.SomasTeam, standing.Games = record.Strategy, record.TrueSomasTeamID, 1
+			standing.Agents++
+			if record.IsAlive {
+				standing.Survivors++
+				standing.TotalFinalScore += record.Score
+			}
+			standing.NetContribution += net[record.AgentID]
+			standings[record.Strategy] = standing
+		}
+	})
+}
+
+// Rank by survival rate, then mean final score, then net contribution
+func rankStandings(standings []StrategyStanding) {
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.SurvivalRate() != b.SurvivalRate() {
+			return a.SurvivalRate() > b.SurvivalRate()
+		}
+		if a.MeanFinalScore() != b.MeanFinalScore() {
+			return a.MeanFinalScore() > b.MeanFinalScore()
+		}
+		return a.MeanNetContribution() > b.MeanNetContribution()
+	})
+}
+
+func (r *TournamentResult) rank() {
+	overall := map[string]*StrategyStanding{}
+	teams := map[int]*StrategyStanding{}
+	for _, matchup := range r.Matchups {
+		for name, standing := range matchup.Standings {
+			if overall[name] == nil {
+				overall[name] = &StrategyStanding{Strategy: name, SomasTeam: standing.SomasTeam}
+			}
+			overall[name].add(*standing)
+			if teams[standing.SomasTeam] == nil {
+				teams[standing.SomasTeam] = &StrategyStanding{Strategy: teamName(standing.SomasTeam), SomasTeam: standing.SomasTeam}
+			}
+			teams[standing.SomasTeam].add(*standing)
+		}
+	}
+	r.Leaderboard, r.ByTeam = []StrategyStanding{}, []StrategyStanding{}
+	for _, name := range r.Config.Strategies {
+		r.Leaderboard = append(r.Leaderboard, *overall[name])
+	}
+	for _, standing := range teams {
+		r.ByTeam = append(r.ByTeam, *standing)
+	}
+	sort.Slice(r.ByTeam, func(i, j int) bool { return r.ByTeam[i].SomasTeam < r.ByTeam[j].SomasTeam })
+	rankStandings(r.Leaderboard)
+	rankStandings(r.ByTeam)
+}
+
+func teamName(somasTeam int) string {
+	if somasTeam == 0 {
+		return "shared"
+	}
+	return fmt.Sprintf("team %d", somasTeam)
+}
+
+// Standing of a strategy over the matchups it played against another one
+func (r *TournamentResult) against(strategy string, opponent string) StrategyStanding {
+	standing := StrategyStanding{Strategy: strategy}
+	for _, matchup := range r.Matchups {
+		if mine, ok := matchup.Standings[strategy]; ok && matchup.Standings[opponent] != nil {
+			standing.add(*mine)
+		}
+	}
+	return standing
+}
+
+func (r *TournamentResult) Violations() []string {
+	violations := []string{}
+	for _, matchup := range r.Matchups {
+		violations = append(violations, matchup.Violations...)
+	}
+	return violations
+}
+
+/*
+* Write the leaderboard and the matchup matrix as markdown. A cell of the
+* matrix is the survival rate (and mean final score) of the row's strategy in
+* the games it played against the column's.
+ */
+func WriteTournamentReport(w io.Writer, r *TournamentResult) {
+	fmt.Fprintf(w, "# SOMAS tournament\n\n%d strategies, %d matchups of %d, %d games each, %d agents per strategy\n\n",
+		len(r.Config.Strategies), len(r.Matchups), r.Config.MatchupSize, r.Config.Games, r.Config.AgentsPerStrategy)
+
+	writeLeaderboard := func(title string, standings []StrategyStanding) {
+		fmt.Fprintf(w, "## %s\n\n", title)
+		fmt.Fprintln(w, "| Rank | Strategy | Team | Survival | Mean final score | Net contribution | Agent-games |")
+		fmt.Fprintln(w, "| --- | --- | --- | --- | --- | --- | --- |")
+		for i, s := range standings {
+			fmt.Fprintf(w, "| %d | %s | %s | %.0f%% | %.1f | %.1f | %d |\n",
+				i+1, s.Strategy, teamName(s.SomasTeam), 100*s.SurvivalRate(), s.MeanFinalScore(), s.MeanNetContribution(), s.Agents)
+		}
+		fmt.Fprintln(w)
+	}
+	writeLeaderboard("Leaderboard", r.Leaderboard)
+	writeLeaderboard("By SOMAS team", r.ByTeam)
+
+	order := []string{}
+	for _, standing := range r.Leaderboard {
+		order = append(order, standing.Strategy)
+	}
+	fmt.Fprint(w, "## Matchups\n\nSurvival rate (mean final score) of the row against the column.\n\n")
+	fmt.Fprintf(w, "| | %s |\n", strings.Join(order, " | "))
+	fmt.Fprintf(w, "|---|%s\n", strings.Repeat(" --- |", len(order)))
+	for _, row := range order {
+		cells := []string{}
+		for _, column := range order {
+			standing := r.against(row, column)
+			if row == column || standing.Agents == 0 {
+				cells = append(cells, "-")
+				continue
+			}
+			cells = append(cells, fmt.Sprintf("%.0f%% (%.1f)", 100*standing.SurvivalRate(), standing.MeanFinalScore()))
+		}
+		fmt.Fprintf(w, "| %s | %s |\n", row, strings.Join(cells, " | "))
+	}
+
+	if violations := r.Violations(); len(violations) > 0 {
+		fmt.Fprint(w, "\n## Invariant violations\n\n")
+		for _, violation := range violations {
+			fmt.Fprintf(w, "- %s\n", violation)
+		}
+	}
+}
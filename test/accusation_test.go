@@ -0,0 +1,72 @@
+package main
+
+/*
+* Code to test accusations and the audits they call.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Only agents with enough distinct accusers make the shortlist, most accused first
+func TestWhistleblowerShortlist(t *testing.T) {
+	a, b, x, y := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	accusations := []common.Accusation{
+		{AccuserID: a, AccusedID: y}, {AccuserID: a, AccusedID: x}, {AccuserID: b, AccusedID: x}, {AccuserID: b, AccusedID: x},
+	}
+	assert.Equal(t, []uuid.UUID{x}, common.WhistleblowerTerms{MinAccusers: 2}.Shortlist(accusations))
+	assert.Equal(t, []uuid.UUID{x, y}, common.WhistleblowerTerms{MinAccusers: 1}.Shortlist(accusations))
+	assert.Empty(t, common.WhistleblowerTerms{}.Shortlist(accusations))
+}
+
+/*
+* Two vigilantes accuse an open free-rider, which gets it audited although the
+* AoA never calls audits by vote; the accusations are settled by the verdict.
+ */
+func runAccusationTurn(t *testing.T, infraction string) []envServer.AccusationEvent {
+	serv, team, _ := CreateTeamTestServer(t, "vigilante=2,free-rider=1")
+	for _, agent := range serv.GetAgentMap() {
+		agent.SetTrueScore(20) // so something is expected of everyone whatever they roll
+	}
+	aoa, err := common.AoASpec{
+		Contribution:   common.ComponentSpec{Name: "share", Params: common.AoAParams{"share": 0.5}},
+		Infraction:     common.ComponentSpec{Name: infraction},
+		Verdict:        common.ComponentSpec{Name: "latest"},
+		Whistleblowing: common.WhistleblowerTerms{MinAccusers: 2, Reward: 1, Penalty: 1},
+	}.Build(team)
+	assert.NoError(t, err)
+	team.TeamAoA = aoa
+
+	accusations := []envServer.AccusationEvent{}
+	serv.OnAccusation(func(e envServer.AccusationEvent) { accusations = append(accusations, e) })
+	serv.RunTurn(0, 1)
+	assert.Len(t, serv.GetAuditHistory(team.TeamID), 1)
+	assert.Empty(t, serv.GetAccusations(team.TeamID))
+	return accusations
+}
+
+func TestAccusationsOfCheatsAreRewarded(t *testing.T) {
+	accusations := runAccusationTurn(t, "deviation")
+	assert.Len(t, accusations, 2)
+	for _, accusation := range accusations {
+		assert.Equal(t, envServer.AccusationUpheld, accusation.Outcome)
+		assert.Equal(t, 1, accusation.Reward)
+		assert.NotNil(t, accusation.Evidence)
+		assert.Zero(t, accusation.Evidence.StatedAmount)
+	}
+}
+
+// The free-rider says what it contributed, so under the misreport rule it is honest
+func TestFalseAccusationsArePenalised(t *testing.T) {
+	accusations := runAccusationTurn(t, "misreport")
+	assert.Len(t, accusations, 2)
+	for _, accusation := range accusations {
+		assert.Equal(t, envServer.AccusationFalse, accusation.Outcome)
+		assert.Equal(t, 1, accusation.Penalty)
+	}
+}
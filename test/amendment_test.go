@@ -0,0 +1,98 @@
+package main
+
+/*
+* Code to test amending a team's AoA during an iteration.
+ */
+
+import (
+	"testing"
+
+	agents "github.com/ADimoska/SOMASExtended/agents"
+	common "github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/ADimoska/SOMASExtended/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+// An agent that wants its team to run the AoA with the target ID
+type reformerAgent struct {
+	*agents.ExtendedAgent
+	target int
+}
+
+func (r *reformerAgent) ProposeAoAAmendment(instance common.IExtendedAgent) *common.AoAAmendment {
+	if team := r.Server.GetTeam(r.GetID()); team != nil && team.TeamAoAID != r.target {
+		amendment := &common.AoAAmendment{AoAID: r.target, KeepAuditHistory: true}
+		if r.target == 2 {
+			amendment.Params = common.AoAParams{"auditDuration": 2}
+		}
+		return amendment
+	}
+	return nil
+}
+
+func (r *reformerAgent) VoteOnAoAAmendment(amendment common.AoAAmendment) bool {
+	return amendment.AoAID == r.target
+}
+
+/*
+* A unanimous amendment should pass in the first turn, move the team to the
+* new AoA and keep its common pool; no further amendment is proposed after it
+ */
+func TestAmendmentMovesTeamToNewAoA(t *testing.T) {
+	scenario := simulation.DefaultScenario()
+	scenario.Iterations, scenario.Turns, scenario.TurnTimeoutMs = 1, 3, 10
+	scenario.Population = []simulation.PopulationEntry{{Agent: "test-reformer", Count: 3}}
+
+	amendments := []envServer.AmendmentEvent{}
+	result, err := simulation.Run(scenario, simulation.RunOptions{
+		Subscribe: func(serv *envServer.EnvironmentServer) {
+			serv.OnAmendment(func(e envServer.AmendmentEvent) {
+				assert.Equal(t, e.CommonPool, serv.GetTeamFromTeamID(e.TeamID).GetCommonPool())
+				amendments = append(amendments, e)
+			})
+		},
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, amendments)
+	for _, e := range amendments {
+		assert.True(t, e.Passed)
+		assert.Equal(t, 2, e.ToAoAID)
+		assert.True(t, e.AuditHistoryKept)
+	}
+	assert.Equal(t, len(amendments), result.Summary.AmendmentsPassed)
+	for _, team := range result.TurnRecords[len(result.TurnRecords)-1].TeamRecords {
+		assert.Equal(t, 2, team.AoAID)
+	}
+}
+
+// Members' offences follow them to the new AoA, so sanctions keep escalating
+func TestAmendmentCarriesOffencesOver(t *testing.T) {
+	serv, team, agentIDs := CreateTeamTestServer(t, "test-reformer=3")
+	aoa, err := common.AoASpec{Sanction: common.ComponentSpec{Name: "ladder"}}.Build(team)
+	assert.NoError(t, err)
+	aoa.SetOffences(agentIDs[0], 2)
+	team.TeamAoA, team.TeamAoAID = aoa, 0
+
+	amendments := []envServer.AmendmentEvent{}
+	serv.OnAmendment(func(e envServer.AmendmentEvent) { amendments = append(amendments, e) })
+	serv.RunTurn(0, 1)
+
+	assert.Len(t, amendments, 1)
+	assert.True(t, amendments[0].OffencesKept)
+	assert.Equal(t, 2, team.TeamAoAID)
+	assert.Equal(t, 2, team.TeamAoA.(common.OffenceKeeper).GetOffences(agentIDs[0]))
+}
+
+// A proposal for an AoA that is not registered never reaches a vote
+func TestAmendmentToUnregisteredAoAIsRejected(t *testing.T) {
+	serv, team, _ := CreateTeamTestServer(t, "test-bad-reformer=3")
+	aoaID := team.TeamAoAID
+
+	amendments := []envServer.AmendmentEvent{}
+	serv.OnAmendment(func(e envServer.AmendmentEvent) { amendments = append(amendments, e) })
+	serv.RunTurn(0, 1)
+
+	assert.Empty(t, amendments)
+	assert.Equal(t, aoaID, team.TeamAoAID)
+}
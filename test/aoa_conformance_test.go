@@ -0,0 +1,56 @@
+package main
+
+/*
+* Code to run every registered AoA through the conformance harness.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/aoatest"
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Every registered AoA should keep the AoA contract
+func TestEveryRegisteredAoAConforms(t *testing.T) {
+	for name, violations := range aoatest.CheckRegistered(aoatest.DefaultConfig()) {
+		for _, violation := range violations {
+			t.Errorf("AoA %s: %s", name, violation)
+		}
+	}
+}
+
+// An AoA that audits whoever the first vote names, member or not
+type outsiderAuditAoA struct {
+	common.IArticlesOfAssociation
+}
+
+func (o outsiderAuditAoA) GetVoteResult(ballot common.BallotResult) uuid.UUID {
+	return uuid.New()
+}
+
+// The harness should catch an AoA that breaks the contract, and say where
+func TestConformanceCatchesBrokenAoA(t *testing.T) {
+	violations := aoatest.Check(func(team *common.Team) common.IArticlesOfAssociation {
+		return outsiderAuditAoA{common.CreateFixedAoA(1)}
+	}, aoatest.DefaultConfig())
+	assert.NotEmpty(t, violations)
+	assert.Equal(t, "GetVoteResult", violations[0].Call)
+	assert.Equal(t, "GetVoteResult", violations[0].Sequence[len(violations[0].Sequence)-1])
+}
+
+// Random seeds for the harness; `go test -fuzz FuzzAoAContract ./test` explores further
+func FuzzAoAContract(f *testing.F) {
+	f.Add(int64(1), uint8(4))
+	f.Add(int64(42), uint8(2))
+	f.Fuzz(func(t *testing.T, seed int64, members uint8) {
+		config := aoatest.Config{Seed: seed, Sequences: 1, Steps: 100, MaxMembers: 2 + int(members%10)}
+		for name, violations := range aoatest.CheckRegistered(config) {
+			for _, violation := range violations {
+				t.Errorf("AoA %s: %s", name, violation)
+			}
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+/*
+* Code to test AoAs defined in JSON or YAML.
+ */
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/ADimoska/SOMASExtended/simulation"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const needsAoA = `
+id: 20
+name: test-needs
+description: contribute 75%, withdraw by need
+contribution: {rule: share, share: 0.75}
+withdrawal: {rule: need, alpha: 0.7}
+auditTrigger: {rule: majority, share: 0.5}
+auditCost: {rule: pool-share, share: 0.05}
+verdict: ever
+sanction: {rule: strikes, strikes: 3}
+`
+
+/*
+* A YAML definition should register an AoA that teams can run, with its rule
+* parameters open to overrides; the poorest member is topped up first
+ */
+func TestAoADefinitionFromYAML(t *testing.T) {
+	definition, err := common.ParseAoADefinition([]byte(needsAoA), "yaml")
+	assert.NoError(t, err)
+	info, err := common.DefineAoA(definition)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.7, info.Params["withdrawal.alpha"])
+
+	// defining the same AoA again is harmless
+	_, err = common.DefineAoA(definition)
+	assert.NoError(t, err)
+
+	team := common.NewTeam(uuid.New())
+	poor, rich := uuid.New(), uuid.New()
+	team.Agents = []uuid.UUID{poor, rich}
+	aoa, id := common.CreateAoA(20, team, common.AoAParams{"contribution.share": 0.5})
+	assert.Equal(t, 20, id)
+	assert.Equal(t, 5, aoa.GetExpectedContribution(poor, 10))
+
+	aoa.SetContributionAuditResult(poor, 4, 2, 2)
+	aoa.SetContributionAuditResult(rich, 40, 20, 20)
+	assert.Equal(t, 2, aoa.GetAuditCost(40))
+	assert.Equal(t, poor, aoa.GetVoteResult(auditBallot(team.Agents, poor, poor)))
+	assert.Greater(t, aoa.GetExpectedWithdrawal(poor, 2, 10), aoa.GetExpectedWithdrawal(rich, 20, 10))
+}
+
+// Typos in a definition should be reported rather than ignored
+func TestAoADefinitionRejectsMistakes(t *testing.T) {
+	_, err := common.ParseAoADefinition([]byte(`{"id": 21, "name": "typo", "contributon": "all"}`), "json")
+	assert.Error(t, err)
+	_, err = common.ParseAoADefinition([]byte(`{"id": 21, "name": "typo", "verdict": {"rule": "ever", "turns": 3}}`), "json")
+	assert.Error(t, err)
+	_, err = common.ParseAoADefinition([]byte("id: 21\nname: typo\nsanction: {strikes: 3}\n"), "yaml")
+	assert.Error(t, err)
+
+	definition, err := common.ParseAoADefinition([]byte(`{"id": 0, "name": "taken"}`), "json")
+	assert.NoError(t, err)
+	_, err = common.DefineAoA(definition)
+	assert.Error(t, err)
+}
+
+/*
+* Validating a scenario only reads its AoA files; the AoAs are registered when
+* a server is built from it, however often it was validated before
+ */
+// Runs of TestScenarioValidationDoesNotRegisterAoAs, so that each defines an AoA of its own
+var lenientRuns int
+
+func TestScenarioValidationDoesNotRegisterAoAs(t *testing.T) {
+	lenientRuns++
+	id, name := 200+lenientRuns, fmt.Sprintf("test-lenient-%d", lenientRuns)
+	path := filepath.Join(t.TempDir(), "lenient.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf("id: %d\nname: %s\ncontribution: {rule: share, share: 0.25}\n", id, name)), 0644))
+	scenario := simulation.DefaultScenario()
+	scenario.AoAFiles = []string{path}
+	scenario.AoAParams = map[string]common.AoAParams{name: {"contribution.share": 0.5}}
+
+	assert.NoError(t, scenario.Validate())
+	assert.NoError(t, scenario.Validate())
+	_, registered := common.LookupAoAByName(name)
+	assert.False(t, registered)
+
+	_, err := simulation.NewServer(scenario)
+	assert.NoError(t, err)
+	_, registered = common.LookupAoAByName(name)
+	assert.True(t, registered)
+	assert.NoError(t, scenario.Validate())
+	_, err = simulation.NewServer(scenario)
+	assert.NoError(t, err)
+
+	// a file clashing with a registered AoA is still caught by validation
+	clash := filepath.Join(t.TempDir(), "clash.yaml")
+	assert.NoError(t, os.WriteFile(clash, []byte(fmt.Sprintf("id: %d\nname: test-clash\n", id)), 0644))
+	scenario.AoAFiles = []string{clash}
+	scenario.AoAParams = nil
+	assert.Error(t, scenario.Validate())
+}
@@ -0,0 +1,50 @@
+package main
+
+/*
+* Code to test that every AoA copes with its team's membership changing.
+ */
+
+import (
+	"testing"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// Play the AoA's side of a turn for every member of the team
+func playAoATurn(aoa common.IArticlesOfAssociation, team *common.Team) {
+	for _, agentID := range team.Agents {
+		expected := aoa.GetExpectedContribution(agentID, 10)
+		aoa.SetContributionAuditResult(agentID, 10, expected, expected)
+	}
+	aoa.GetVoteResult(auditBallot(team.Agents, team.Agents...))
+	for _, agentID := range aoa.GetWithdrawalOrder(team.Agents) {
+		expected := aoa.GetExpectedWithdrawal(agentID, 5, 30)
+		aoa.SetWithdrawalAuditResult(agentID, 5, expected, expected, 30)
+	}
+}
+
+/*
+* An orphan admitted after the team chose its AoA should be able to play a
+* full turn under every registered AoA, and so should the team after one of
+* its members dies
+ */
+func TestEveryAoAAdmitsOrphansMidIteration(t *testing.T) {
+	for _, info := range common.AoAs() {
+		serv, agentIDs := CreateScenarioTestServer(t, "base=4")
+		teamID := serv.CreateAndInitTeamWithAgents(agentIDs[:3])
+		team := serv.GetTeamFromTeamID(teamID)
+		team.TeamAoA, team.TeamAoAID = common.CreateAoA(info.ID, team, nil)
+		assert.NotPanics(t, func() { playAoATurn(team.TeamAoA, team) }, info.Name)
+
+		orphan := agentIDs[3]
+		serv.GetAgentMap()[orphan].SetTeamID(teamID)
+		serv.AddAgentToTeam(orphan, teamID)
+		assert.Contains(t, team.Agents, orphan)
+		assert.NotPanics(t, func() { playAoATurn(team.TeamAoA, team) }, info.Name)
+
+		team.Agents = team.Agents[1:]
+		team.TeamAoA.OnMemberDied(agentIDs[0])
+		assert.NotPanics(t, func() { playAoATurn(team.TeamAoA, team) }, info.Name)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+/*
+* Code to test building AoAs from the registry.
+ */
+
+import (
+	"testing"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+* A team should record the AoA it actually runs: the one it voted for if it is
+* registered, the default one otherwise
+ */
+func TestCreateAoAFromRegistry(t *testing.T) {
+	team := common.NewTeam(uuid.New())
+
+	aoa, id := common.CreateAoA(2, team, common.AoAParams{"auditDuration": 3})
+	assert.Equal(t, 2, id)
+	assert.IsType(t, &common.Team2AoA{}, aoa)
+
+	aoa, id = common.CreateAoA(4, team, nil)
+	assert.Equal(t, common.DefaultAoAID, id)
+	assert.IsType(t, &common.FixedAoA{}, aoa)
+
+	_, err := common.ResolveAoAParams("team2", common.AoAParams{"duration": 3})
+	assert.Error(t, err)
+}
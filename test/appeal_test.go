@@ -0,0 +1,91 @@
+package main
+
+/*
+* Code to test appeals against audit verdicts.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// A composed AoA that convicts one agent at the first contribution audit
+type convictingAoA struct {
+	*common.ComposableAoA
+	target  uuid.UUID
+	audited bool
+}
+
+func (c *convictingAoA) GetVoteResult(ballot common.BallotResult) uuid.UUID {
+	if c.audited {
+		return uuid.Nil
+	}
+	c.audited = true
+	return c.target
+}
+
+func (c *convictingAoA) GetContributionAuditResult(agentId uuid.UUID) bool {
+	return agentId == c.target
+}
+
+func newAppealTestServer(t *testing.T, spec common.AoASpec) (*envServer.EnvironmentServer, *common.Team, uuid.UUID, *[]envServer.AppealEvent) {
+	serv, team, _ := CreateTeamTestServer(t, "base=3")
+	aoa, err := spec.Build(team)
+	assert.NoError(t, err)
+	target := team.Agents[0]
+	team.TeamAoA = &convictingAoA{ComposableAoA: aoa, target: target}
+	appeals := []envServer.AppealEvent{}
+	serv.OnAppeal(func(e envServer.AppealEvent) { appeals = append(appeals, e) })
+	return serv, team, target, &appeals
+}
+
+// A jury gives a first offender the benefit of the doubt, which undoes the expulsion
+func TestJuryOverturnsExpulsion(t *testing.T) {
+	serv, team, target, appeals := newAppealTestServer(t, common.AoASpec{
+		Sanction: common.ComponentSpec{Name: "strikes", Params: common.AoAParams{"strikes": 1}},
+		Appeal:   common.AppealTerms{Method: common.AppealByJury},
+	})
+
+	serv.RunTurn(0, 1)
+	assert.Len(t, *appeals, 1)
+	assert.True(t, (*appeals)[0].Overturned)
+	assert.True(t, (*appeals)[0].Readmitted)
+	assert.Contains(t, team.Agents, target)
+	assert.Equal(t, team.TeamID, serv.GetAgentMap()[target].GetTeamID())
+	assert.Equal(t, 0, team.TeamAoA.(*convictingAoA).GetOffences(target))
+
+	history := serv.GetAuditHistory(team.TeamID)
+	assert.Len(t, history, 1)
+	assert.True(t, history[0].Appealed)
+	assert.True(t, history[0].Overturned)
+}
+
+// A second audit of a correct verdict upholds it, and the appellant loses its fee
+func TestAuditAppealUpholdsCorrectVerdict(t *testing.T) {
+	serv, team, target, appeals := newAppealTestServer(t, common.AoASpec{
+		Contribution: common.ComponentSpec{Name: "share", Params: common.AoAParams{"share": 0.5}},
+		Sanction:     common.ComponentSpec{Name: "fine", Params: common.AoAParams{"amount": 5}},
+		Appeal:       common.AppealTerms{Method: common.AppealByAudit, Fee: 1, CostFactor: 3},
+	})
+	serv.GetAgentMap()[target].SetTrueScore(50)
+
+	serv.RunTurn(0, 1)
+	assert.Len(t, *appeals, 1)
+	appeal := (*appeals)[0]
+	assert.False(t, appeal.Overturned)
+	assert.Equal(t, 1, appeal.Fee)
+	assert.Equal(t, 3, appeal.Cost)
+	assert.Zero(t, appeal.Refund)
+	assert.Equal(t, 1, team.TeamAoA.(*convictingAoA).GetOffences(target))
+}
+
+// Unknown appeal methods and impossible majorities are rejected
+func TestAppealTermsAreValidated(t *testing.T) {
+	assert.Error(t, common.AoASpec{Appeal: common.AppealTerms{Method: "duel"}}.Validate())
+	assert.Error(t, common.AoASpec{Appeal: common.AppealTerms{Method: common.AppealByJury, Majority: 2}}.Validate())
+	assert.NoError(t, common.AoASpec{Appeal: common.AppealTerms{Method: common.AppealByAudit, Fee: 2}}.Validate())
+}
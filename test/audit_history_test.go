@@ -0,0 +1,85 @@
+package main
+
+/*
+* Code to test the audit history teams keep and share with their members.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// An agent that keeps every audit verdict it is sent
+type verdictListener struct {
+	common.IExtendedAgent
+	verdicts []common.AuditEntry
+}
+
+func (v *verdictListener) HandleAuditVerdictMessage(msg *common.AuditVerdictMessage) {
+	v.verdicts = append(v.verdicts, msg.Entry)
+}
+
+// Audits should be kept in the team's history and sent to its members as they happen
+func TestAuditHistoryIsSharedWithTeam(t *testing.T) {
+	serv, team, agentIDs := CreateTeamTestServer(t, "vigilante=2,free-rider=1")
+	teamID := team.TeamID
+	listeners := []*verdictListener{}
+	for _, agent := range serv.GetAgentMap() {
+		listener := &verdictListener{IExtendedAgent: agent}
+		listeners = append(listeners, listener)
+		serv.AddAgent(listener)
+	}
+	info, _ := common.LookupAoAByName("commons")
+	team.TeamAoA, team.TeamAoAID = common.CreateAoA(info.ID, team, nil)
+
+	for turn := 1; turn <= 4; turn++ {
+		serv.RunTurn(0, turn)
+	}
+
+	history := serv.GetAuditHistory(teamID)
+	assert.NotEmpty(t, history)
+	for _, entry := range history {
+		assert.Equal(t, teamID, entry.TeamID)
+		assert.Contains(t, agentIDs, entry.AgentID)
+		assert.NotEmpty(t, entry.Voters)
+		assert.NotContains(t, entry.Voters, uuid.Nil)
+	}
+	for _, listener := range listeners {
+		if listener.GetTeamID() == teamID {
+			assert.Equal(t, history, listener.verdicts)
+		}
+	}
+	assert.Empty(t, serv.GetAuditHistory(uuid.New()))
+}
+
+// The cost of an audit comes out of the common pool and is recorded with it
+func TestAuditCostIsPaidFromPool(t *testing.T) {
+	serv, team, _ := CreateTeamTestServer(t, "vigilante=3")
+	teamID := team.TeamID
+	for _, agent := range serv.GetAgentMap() {
+		agent.SetTrueScore(20)
+	}
+	aoa, err := common.AoASpec{
+		AuditTrigger: common.ComponentSpec{Name: "plurality"},
+		AuditCost:    common.ComponentSpec{Name: "fixed", Params: common.AoAParams{"cost": 3}},
+	}.Build(team)
+	assert.NoError(t, err)
+	team.TeamAoA = aoa
+
+	pools := map[string]int{}
+	serv.OnPhase(func(e envServer.PhaseEvent) {
+		if e.TeamID == teamID {
+			pools[e.Phase] = serv.GetTeamFromTeamID(teamID).GetCommonPool()
+		}
+	})
+	serv.RunTurn(0, 1)
+
+	history := serv.GetAuditHistory(teamID)
+	assert.NotEmpty(t, history)
+	assert.Equal(t, 3, history[0].Cost)
+	assert.Equal(t, pools[envServer.PhaseContributionAudit]-3, pools[envServer.PhaseGovernance])
+}
@@ -69,3 +69,41 @@ func TestIncrementLastRecord(t *testing.T) {
 		t.Errorf("expected 2 infractions, got %d", infractions)
 	}
 }
+
+// Warnings count the failed audits still in the queue
+func TestAuditQueueWarnings(t *testing.T) {
+	queue := common.NewAuditQueue(2)
+	queue.AddToQueue(true)
+	queue.AddToQueue(false)
+	queue.AddToQueue(true)
+
+	if warnings := queue.GetWarnings(); warnings != 1 {
+		t.Errorf("expected %d warnings, got %d", 1, warnings)
+	}
+}
+
+// Error rates shrink with the effort spent on an audit, and the zero value is a perfect audit
+func TestAuditReliability(t *testing.T) {
+	perfect := common.AuditReliability{}
+	for i := 0; i < 10; i++ {
+		if !perfect.Verdict(true, 0, 1) || perfect.Verdict(false, 0, 1) {
+			t.Fatalf("a perfect audit should find exactly what the records show")
+		}
+	}
+
+	noisy := common.AuditReliability{Miss: 0.4, FalsePositive: 0.2, HalfEffort: 10, TurnEffort: 5}
+	detection, falsePositive := noisy.Rates(5, 1)
+	if detection != 0.8 || falsePositive != 0.1 {
+		t.Errorf("expected rates 0.8 and 0.1 with half effort, got %v and %v", detection, falsePositive)
+	}
+	if _, fp := noisy.Rates(5, 3); fp >= falsePositive {
+		t.Errorf("a longer audit should convict fewer honest agents")
+	}
+
+	if _, err := common.AuditReliabilityFromParams(common.StrategyParams{"miss": 1.5}); err == nil {
+		t.Errorf("a miss rate above 1 should be rejected")
+	}
+	if _, err := common.AuditReliabilityFromParams(common.StrategyParams{"noise": 0.1}); err == nil {
+		t.Errorf("unknown parameters should be rejected")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+/*
+* Code to test the ballots teams hold through the server.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func ballotResult(results []common.BallotResult, question string) (common.BallotResult, bool) {
+	for _, result := range results {
+		if result.Question == question {
+			return result, true
+		}
+	}
+	return common.BallotResult{}, false
+}
+
+/*
+* The result of an audit ballot among agentIDs, counted under plurality. Each
+* vote names the agent it is for, uuid.Nil for no audit.
+ */
+func auditBallot(agentIDs []uuid.UUID, votes ...uuid.UUID) common.BallotResult {
+	ballot := common.BallotResult{
+		BallotSpec: common.BallotSpec{Options: []string{common.NoAudit}},
+		Scores:     map[int]float64{0: 0},
+		Eligible:   len(votes),
+		Turnout:    len(votes),
+	}
+	for i, agentID := range agentIDs {
+		ballot.Options = append(ballot.Options, agentID.String())
+		ballot.Scores[i+1] = 0
+	}
+	for _, vote := range votes {
+		for i, option := range ballot.Options {
+			if option == vote.String() || (i == 0 && vote == uuid.Nil) {
+				ballot.Scores[i]++
+			}
+		}
+	}
+	return ballot
+}
+
+// Ballots opened by agents close at the end of their last turn, counting weights and quorum
+func TestBallotsCloseWithWeightsAndQuorum(t *testing.T) {
+	serv, team, agentIDs := CreateTeamTestServer(t, "base=3")
+	teamID := team.TeamID
+	a, b, c := agentIDs[0], agentIDs[1], agentIDs[2]
+
+	weighted, err := serv.OpenBallot(common.BallotSpec{TeamID: teamID, Question: "weighted", Options: []string{"x", "y"}, Weights: map[uuid.UUID]int{a: 3}})
+	assert.NoError(t, err)
+	assert.NoError(t, serv.CastBallot(weighted, a, []int{1}))
+	assert.NoError(t, serv.CastBallot(weighted, b, []int{0}))
+	assert.NoError(t, serv.CastBallot(weighted, c, []int{0, 1}))
+	assert.Error(t, serv.CastBallot(weighted, c, []int{2}))
+	assert.Error(t, serv.CastBallot(weighted, uuid.New(), []int{0}))
+
+	secret, err := serv.OpenBallot(common.BallotSpec{TeamID: teamID, Question: "secret", Options: []string{"x", "y"}, Quorum: 1, Secret: true, Turns: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, serv.CastBallot(secret, a, []int{0}))
+	assert.Nil(t, serv.GetOpenBallots(teamID)[1].Votes)
+
+	_, err = serv.OpenBallot(common.BallotSpec{TeamID: teamID, Question: "bad", Options: []string{"x"}, Rule: "dictator"})
+	assert.Error(t, err)
+
+	serv.RunTurn(0, 0)
+	result, ok := ballotResult(serv.GetBallotResults(teamID), "weighted")
+	assert.True(t, ok)
+	assert.Equal(t, "y", result.Chosen())
+	assert.Equal(t, 5, result.Eligible)
+	assert.Equal(t, 5, result.Turnout)
+	_, ok = ballotResult(serv.GetBallotResults(teamID), "secret")
+	assert.False(t, ok, "the secret ballot should stay open for another turn")
+
+	serv.RunTurn(0, 1)
+	result, ok = ballotResult(serv.GetBallotResults(teamID), "secret")
+	assert.True(t, ok)
+	assert.Equal(t, -1, result.Winner, "one of three voters is short of the quorum")
+	assert.Nil(t, result.Votes)
+	assert.Empty(t, serv.GetOpenBallots(teamID))
+}
+
+// An AoA that audits nobody, counts its leader's audit vote twice and keeps the audit ballots it is given
+type ballotKeepingAoA struct {
+	common.IArticlesOfAssociation
+	leader  uuid.UUID
+	ballots []common.BallotResult
+}
+
+func (b *ballotKeepingAoA) GetVoteResult(ballot common.BallotResult) uuid.UUID {
+	b.ballots = append(b.ballots, ballot)
+	return uuid.Nil
+}
+
+func (b *ballotKeepingAoA) GetAuditVoteWeights() map[uuid.UUID]int {
+	return map[uuid.UUID]int{b.leader: 2}
+}
+
+// The AoA decides who to audit from the audit ballot the server counted, weights and all
+func TestAuditVotesAreCountedByBallot(t *testing.T) {
+	serv, team, agentIDs := CreateTeamTestServer(t, "vigilante=3")
+	aoa := &ballotKeepingAoA{IArticlesOfAssociation: common.CreateFixedAoA(1), leader: agentIDs[0]}
+	team.TeamAoA = aoa
+
+	serv.RunTurn(0, 0)
+	assert.Len(t, aoa.ballots, 2)
+	recorded, ok := ballotResult(serv.GetBallotResults(team.TeamID), "contribution audit")
+	assert.True(t, ok)
+	ballot := aoa.ballots[0]
+	assert.Equal(t, recorded.BallotID, ballot.BallotID)
+	assert.Equal(t, []string{common.NoAudit, agentIDs[0].String(), agentIDs[1].String(), agentIDs[2].String()}, ballot.Options)
+	assert.Equal(t, 4, ballot.Turnout, "the leader's vote counts twice")
+	assert.Equal(t, 0.0, ballot.Scores[0], "vigilantes always vote for an audit")
+	assert.Equal(t, 4.0, ballot.Scores[1]+ballot.Scores[2]+ballot.Scores[3])
+	_, votes := ballot.MostAuditVotes()
+	assert.GreaterOrEqual(t, votes, 2.0)
+}
@@ -0,0 +1,151 @@
+package main
+
+/*
+* Code to test the baseline agents.
+ */
+
+import (
+	"testing"
+
+	agents "github.com/ADimoska/SOMASExtended/agents"
+	common "github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+* A liar should state the expected contribution while paying less, and give
+* the same answers every time it is asked in a turn
+ */
+func TestLiarStatesMoreThanItContributes(t *testing.T) {
+	serv, agentIDs := CreateTestServer()
+	liar, err := agents.CreateAgent("liar", serv, agents.AgentConfig{InitScore: 20}, common.StrategyParams{"share": 0.25})
+	assert.NoError(t, err)
+	serv.AddAgent(liar)
+	serv.CreateAndInitTeamWithAgents([]uuid.UUID{liar.GetID(), agentIDs[0]})
+
+	// the fixed AoA expects the whole score to be contributed
+	actual := liar.GetActualContribution(liar)
+	assert.Equal(t, 5, actual)
+	assert.Equal(t, 20, liar.GetStatedContribution(liar))
+
+	// the decision holds for the rest of the turn, even once the score has changed
+	liar.SetTrueScore(20 - actual)
+	assert.Equal(t, actual, liar.GetActualContribution(liar))
+	assert.Equal(t, 20, liar.GetStatedContribution(liar))
+
+	honest, err := agents.CreateAgent("honest", serv, agents.AgentConfig{InitScore: 12}, nil)
+	assert.NoError(t, err)
+	serv.AddAgent(honest)
+	serv.AddAgentToTeam(honest.GetID(), liar.GetTeamID())
+	honest.SetTeamID(liar.GetTeamID())
+	assert.Equal(t, 12, honest.GetActualContribution(honest))
+	assert.Equal(t, honest.GetActualContribution(honest), honest.GetStatedContribution(honest))
+}
+
+// Create a baseline agent of the named strategy and add it to the server
+func addBaselineAgent(t *testing.T, serv *envServer.EnvironmentServer, name string, score int, params common.StrategyParams) *agents.BaselineAgent {
+	agent, err := agents.CreateAgent(name, serv, agents.AgentConfig{InitScore: score}, params)
+	assert.NoError(t, err)
+	serv.AddAgent(agent)
+	return agent.(*agents.BaselineAgent)
+}
+
+// A free-rider pays only its share of what is expected, withdraws in full and says so
+func TestFreeRiderContributesItsShare(t *testing.T) {
+	serv, _ := CreateTestServer()
+	freeRider := addBaselineAgent(t, serv, "free-rider", 20, nil)
+	halfRider := addBaselineAgent(t, serv, "free-rider", 20, common.StrategyParams{"share": 0.5})
+	team := serv.GetTeamFromTeamID(serv.CreateAndInitTeamWithAgents([]uuid.UUID{freeRider.GetID(), halfRider.GetID()}))
+	team.SetCommonPool(10)
+
+	assert.Equal(t, 0, freeRider.GetActualContribution(freeRider))
+	assert.Equal(t, 0, freeRider.GetStatedContribution(freeRider))
+	assert.Equal(t, 10, halfRider.GetActualContribution(halfRider))
+	assert.Equal(t, 10, halfRider.GetStatedContribution(halfRider))
+	// the fixed AoA expects a withdrawal of 2
+	assert.Equal(t, 2, freeRider.GetActualWithdrawal(freeRider))
+	assert.Equal(t, 2, freeRider.GetStatedWithdrawal(freeRider))
+}
+
+// A greedy agent withdraws greed times what is expected, as far as the pool allows
+func TestGreedyWithdrawsMoreThanExpected(t *testing.T) {
+	serv, _ := CreateTestServer()
+	greedy := addBaselineAgent(t, serv, "greedy", 20, nil)
+	greedier := addBaselineAgent(t, serv, "greedy", 20, common.StrategyParams{"greed": 10})
+	team := serv.GetTeamFromTeamID(serv.CreateAndInitTeamWithAgents([]uuid.UUID{greedy.GetID(), greedier.GetID()}))
+	team.SetCommonPool(10)
+
+	assert.Equal(t, 20, greedy.GetActualContribution(greedy))
+	assert.Equal(t, 6, greedy.GetActualWithdrawal(greedy))
+	assert.Equal(t, 6, greedy.GetStatedWithdrawal(greedy))
+	assert.Equal(t, 10, greedier.GetActualWithdrawal(greedier))
+}
+
+// Tit-for-tat cooperates until its teammates defect, then pays back as much as they do
+func TestTitForTatRetaliates(t *testing.T) {
+	serv, _ := CreateTestServer()
+	trusting := addBaselineAgent(t, serv, "tit-for-tat", 20, common.StrategyParams{"forgiveness": 0})
+	retaliating := addBaselineAgent(t, serv, "tit-for-tat", 20, common.StrategyParams{"forgiveness": 0})
+	forgiving := addBaselineAgent(t, serv, "tit-for-tat", 20, common.StrategyParams{"forgiveness": 1})
+	defector := addBaselineAgent(t, serv, "free-rider", 20, nil)
+	serv.CreateAndInitTeamWithAgents([]uuid.UUID{trusting.GetID(), retaliating.GetID(), forgiving.GetID(), defector.GetID()})
+
+	assert.Equal(t, 20, trusting.GetActualContribution(trusting))
+	// the defector stated a quarter of what was expected of it
+	for _, agent := range []*agents.BaselineAgent{retaliating, forgiving} {
+		agent.Memory.ObserveContribution(defector.GetID(), 5, 20)
+	}
+	assert.Equal(t, 5, retaliating.GetActualContribution(retaliating))
+	assert.Equal(t, 20, forgiving.GetActualContribution(forgiving))
+}
+
+// A vigilante votes to audit the most suspicious teammate and accuses open under-contributors
+func TestVigilanteTargetsSuspects(t *testing.T) {
+	serv, _ := CreateTestServer()
+	vigilante := addBaselineAgent(t, serv, "vigilante", 20, nil)
+	idle := addBaselineAgent(t, serv, "vigilante", 20, common.StrategyParams{"auditRate": 0})
+	shirker := addBaselineAgent(t, serv, "honest", 20, nil)
+	taker := addBaselineAgent(t, serv, "honest", 20, nil)
+	teamID := serv.CreateAndInitTeamWithAgents([]uuid.UUID{vigilante.GetID(), idle.GetID(), shirker.GetID(), taker.GetID()})
+
+	vigilante.Memory.ObserveContribution(shirker.GetID(), 2, 10)
+	vigilante.Memory.ObserveContribution(taker.GetID(), 10, 10)
+	vigilante.Memory.ObserveWithdrawal(shirker.GetID(), 1, 2)
+	vigilante.Memory.ObserveWithdrawal(taker.GetID(), 9, 2)
+	contributionVote := vigilante.GetContributionAuditVote()
+	assert.Equal(t, 1, contributionVote.IsVote)
+	assert.Equal(t, shirker.GetID(), contributionVote.VotedForID)
+	assert.Equal(t, 3, contributionVote.AuditDuration)
+	assert.Equal(t, taker.GetID(), vigilante.GetWithdrawalAuditVote().VotedForID)
+	assert.Equal(t, 0, idle.GetContributionAuditVote().IsVote)
+
+	// stating all that was expected is no reason to accuse, stating less is
+	msg := taker.CreateContributionMessage(10)
+	msg.ExpectedAmount = 10
+	vigilante.HandleContributionMessage(msg)
+	assert.Empty(t, serv.GetAccusations(teamID))
+	msg = shirker.CreateContributionMessage(2)
+	msg.ExpectedAmount = 10
+	vigilante.HandleContributionMessage(msg)
+	accusations := serv.GetAccusations(teamID)
+	assert.Len(t, accusations, 1)
+	assert.Equal(t, shirker.GetID(), accusations[0].AccusedID)
+	assert.Equal(t, common.ContributionAudit, accusations[0].Phase)
+}
+
+// A survivor contributes only its surplus above threshold+margin and withdraws to make up a shortfall
+func TestSurvivorKeepsAboveThreshold(t *testing.T) {
+	serv, _ := CreateTestServer()
+	rich := addBaselineAgent(t, serv, "survivor", 25, nil)
+	poor := addBaselineAgent(t, serv, "survivor", 15, nil)
+	team := serv.GetTeamFromTeamID(serv.CreateAndInitTeamWithAgents([]uuid.UUID{rich.GetID(), poor.GetID()}))
+	team.SetCommonPool(20)
+
+	// 19 + 2 is safe, so of its 25 the rich one can spare 4
+	assert.Equal(t, 4, rich.GetActualContribution(rich))
+	assert.Equal(t, 2, rich.GetActualWithdrawal(rich))
+	assert.Equal(t, 0, poor.GetActualContribution(poor))
+	assert.Equal(t, 2+6, poor.GetActualWithdrawal(poor))
+}
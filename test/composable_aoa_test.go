@@ -0,0 +1,55 @@
+package main
+
+/*
+* Code to test AoAs composed from stock rules.
+ */
+
+import (
+	"testing"
+
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+* A composed AoA should follow each of its rules: here a half contribution,
+* plurality-called audits, the latest turn judged, and expulsion at the
+* second failed audit
+ */
+func TestComposableAoAFollowsItsRules(t *testing.T) {
+	team := common.NewTeam(uuid.New())
+	cheat, honest := uuid.New(), uuid.New()
+	team.Agents = []uuid.UUID{cheat, honest}
+
+	aoa, err := common.AoASpec{
+		Contribution: common.ComponentSpec{Name: "share", Params: common.AoAParams{"share": 0.5}},
+		AuditTrigger: common.ComponentSpec{Name: "plurality"},
+		Infraction:   common.ComponentSpec{Name: "overstate"},
+		Verdict:      common.ComponentSpec{Name: "latest"},
+		Sanction:     common.ComponentSpec{Name: "strikes", Params: common.AoAParams{"strikes": 2}},
+	}.Build(team)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 5, aoa.GetExpectedContribution(cheat, 10))
+	assert.Equal(t, 2, aoa.GetExpectedWithdrawal(cheat, 10, 100))
+	assert.Equal(t, common.DefaultAmendmentMajority, aoa.GetAmendmentMajority())
+	assert.Equal(t, uuid.Nil, aoa.GetVoteResult(auditBallot(team.Agents, uuid.Nil)))
+	assert.Equal(t, cheat, aoa.GetVoteResult(auditBallot(team.Agents, cheat)))
+
+	for turn := 1; turn <= 2; turn++ {
+		aoa.SetContributionAuditResult(cheat, 10, 0, 5)
+		aoa.SetContributionAuditResult(honest, 10, 5, 5)
+		assert.True(t, aoa.GetContributionAuditResult(cheat))
+		assert.False(t, aoa.GetContributionAuditResult(honest))
+		assert.Equal(t, turn == 2, aoa.GetSanction(cheat, 0).Expel)
+	}
+	assert.True(t, aoa.GetSanction(honest, 0).IsZero())
+}
+
+// A spec naming a rule or parameter that does not exist should not build
+func TestComposableAoARejectsUnknownRules(t *testing.T) {
+	assert.Error(t, common.AoASpec{Verdict: common.ComponentSpec{Name: "never"}}.Validate())
+	assert.Error(t, common.AoASpec{AuditCost: common.ComponentSpec{Name: "fixed", Params: common.AoAParams{"share": 1}}}.Validate())
+	assert.NoError(t, common.AoASpec{}.Validate())
+}
@@ -0,0 +1,97 @@
+package main
+
+/*
+* Code to test the step-through debugger: its breakpoints and its prompt.
+ */
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBreakpoint(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    envServer.Breakpoint
+		wantErr bool
+	}{
+		{spec: "", want: envServer.NewBreakpoint()},
+		{spec: "iteration=2,turn=9,phase=withdrawal", want: envServer.Breakpoint{Iteration: 2, Turn: 9, Phase: "withdrawal"}},
+		{spec: " event=Audit , agent=3f2a ", want: envServer.Breakpoint{Iteration: -1, Turn: -1, AgentPrefix: "3f2a", EventType: envServer.EventAudit}},
+		{spec: "turn=x", wantErr: true},
+		{spec: "phase", wantErr: true},
+		{spec: "colour=red", wantErr: true},
+	}
+	for _, test := range tests {
+		bp, err := envServer.ParseBreakpoint(test.spec)
+		if test.wantErr {
+			assert.Error(t, err, test.spec)
+			continue
+		}
+		assert.NoError(t, err, test.spec)
+		assert.Equal(t, test.want, bp, test.spec)
+	}
+}
+
+func TestBreakpointMatches(t *testing.T) {
+	agentID := uuid.New()
+	when := envServer.EventContext{Iteration: 1, Turn: 4}
+	phase := envServer.PhaseEvent{EventContext: when, Phase: envServer.PhaseWithdrawal}
+	contribution := envServer.ContributionEvent{EventContext: when, AgentID: agentID}
+
+	tests := []struct {
+		spec  string
+		event envServer.Event
+		want  bool
+	}{
+		{spec: "", event: contribution, want: true},
+		{spec: "phase=withdrawal", event: phase, want: true},
+		{spec: "phase=contribution", event: phase, want: false},
+		{spec: "phase=withdrawal", event: contribution, want: false},
+		{spec: "agent=" + agentID.String()[:4], event: contribution, want: true},
+		{spec: "agent=" + agentID.String()[:4], event: phase, want: false},
+		{spec: "agent=" + uuid.New().String(), event: contribution, want: false},
+		{spec: "event=Contribution", event: contribution, want: true},
+		{spec: "event=Audit", event: contribution, want: false},
+		{spec: "iteration=1,turn=4", event: phase, want: true},
+		{spec: "iteration=1,turn=5", event: phase, want: false},
+	}
+	for _, test := range tests {
+		bp, err := envServer.ParseBreakpoint(test.spec)
+		assert.NoError(t, err)
+		assert.Equal(t, test.want, bp.Matches(test.event), "%s on %s", test.spec, test.event.Type())
+	}
+}
+
+// The prompt shows its views, steps to the next phase and lets the game run on
+func TestDebuggerPrompt(t *testing.T) {
+	serv, _, agentIDs := CreateTeamTestServer(t, "honest=3")
+	bp, err := envServer.ParseBreakpoint("phase=" + envServer.PhaseContribution)
+	assert.NoError(t, err)
+
+	in := strings.NewReader("teams\nbogus\nstep\npools\nbreaks\ncontinue\n")
+	out := &bytes.Buffer{}
+	serv.AttachDebugger(in, out, []envServer.Breakpoint{bp})
+	serv.RunTurn(0, 1)
+
+	output := out.String()
+	assert.Equal(t, 2, strings.Count(output, "[debug] paused"))
+	assert.Contains(t, output, "breakpoint 0 (phase=contribution)")
+	assert.Contains(t, output, "on step: start of contributionAudit phase")
+	assert.Contains(t, output, "TEAM")
+	assert.Contains(t, output, "POOL")
+	assert.Contains(t, output, "0: phase=contribution")
+	assert.Contains(t, output, `unknown command "bogus"`)
+	for _, agentID := range agentIDs {
+		assert.Contains(t, output, agentID.String()[:8])
+	}
+
+	// with its input closed the debugger lets the game run to completion
+	serv.RunTurn(0, 2)
+	assert.Equal(t, 3, strings.Count(out.String(), "[debug] paused"))
+}
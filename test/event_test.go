@@ -0,0 +1,86 @@
+package main
+
+/*
+* Code to test that the server publishes game events to its subscribers.
+ */
+
+import (
+	"testing"
+	"time"
+
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+* Forming a team and allocating an orphan should each notify the subscribers
+ */
+func TestTeamEventsArePublished(t *testing.T) {
+	serv, agentIDs := CreateTestServer()
+
+	formed := []envServer.TeamFormedEvent{}
+	allocated := []envServer.OrphanAllocatedEvent{}
+	ballots := []envServer.BallotEvent{}
+	allEvents := 0
+	serv.OnTeamFormed(func(e envServer.TeamFormedEvent) { formed = append(formed, e) })
+	serv.OnOrphanAllocated(func(e envServer.OrphanAllocatedEvent) { allocated = append(allocated, e) })
+	serv.OnBallot(func(e envServer.BallotEvent) { ballots = append(ballots, e) })
+	serv.OnAnyEvent(func(envServer.Event) { allEvents++ })
+
+	// Leave the first agent out of the team so it becomes an orphan
+	orphan := agentIDs[0]
+	teamID := serv.CreateAndInitTeamWithAgents(agentIDs[1:])
+	serv.GetAgentMap()[orphan].SetTeamRanking([]uuid.UUID{teamID})
+
+	serv.PickUpOrphans()
+	serv.AllocateOrphans()
+
+	assert.Equal(t, 1, len(formed))
+	assert.Equal(t, teamID, formed[0].TeamID)
+	assert.Equal(t, agentIDs[1:], formed[0].Agents)
+
+	assert.Equal(t, 1, len(allocated))
+	assert.Equal(t, orphan, allocated[0].AgentID)
+	assert.Equal(t, teamID, allocated[0].TeamID)
+
+	// the team admitted the orphan by ballot
+	assert.Equal(t, 1, len(ballots))
+	assert.Equal(t, teamID, ballots[0].TeamID)
+	assert.Equal(t, 0, ballots[0].Winner)
+
+	assert.Equal(t, 3, allEvents)
+}
+
+/*
+* Handlers called in the middle of a turn can call back into the server,
+* including functions that take its team lock
+ */
+func TestHandlersCanCallBackIntoServer(t *testing.T) {
+	serv, agentIDs := CreateScenarioTestServer(t, "honest=4")
+	serv.CreateAndInitTeamWithAgents(agentIDs[1:])
+
+	inTeam := []bool{}
+	newTeamID := uuid.Nil
+	serv.OnContribution(func(e envServer.ContributionEvent) {
+		inTeam = append(inTeam, serv.CheckAgentAlreadyInTeam(e.AgentID))
+		if newTeamID == uuid.Nil {
+			newTeamID = serv.CreateAndInitTeamWithAgents(agentIDs[:1])
+		}
+	})
+
+	done := make(chan bool)
+	go func() {
+		serv.RunTurn(0, 1)
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("turn deadlocked on a handler calling back into the server")
+	}
+
+	assert.Equal(t, []bool{true, true, true}, inTeam)
+	assert.NotEqual(t, uuid.Nil, newTeamID)
+	assert.Equal(t, newTeamID, serv.GetAgentMap()[agentIDs[0]].GetTeamID())
+}
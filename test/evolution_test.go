@@ -0,0 +1,51 @@
+package main
+
+/*
+* Code to test the evolutionary population mode.
+ */
+
+import (
+	"testing"
+
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/ADimoska/SOMASExtended/simulation"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+* Dead agents should be replaced by offspring playing the strategy of a living
+* parent, and the population should keep its size
+ */
+func TestDeadAgentsReplacedByOffspring(t *testing.T) {
+	scenario := simulation.DefaultScenario()
+	scenario.Iterations, scenario.Turns, scenario.TurnTimeoutMs = 4, 8, 10
+	scenario.Population, _ = simulation.ParsePopulation("honest=3,free-rider=3,liar=3")
+	// unlike imitation, tournament selection finds a parent whenever anyone survives
+	scenario.Evolution, scenario.MutationRate = "tournament", 1
+
+	strategies := map[uuid.UUID]string{}
+	offspring := []envServer.OffspringEvent{}
+	result, err := simulation.Run(scenario, simulation.RunOptions{
+		Subscribe: func(serv *envServer.EnvironmentServer) {
+			for id, agent := range serv.GetAgentMap() {
+				strategies[id] = agent.GetStrategyName()
+			}
+			serv.OnOffspring(func(e envServer.OffspringEvent) {
+				assert.Equal(t, strategies[e.ParentID], e.Strategy)
+				strategies[e.AgentID] = e.Strategy
+				offspring = append(offspring, e)
+			})
+		},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Violations)
+	assert.NotEmpty(t, offspring)
+	assert.Equal(t, len(offspring), result.Summary.Offspring)
+	assert.Equal(t, 9, result.Summary.Agents)
+	for _, e := range offspring {
+		for _, value := range e.Params {
+			assert.GreaterOrEqual(t, value, 0.0)
+		}
+	}
+}
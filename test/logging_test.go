@@ -0,0 +1,41 @@
+package main
+
+/*
+* Code to test the per component log levels.
+ */
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+* A component with its own level should log at that level, while the others
+* follow the default level
+ */
+func TestComponentLevels(t *testing.T) {
+	level, levels, err := logging.ParseLevels("warn,agent=debug")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+	assert.Equal(t, map[string]slog.Level{"agent": slog.LevelDebug}, levels)
+
+	var out bytes.Buffer
+	logging.Configure(logging.Config{Level: level, Levels: levels, Output: &out})
+	defer logging.Disable()
+
+	logging.For(logging.ComponentAgent).Debug("agent detail")
+	logging.For(logging.ComponentServer).Info("server detail")
+	logging.For(logging.ComponentServer).Warn("server warning")
+
+	assert.Contains(t, out.String(), "agent detail")
+	assert.Contains(t, out.String(), "component=agent")
+	assert.NotContains(t, out.String(), "server detail")
+	assert.Contains(t, out.String(), "server warning")
+
+	_, _, err = logging.ParseLevels("agent=loud")
+	assert.Error(t, err)
+}
@@ -9,6 +9,7 @@ import (
 	agents "github.com/ADimoska/SOMASExtended/agents"
 	common "github.com/ADimoska/SOMASExtended/common"
 	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/ADimoska/SOMASExtended/simulation"
 	baseServer "github.com/MattSScott/basePlatformSOMAS/v2/pkg/server"
 	"reflect"
 	"testing" // built-in go testing package
@@ -25,8 +26,7 @@ import (
 func CreateTestServer() (*envServer.EnvironmentServer, []uuid.UUID) {
 	// Default test config
 	agentConfig := agents.AgentConfig{
-		InitScore:    0,
-		VerboseLevel: 10,
+		InitScore: 0,
 	}
 
 	serv := &envServer.EnvironmentServer{
@@ -58,6 +58,35 @@ func CreateTestServer() (*envServer.EnvironmentServer, []uuid.UUID) {
 	return serv, agentIDs
 }
 
+/*
+* Return a server built from the default scenario with the given population,
+* e.g. "honest=2,liar=1", and the IDs of its agents, none of them in a team yet
+ */
+func CreateScenarioTestServer(t *testing.T, population string) (*envServer.EnvironmentServer, []uuid.UUID) {
+	scenario := simulation.DefaultScenario()
+	entries, err := simulation.ParsePopulation(population)
+	assert.NoError(t, err)
+	scenario.Population = entries
+	serv, err := simulation.NewServer(scenario)
+	assert.NoError(t, err)
+
+	agentIDs := make([]uuid.UUID, 0)
+	for id := range serv.GetAgentMap() {
+		agentIDs = append(agentIDs, id)
+	}
+	return serv, agentIDs
+}
+
+/*
+* Return a server as above with all of its agents in a single team, running
+* the default AoA
+ */
+func CreateTeamTestServer(t *testing.T, population string) (*envServer.EnvironmentServer, *common.Team, []uuid.UUID) {
+	serv, agentIDs := CreateScenarioTestServer(t, population)
+	team := serv.GetTeamFromTeamID(serv.CreateAndInitTeamWithAgents(agentIDs))
+	return serv, team, agentIDs
+}
+
 /* Define Mock functions for the VoteOnAgentEntry function. These will override
 * the base implementation to test different voting logic. Yes, monkeypatching
 * is not particularly safe practice with Go but seeing as there is literally no
@@ -0,0 +1,86 @@
+package main
+
+/*
+* Code to test the election of team roles.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/agents"
+	"github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// An AoA with a chair that rotates every turn
+type rotatingChairAoA struct {
+	common.IArticlesOfAssociation
+	*common.TeamRoles
+}
+
+// Team 2's leader is elected by vote, and again when the term is up
+func TestTeam2ElectsItsLeader(t *testing.T) {
+	serv, team, _ := CreateTeamTestServer(t, "base=3")
+	elections := []envServer.ElectionEvent{}
+	serv.OnElection(func(e envServer.ElectionEvent) { elections = append(elections, e) })
+	info, _ := common.LookupAoAByName("team2")
+	team.TeamAoA, team.TeamAoAID = common.CreateAoA(info.ID, team, common.AoAParams{"leaderTerm": 2})
+
+	serv.RunTurn(0, 1)
+	leader := team.TeamAoA.(*common.Team2AoA).Leader
+	assert.NotEqual(t, uuid.Nil, leader)
+	assert.Equal(t, []uuid.UUID{leader}, serv.GetRoleHolders(team.TeamID, common.RoleLeader))
+
+	serv.RunTurn(0, 2)
+	serv.RunTurn(0, 3)
+	assert.Equal(t, 2, len(elections))
+	assert.Equal(t, "first election", elections[0].Reason)
+	assert.Equal(t, "end of term", elections[1].Reason)
+}
+
+// Rotation passes the seat down the team, and an impeached holder loses it
+func TestRotatingChairAndImpeachment(t *testing.T) {
+	serv, team, _ := CreateTeamTestServer(t, "base=3")
+	elections := []envServer.ElectionEvent{}
+	serv.OnElection(func(e envServer.ElectionEvent) { elections = append(elections, e) })
+	team.TeamAoA = rotatingChairAoA{common.CreateFixedAoA(1), common.NewTeamRoles(
+		common.RoleSpec{Role: common.RoleChair, Method: "rotation"},
+		common.RoleSpec{Role: common.RoleAuditor, Method: "rotation", Term: 10, ImpeachmentMajority: 0.3})}
+
+	chairs := []uuid.UUID{}
+	for turn := 1; turn <= 3; turn++ {
+		serv.RunTurn(0, turn)
+		chairs = append(chairs, serv.GetRoleHolders(team.TeamID, common.RoleChair)[0])
+	}
+	assert.ElementsMatch(t, team.Agents, chairs)
+
+	// the ballot closes at the end of the next turn, and the seat is filled the turn after
+	auditor := serv.GetRoleHolders(team.TeamID, common.RoleAuditor)[0]
+	assert.Error(t, serv.Impeach(team.TeamID, common.RoleAuditor, auditor, uuid.New()))
+	assert.NoError(t, serv.Impeach(team.TeamID, common.RoleAuditor, auditor, auditor))
+	serv.RunTurn(0, 4)
+	assert.Empty(t, serv.GetRoleHolders(team.TeamID, common.RoleAuditor))
+	serv.RunTurn(0, 5)
+	last := elections[len(elections)-1]
+	assert.Equal(t, "impeachment", last.Reason)
+	assert.NotContains(t, last.Holders, auditor)
+}
+
+// Default agents vote for the teammates they trust, so one caught cheating is not elected
+func TestDefaultAgentsElectTrustedLeader(t *testing.T) {
+	serv, team, _ := CreateTeamTestServer(t, "base=3")
+	cheat := team.Agents[0]
+	for _, agentID := range team.Agents {
+		agent := serv.GetAgentMap()[agentID].(*agents.ExtendedAgent)
+		agent.Memory.ObserveAudit(common.AuditEntry{AgentID: cheat, Verdict: true})
+	}
+	info, _ := common.LookupAoAByName("team2")
+	team.TeamAoA, team.TeamAoAID = common.CreateAoA(info.ID, team, nil)
+
+	serv.RunTurn(0, 1)
+	leaders := serv.GetRoleHolders(team.TeamID, common.RoleLeader)
+	assert.Len(t, leaders, 1)
+	assert.NotEqual(t, cheat, leaders[0])
+}
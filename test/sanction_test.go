@@ -0,0 +1,89 @@
+package main
+
+/*
+* Code to test graduated sanctions and the bans the server enforces.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Repeated offences climb the ladder, and clean turns forgive them one at a time
+func TestSanctionLadderEscalatesAndForgives(t *testing.T) {
+	aoa, err := common.AoASpec{
+		Sanction: common.ComponentSpec{Name: "ladder", Params: common.AoAParams{"forgiveness": 2}},
+	}.Build(common.NewTeam(uuid.New()))
+	assert.NoError(t, err)
+	cheat := uuid.New()
+
+	expected := []common.Sanction{{Warning: true}, {Fine: 5}, {WithdrawalBan: 2}, {VoteBan: 3}, {Expel: true}, {Expel: true}}
+	for _, sanction := range expected {
+		assert.Equal(t, sanction, aoa.GetSanction(cheat, 0))
+	}
+	assert.Equal(t, 6, aoa.GetOffences(cheat))
+
+	// the turn of the offence does not count towards forgiveness
+	aoa.PassTurn()
+	aoa.PassTurn()
+	assert.Equal(t, 6, aoa.GetOffences(cheat))
+	aoa.PassTurn()
+	assert.Equal(t, 5, aoa.GetOffences(cheat))
+	aoa.PassTurn()
+	aoa.PassTurn()
+	assert.Equal(t, 4, aoa.GetOffences(cheat))
+	assert.Equal(t, common.Sanction{Expel: true}, aoa.GetSanction(cheat, 0))
+
+	assert.Len(t, common.SanctionLadderFromParams(common.AoAParams{"fine": 0, "voteBan": 0}), 3)
+}
+
+// An AoA that audits one agent once and bans it from withdrawing and voting
+type banningAoA struct {
+	common.IArticlesOfAssociation
+	target  uuid.UUID
+	audited bool
+}
+
+func (b *banningAoA) GetVoteResult(ballot common.BallotResult) uuid.UUID {
+	if b.audited {
+		return uuid.Nil
+	}
+	return b.target
+}
+
+func (b *banningAoA) GetContributionAuditResult(agentId uuid.UUID) bool {
+	return agentId == b.target
+}
+
+func (b *banningAoA) GetSanction(agentId uuid.UUID, commonPool int) common.Sanction {
+	b.audited = true
+	return common.Sanction{WithdrawalBan: 1, VoteBan: 1}
+}
+
+// A banned agent sits out the withdrawal phase of the turn it was sanctioned and cannot vote the next turn
+func TestServerEnforcesBans(t *testing.T) {
+	serv, team, _ := CreateTeamTestServer(t, "base=3")
+	target := team.Agents[0]
+	team.TeamAoA = &banningAoA{IArticlesOfAssociation: common.CreateFixedAoA(1), target: target}
+	withdrawals := map[int][]uuid.UUID{}
+	serv.OnWithdrawal(func(e envServer.WithdrawalEvent) { withdrawals[e.Turn] = append(withdrawals[e.Turn], e.AgentID) })
+	sanctions := []envServer.SanctionEvent{}
+	serv.OnSanction(func(e envServer.SanctionEvent) { sanctions = append(sanctions, e) })
+
+	serv.RunTurn(0, 1)
+	assert.Len(t, sanctions, 1)
+	assert.NotContains(t, withdrawals[1], target)
+	assert.Len(t, withdrawals[1], len(team.Agents)-1)
+
+	serv.RunTurn(0, 2)
+	assert.Contains(t, withdrawals[2], target)
+	assert.False(t, serv.IsWithdrawalBanned(target))
+	assert.True(t, serv.IsVoteBanned(target))
+
+	serv.RunTurn(0, 3)
+	assert.False(t, serv.IsVoteBanned(target))
+}
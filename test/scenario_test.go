@@ -0,0 +1,109 @@
+package main
+
+/*
+* Code to test scenarios: populations, parameter sweeps and the invariant checker.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/ADimoska/SOMASExtended/simulation"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePopulation(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []simulation.PopulationEntry
+		wantErr bool
+	}{
+		{spec: "team4=2,base=3", want: []simulation.PopulationEntry{{Agent: "team4", Count: 2}, {Agent: "base", Count: 3}}},
+		{spec: " honest , liar=0", want: []simulation.PopulationEntry{{Agent: "honest", Count: 1}, {Agent: "liar", Count: 0}}},
+		{spec: "liar(lieRate=0.5,declare=1)=3", want: []simulation.PopulationEntry{
+			{Agent: "liar", Count: 3, Params: common.StrategyParams{"lieRate": 0.5, "declare": 1}},
+		}},
+		{spec: "", want: []simulation.PopulationEntry{}},
+		{spec: "base=two", wantErr: true},
+		{spec: "base=-1", wantErr: true},
+		{spec: "liar(lieRate=0.5=2", wantErr: true},
+		{spec: "liar(lieRate=much)=2", wantErr: true},
+	}
+	for _, test := range tests {
+		population, err := simulation.ParsePopulation(test.spec)
+		if test.wantErr {
+			assert.Error(t, err, test.spec)
+			continue
+		}
+		assert.NoError(t, err, test.spec)
+		assert.Equal(t, test.want, population, test.spec)
+	}
+}
+
+func TestParseSweepParameter(t *testing.T) {
+	parameter, err := simulation.ParseSweepParameter("turns=8,12,16")
+	assert.NoError(t, err)
+	assert.Equal(t, simulation.SweepParameter{Key: "turns", Values: []string{"8", "12", "16"}}, parameter)
+
+	// populations contain commas, so they are separated by ';'
+	parameter, err = simulation.ParseSweepParameter("agents=honest=2,liar=1;honest=3")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"honest=2,liar=1", "honest=3"}, parameter.Values)
+
+	for _, spec := range []string{"turns", "turns="} {
+		_, err = simulation.ParseSweepParameter(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+// Every combination of the parameters is run with consecutive seeds
+func TestGridScenarios(t *testing.T) {
+	base := simulation.DefaultScenario()
+	base.Seed = 10
+	turns, _ := simulation.ParseSweepParameter("turns=8,12")
+	population, _ := simulation.ParseSweepParameter("agents=honest=3;liar=3")
+
+	scenarios, err := simulation.GridScenarios(base, []simulation.SweepParameter{turns, population}, 2)
+	assert.NoError(t, err)
+	assert.Len(t, scenarios, 8)
+	assert.Equal(t, "default_turns=8_agents=honest=3_seed10", scenarios[0].Name)
+	assert.Equal(t, "default_turns=8_agents=honest=3_seed11", scenarios[1].Name)
+	assert.Equal(t, int64(11), scenarios[1].Seed)
+	assert.Equal(t, "default_turns=12_agents=liar=3_seed11", scenarios[7].Name)
+	assert.Equal(t, 12, scenarios[7].Turns)
+	assert.Equal(t, []simulation.PopulationEntry{{Agent: "liar", Count: 3}}, scenarios[7].Population)
+
+	unknown, _ := simulation.ParseSweepParameter("colour=red")
+	_, err = simulation.GridScenarios(base, []simulation.SweepParameter{unknown}, 1)
+	assert.Error(t, err)
+	// every point is validated, not just parsed
+	noTurns, _ := simulation.ParseSweepParameter("turns=0")
+	_, err = simulation.GridScenarios(base, []simulation.SweepParameter{noTurns}, 1)
+	assert.Error(t, err)
+}
+
+func TestInvariantChecker(t *testing.T) {
+	bus := envServer.NewEventBus()
+	checker := simulation.NewInvariantChecker()
+	checker.Attach(bus)
+	teamID, agentID, stranger := uuid.New(), uuid.New(), uuid.New()
+	at := func(turn int) envServer.EventContext { return envServer.EventContext{Turn: turn} }
+
+	bus.Publish(envServer.TeamFormedEvent{EventContext: at(0), TeamID: teamID, Agents: []uuid.UUID{agentID}})
+	bus.Publish(envServer.ContributionEvent{EventContext: at(1), TeamID: teamID, AgentID: agentID, ActualContribution: 3})
+	bus.Publish(envServer.WithdrawalEvent{EventContext: at(1), TeamID: teamID, AgentID: agentID, ActualWithdrawal: 2, CommonPoolAfter: 1})
+	assert.Empty(t, checker.Violations)
+
+	bus.Publish(envServer.ContributionEvent{EventContext: at(2), TeamID: teamID, AgentID: stranger})
+	bus.Publish(envServer.WithdrawalEvent{EventContext: at(2), TeamID: teamID, AgentID: agentID, CommonPoolAfter: -1})
+	bus.Publish(envServer.DeathEvent{EventContext: at(2), AgentID: agentID})
+	bus.Publish(envServer.DeathEvent{EventContext: at(2), AgentID: agentID})
+	bus.Publish(envServer.AuditEvent{EventContext: at(1), TeamID: teamID, AuditedAgentID: agentID})
+	assert.Len(t, checker.Violations, 6)
+	assert.Contains(t, checker.Violations[0], "is not a member of team")
+	assert.Contains(t, checker.Violations[1], "went negative")
+	assert.Contains(t, checker.Violations[2], "died twice")
+	assert.Contains(t, checker.Violations[3], "time went backwards")
+}
@@ -0,0 +1,115 @@
+package main
+
+/*
+* Code to test the score report phase and AoAs basing contributions on it.
+ */
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/agents"
+	"github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Every member declares its turn score once, and liars declare half of it
+func TestScoreReportsAreRecordedAgainstRolls(t *testing.T) {
+	serv, team, _ := CreateTeamTestServer(t, "honest=2,liar=1")
+	teamID := team.TeamID
+
+	events := []envServer.ScoreReportEvent{}
+	serv.OnScoreReport(func(e envServer.ScoreReportEvent) { events = append(events, e) })
+	serv.RunTurn(0, 1)
+
+	reports := serv.GetScoreReports(teamID)
+	assert.Len(t, reports, 3)
+	assert.Len(t, events, 3)
+	for _, report := range reports {
+		agent := serv.GetAgentMap()[report.AgentID]
+		expected := report.Rolled
+		if agent.GetStrategyName() == "liar" {
+			expected = int(math.Round(float64(report.Rolled) * 0.5))
+		}
+		assert.Equal(t, expected, report.Declared)
+	}
+}
+
+// An agent that declares a higher turn score every time it is asked, and keeps the score reports it is sent
+type driftingAgent struct {
+	*agents.ExtendedAgent
+	asked int
+	heard []int
+}
+
+func (d *driftingAgent) GetStatedTurnScore(instance common.IExtendedAgent) int {
+	d.asked++
+	return d.asked
+}
+
+func (d *driftingAgent) HandleScoreReportMessage(msg *common.ScoreReportMessage) {
+	d.heard = append(d.heard, msg.TurnScore)
+	d.ExtendedAgent.HandleScoreReportMessage(msg)
+}
+
+// Each member is asked for its turn score once, and the team hears what was recorded
+func TestScoreReportIsBroadcastAsRecorded(t *testing.T) {
+	serv, team, agentIDs := CreateTeamTestServer(t, "test-drifting=2")
+	serv.RunTurn(0, 1)
+
+	reports := serv.GetScoreReports(team.TeamID)
+	assert.Len(t, reports, 2)
+	for _, report := range reports {
+		assert.Equal(t, 1, serv.GetAgentMap()[report.AgentID].(*driftingAgent).asked)
+		for _, agentID := range agentIDs {
+			if agentID != report.AgentID {
+				assert.Equal(t, []int{report.Declared}, serv.GetAgentMap()[agentID].(*driftingAgent).heard)
+			}
+		}
+	}
+}
+
+// Under declared scores, the expected contribution follows the declaration and under-declaring is an infraction
+func TestDeclaredScoreBasis(t *testing.T) {
+	agentID := uuid.New()
+	spec := common.AoASpec{
+		Contribution: common.ComponentSpec{Name: "share", Params: common.AoAParams{"share": 0.5}},
+		Verdict:      common.ComponentSpec{Name: "latest"},
+	}
+	trueBasis, err := spec.Build(nil)
+	assert.NoError(t, err)
+	spec.ScoreBasis = common.DeclaredScores
+	declaredBasis, err := spec.Build(nil)
+	assert.NoError(t, err)
+
+	report := common.ScoreReport{AgentID: agentID, Declared: 4, Rolled: 10}
+	for _, aoa := range []*common.ComposableAoA{trueBasis, declaredBasis} {
+		aoa.SetScoreReport(report)
+	}
+	assert.Equal(t, 10, trueBasis.GetExpectedContribution(agentID, 20))
+	assert.Equal(t, 7, declaredBasis.GetExpectedContribution(agentID, 20))
+
+	trueBasis.SetContributionAuditResult(agentID, 20, 10, 10)
+	declaredBasis.SetContributionAuditResult(agentID, 20, 7, 7)
+	assert.False(t, trueBasis.GetContributionAuditResult(agentID))
+	assert.True(t, declaredBasis.GetContributionAuditResult(agentID))
+
+	// last turn's declaration no longer counts once the reports are reset
+	declaredBasis.ResetScoreReports()
+	assert.Equal(t, 10, declaredBasis.GetExpectedContribution(agentID, 20))
+
+	spec.ScoreBasis = "guessed"
+	assert.Error(t, spec.Validate())
+}
+
+// Teams on the Team 5 AoA, which run their own turn, declare their scores too
+func TestTeam5DeclaresScores(t *testing.T) {
+	serv, team, _ := CreateTeamTestServer(t, "honest=3")
+	team.TeamAoA, team.TeamAoAID = common.CreateAoA(5, team, nil)
+	assert.Equal(t, 5, team.TeamAoAID)
+
+	serv.RunTurn(0, 1)
+	assert.Len(t, serv.GetScoreReports(team.TeamID), 3)
+}
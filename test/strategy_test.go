@@ -0,0 +1,68 @@
+package main
+
+/*
+* Code to test building agents from the strategy registry.
+ */
+
+import (
+	"testing"
+
+	agents "github.com/ADimoska/SOMASExtended/agents"
+	common "github.com/ADimoska/SOMASExtended/common"
+	"github.com/MattSScott/basePlatformSOMAS/v2/pkg/agent"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+* The strategies every test file builds agents from. They are all registered
+* here, once for the whole test binary, since the registry rejects a name
+* registered twice.
+ */
+func init() {
+	agents.RegisterStrategy(agents.StrategyInfo{
+		Name:   "test-cautious",
+		Params: common.StrategyParams{"caution": 0.5, "greed": 0.1},
+		Constructor: func(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config agents.AgentConfig, params common.StrategyParams) common.IExtendedAgent {
+			return agents.GetBaseAgents(funcs, config)
+		},
+	})
+
+	agents.RegisterStrategy(agents.StrategyInfo{
+		Name: "test-drifting",
+		Constructor: func(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config agents.AgentConfig, params common.StrategyParams) common.IExtendedAgent {
+			return &driftingAgent{ExtendedAgent: agents.GetBaseAgents(funcs, config)}
+		},
+	})
+
+	// reformers propose moving their team to the target AoA (see amendment_test.go)
+	for name, target := range map[string]int{"test-reformer": 2, "test-bad-reformer": 99} {
+		agents.RegisterStrategy(agents.StrategyInfo{
+			Name: name,
+			Constructor: func(funcs agent.IExposedServerFunctions[common.IExtendedAgent], config agents.AgentConfig, params common.StrategyParams) common.IExtendedAgent {
+				return &reformerAgent{ExtendedAgent: agents.GetBaseAgents(funcs, config), target: target}
+			},
+		})
+	}
+}
+
+/*
+* An agent built by name should remember its strategy and parameters, with
+* defaults filled in for the parameters that were not given
+ */
+func TestCreateAgentFromRegistry(t *testing.T) {
+	serv, _ := CreateTestServer()
+
+	created, err := agents.CreateAgent("test-cautious", serv, agents.AgentConfig{}, common.StrategyParams{"greed": 0.9})
+	assert.NoError(t, err)
+	assert.Equal(t, "test-cautious", created.GetStrategyName())
+	assert.Equal(t, common.StrategyParams{"caution": 0.5, "greed": 0.9}, created.GetStrategyParams())
+
+	_, err = agents.CreateAgent("test-cautious", serv, agents.AgentConfig{}, common.StrategyParams{"greeed": 0.9})
+	assert.Error(t, err)
+	_, err = agents.CreateAgent("no-such-strategy", serv, agents.AgentConfig{}, nil)
+	assert.Error(t, err)
+
+	team4, err := agents.CreateAgent("team4", serv, agents.AgentConfig{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, team4.GetTrueSomasTeamID())
+}
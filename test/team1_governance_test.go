@@ -0,0 +1,97 @@
+package main
+
+/*
+* Code to test Team 1's governance: the rank boundary vote and the chairs' ranking.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/common"
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// An agent that votes for rank boundaries anyone can reach, and ranks itself top when selfish
+type rankVoter struct {
+	common.IExtendedAgent
+	selfish bool
+}
+
+func (r *rankVoter) Team1_VoteOnRankBoundaries(initialBoundaries [5]int) [5]int {
+	return [5]int{0, 0, 0, 0, 0}
+}
+
+func (r *rankVoter) Team1_ChairUpdateRanks(currentRanks map[uuid.UUID]int, guidelineRanks map[uuid.UUID]int) map[uuid.UUID]int {
+	if r.selfish {
+		guidelineRanks[r.GetID()] = 5
+	}
+	return guidelineRanks
+}
+
+func newTeam1TestServer(t *testing.T) (*envServer.EnvironmentServer, *common.Team, []*rankVoter) {
+	serv, team, _ := CreateTeamTestServer(t, "base=3")
+	voters := []*rankVoter{}
+	for _, agent := range serv.GetAgentMap() {
+		voter := &rankVoter{IExtendedAgent: agent}
+		voters = append(voters, voter)
+		serv.AddAgent(voter)
+	}
+	info, _ := common.LookupAoAByName("team1")
+	team.TeamAoA, team.TeamAoAID = common.CreateAoA(info.ID, team, nil)
+	return serv, team, voters
+}
+
+// Members climb one rank a turn when the chairs agree, and every change is published
+func TestTeam1ChairsRankMembers(t *testing.T) {
+	serv, team, _ := newTeam1TestServer(t)
+	changes := []envServer.RankChangeEvent{}
+	serv.OnRankChange(func(e envServer.RankChangeEvent) { changes = append(changes, e) })
+	aoa := team.TeamAoA.(*common.Team1AoA)
+
+	serv.RunTurn(0, 1)
+	assert.Equal(t, [5]int{0, 0, 0, 0, 0}, aoa.GetRankBoundaries())
+	assert.Equal(t, 2, len(serv.GetRoleHolders(team.TeamID, common.RoleChair)))
+	serv.RunTurn(0, 2)
+
+	for _, agentID := range team.Agents {
+		assert.Equal(t, 3, aoa.GetMemberRanks()[agentID])
+	}
+	assert.Equal(t, 2*len(team.Agents), len(changes))
+	for _, change := range changes {
+		assert.Equal(t, team.TeamID, change.TeamID)
+		assert.Equal(t, change.From+1, change.To)
+	}
+}
+
+// Chairs that cannot agree leave the ranks as they are and lose a rank themselves
+func TestTeam1ChairsThatDisagreeAreDemoted(t *testing.T) {
+	serv, team, voters := newTeam1TestServer(t)
+	aoa := team.TeamAoA.(*common.Team1AoA)
+
+	serv.RunTurn(0, 1)
+	for _, voter := range voters {
+		voter.selfish = true
+	}
+	serv.RunTurn(0, 2)
+
+	chairs := serv.GetRoleHolders(team.TeamID, common.RoleChair)
+	assert.Equal(t, 2, len(chairs))
+	for _, agentID := range team.Agents {
+		if containsAgent(chairs, agentID) {
+			assert.Equal(t, 1, aoa.GetMemberRanks()[agentID])
+		} else {
+			assert.Equal(t, 2, aoa.GetMemberRanks()[agentID])
+		}
+	}
+}
+
+func containsAgent(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package main
+
+/*
+* Code to test the memory agents keep of their teammates.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/agents"
+	"github.com/ADimoska/SOMASExtended/common"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeammateMemoryEstimators(t *testing.T) {
+	memory := agents.NewTeammateMemory(3)
+	id := uuid.New()
+	assert.Equal(t, 0.5, memory.Honesty(id))
+	_, ok := memory.AverageContribution(id)
+	assert.False(t, ok)
+
+	// only the last 3 contributions are kept: 4, 6, 8
+	for _, stated := range []int{0, 4, 6, 8} {
+		memory.ObserveContribution(id, stated, 8)
+	}
+	average, ok := memory.AverageContribution(id)
+	assert.True(t, ok)
+	assert.Equal(t, 6.0, average)
+	assert.Equal(t, 2.0, memory.ContributionTrend(id))
+	cooperation, _ := memory.Cooperation(id)
+	assert.Equal(t, 1.0, cooperation)
+
+	// Beta(1, 1) updated with two honest audits and one that stood against it
+	memory.ObserveAudit(common.AuditEntry{AgentID: id, Verdict: false})
+	memory.ObserveAudit(common.AuditEntry{AgentID: id, Verdict: true, Overturned: true})
+	memory.ObserveAudit(common.AuditEntry{AgentID: id, Verdict: true})
+	assert.Equal(t, 0.6, memory.Honesty(id))
+
+	memory.ObserveOpinion(uuid.New(), id, 40)
+	memory.ObserveOpinion(uuid.New(), id, 80)
+	opinion, _ := memory.AverageOpinion(id)
+	assert.Equal(t, 60.0, opinion)
+
+	memory.Forget(id)
+	assert.Nil(t, memory.Teammate(id))
+}
+
+// The message handlers of every agent fill in its memory of its teammates
+func TestAgentsRememberTeammates(t *testing.T) {
+	serv, _, agentIDs := CreateTeamTestServer(t, "honest=3")
+	serv.RunTurn(0, 1)
+	serv.RunTurn(0, 2)
+
+	for _, agentID := range agentIDs {
+		memory := serv.GetAgentMap()[agentID].(*agents.BaselineAgent).Memory
+		assert.Nil(t, memory.Teammate(agentID))
+		for _, teammateID := range agentIDs {
+			if teammateID == agentID {
+				continue
+			}
+			record := memory.Teammate(teammateID)
+			assert.NotNil(t, record)
+			assert.Len(t, record.Contributions, 2)
+			assert.Len(t, record.TurnScores, 2)
+			assert.Len(t, record.Withdrawals, 2)
+		}
+	}
+}
+
+// Agents forget a teammate once it is no longer in their team, and forget everyone when they leave it
+func TestAgentsForgetFormerTeammates(t *testing.T) {
+	serv, team, agentIDs := CreateTeamTestServer(t, "honest=3")
+	serv.RunTurn(0, 1)
+
+	leaver := serv.GetAgentMap()[agentIDs[0]].(*agents.BaselineAgent)
+	assert.NotNil(t, leaver.Memory.Teammate(agentIDs[1]))
+	team.Agents = agentIDs[1:]
+	team.TeamAoA.OnMemberLeft(agentIDs[0])
+	leaver.SetTeamID(uuid.Nil)
+	assert.Nil(t, leaver.Memory.Teammate(agentIDs[1]))
+
+	serv.RunTurn(0, 2)
+	for _, agentID := range agentIDs[1:] {
+		memory := serv.GetAgentMap()[agentID].(*agents.BaselineAgent).Memory
+		assert.Nil(t, memory.Teammate(agentIDs[0]))
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+/*
+* Code to test the tournament runner.
+ */
+
+import (
+	"testing"
+
+	"github.com/ADimoska/SOMASExtended/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+* Every pair of entrants should meet, and every entrant should appear on the
+* leaderboard with the agent-games it played
+ */
+func TestTournamentPlaysEveryPair(t *testing.T) {
+	base := simulation.DefaultScenario()
+	base.Iterations, base.Turns, base.TurnTimeoutMs = 1, 4, 10
+	config := simulation.TournamentConfig{
+		Base:              base,
+		Strategies:        []string{"honest", "free-rider", "greedy"},
+		MatchupSize:       2,
+		AgentsPerStrategy: 2,
+		Games:             1,
+	}
+	assert.Equal(t, [][]string{{"honest", "free-rider"}, {"honest", "greedy"}, {"free-rider", "greedy"}}, config.Matchups())
+
+	result, err := simulation.RunTournament(config, nil)
+	assert.NoError(t, err)
+	assert.Len(t, result.Leaderboard, 3)
+	for _, standing := range result.Leaderboard {
+		// two matchups each, one game of two agents per matchup
+		assert.Equal(t, 4, standing.Agents, standing.Strategy)
+		assert.Equal(t, 2, standing.Games, standing.Strategy)
+	}
+}
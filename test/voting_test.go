@@ -0,0 +1,65 @@
+package main
+
+/*
+* Code to test the voting rules used for the AoA vote.
+ */
+
+import (
+	"testing"
+
+	envServer "github.com/ADimoska/SOMASExtended/server"
+	"github.com/ADimoska/SOMASExtended/voting"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+* 12 beats every other AoA head to head, but 1 has the most first choices. The
+* pairwise rules should follow the voters' rank order (not the numeric order of
+* the IDs) and cope with IDs of two digits.
+ */
+func TestVotingRulesOnCondorcetWinner(t *testing.T) {
+	ballots := []voting.Ballot{}
+	for i := 0; i < 4; i++ {
+		ballots = append(ballots, voting.Ballot{1, 12, 2})
+	}
+	for i := 0; i < 3; i++ {
+		ballots = append(ballots, voting.Ballot{12, 2, 1})
+	}
+	for i := 0; i < 2; i++ {
+		ballots = append(ballots, voting.Ballot{2, 12, 1})
+	}
+	candidates := voting.CandidatesOf(ballots)
+	assert.Equal(t, []int{1, 2, 12}, candidates)
+
+	winners := voting.CompareRules(candidates, ballots)
+	assert.Equal(t, []int{1}, winners["plurality"])
+	for _, rule := range []string{"copeland", "schulze", "irv", "borda"} {
+		assert.Equal(t, []int{12}, winners[rule], rule)
+	}
+
+	tally, err := voting.Elect("copeland", candidates, ballots)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]float64{1: 0, 2: 1, 12: 2}, tally.Scores)
+
+	_, err = voting.Elect("dictator", candidates, ballots)
+	assert.Error(t, err)
+	assert.Error(t, voting.Ballot{1, 2, 1}.Validate(candidates))
+	assert.Equal(t, voting.Ballot{1, 2}, voting.Ballot{1, 2, 1, 7}.Clean(candidates))
+}
+
+// An AoA that is not registered cannot win the AoA vote, however highly it is ranked
+func TestAoAVoteIgnoresUnregisteredAoAs(t *testing.T) {
+	serv, _ := CreateScenarioTestServer(t, "honest=3")
+	for _, agent := range serv.GetAgentMap() {
+		agent.SetAoARanking([]int{99, 2})
+	}
+	selections := []envServer.AoASelectedEvent{}
+	serv.OnAoASelected(func(e envServer.AoASelectedEvent) { selections = append(selections, e) })
+	serv.RunStartOfIteration(0)
+
+	assert.NotEmpty(t, selections)
+	for _, selection := range selections {
+		assert.Equal(t, 2, selection.VotedAoAID)
+		assert.Equal(t, 2, selection.AoAID)
+	}
+}
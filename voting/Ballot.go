@@ -0,0 +1,103 @@
+package voting
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+* Social choice for the game: a team's members each hand in a ranked ballot
+* and a voting rule turns the ballots into one or more (tied) winners. The
+* rules only see candidate IDs, so the package can be used for anything the
+* team votes on, not just AoAs.
+ */
+
+// A ranked ballot: candidates, most preferred first. Candidates left off the
+// ballot are ranked below every listed candidate and tied with each other.
+type Ballot []int
+
+// A ballot is valid if every candidate on it is standing and appears once
+func (b Ballot) Validate(candidates []int) error {
+	standing := candidateSet(candidates)
+	seen := map[int]bool{}
+	for _, candidate := range b {
+		if !standing[candidate] {
+			return fmt.Errorf("ballot %v ranks %d, which is not a candidate", []int(b), candidate)
+		}
+		if seen[candidate] {
+			return fmt.Errorf("ballot %v ranks %d more than once", []int(b), candidate)
+		}
+		seen[candidate] = true
+	}
+	return nil
+}
+
+// The ballot without the candidates that are not standing and without repeats
+func (b Ballot) Clean(candidates []int) Ballot {
+	standing := candidateSet(candidates)
+	seen := map[int]bool{}
+	cleaned := Ballot{}
+	for _, candidate := range b {
+		if standing[candidate] && !seen[candidate] {
+			cleaned = append(cleaned, candidate)
+			seen[candidate] = true
+		}
+	}
+	return cleaned
+}
+
+// Position of every listed candidate, 0 for the first choice
+func (b Ballot) positions() map[int]int {
+	positions := make(map[int]int, len(b))
+	for i, candidate := range b {
+		positions[candidate] = i
+	}
+	return positions
+}
+
+// Whether the ballot prefers a to b
+func (b Ballot) prefers(positions map[int]int, a int, c int) bool {
+	pa, aRanked := positions[a]
+	pc, cRanked := positions[c]
+	return aRanked && (!cRanked || pa < pc)
+}
+
+/*
+* Restrict ballots to some of the candidates, keeping their order. Used for
+* tie breaks, where only the tied candidates are still standing.
+ */
+func Restrict(ballots []Ballot, candidates []int) []Ballot {
+	restricted := make([]Ballot, 0, len(ballots))
+	for _, ballot := range ballots {
+		restricted = append(restricted, ballot.Clean(candidates))
+	}
+	return restricted
+}
+
+// Every candidate ranked on at least one ballot, sorted
+func CandidatesOf(ballots []Ballot) []int {
+	set := map[int]bool{}
+	for _, ballot := range ballots {
+		for _, candidate := range ballot {
+			set[candidate] = true
+		}
+	}
+	return sortedCandidates(set)
+}
+
+func candidateSet(candidates []int) map[int]bool {
+	set := make(map[int]bool, len(candidates))
+	for _, candidate := range candidates {
+		set[candidate] = true
+	}
+	return set
+}
+
+func sortedCandidates(set map[int]bool) []int {
+	candidates := make([]int, 0, len(set))
+	for candidate := range set {
+		candidates = append(candidates, candidate)
+	}
+	sort.Ints(candidates)
+	return candidates
+}
@@ -0,0 +1,325 @@
+package voting
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+/*
+* Voting rules. Every rule gives each candidate a score and the candidates
+* with the highest score win, so a tally can always be shown as a table of
+* scores. Rules are chosen by name so that they can be set from scenario files
+* and the command line.
+ */
+type Rule func(candidates []int, ballots []Ballot) Tally
+
+type Tally struct {
+	Rule    string
+	Scores  map[int]float64   // the rule's score for every candidate
+	Rounds  []map[int]float64 // first choice counts per round, for instant-runoff
+	Winners []int             // every candidate tied on the highest score, sorted
+}
+
+type RuleInfo struct {
+	Name        string
+	Description string
+	Rule        Rule
+}
+
+const DefaultRule = "copeland"
+
+var rules = map[string]RuleInfo{
+	"plurality": {
+		Name:        "plurality",
+		Description: "one point for each ballot's first choice",
+		Rule:        Plurality,
+	},
+	"borda": {
+		Name:        "borda",
+		Description: "n-1 points for a first choice, n-2 for a second, ..., none when unranked",
+		Rule:        Borda,
+	},
+	"copeland": {
+		Name:        "copeland",
+		Description: "one point for each head to head majority won, half for a draw",
+		Rule:        Copeland,
+	},
+	"schulze": {
+		Name:        "schulze",
+		Description: "the candidates no one beats through a stronger chain of head to head majorities",
+		Rule:        Schulze,
+	},
+	"irv": {
+		Name:        "irv",
+		Description: "instant-runoff: eliminate the fewest first choices until one candidate has a majority",
+		Rule:        InstantRunoff,
+	},
+	"approval": {
+		Name:        "approval",
+		Description: "each ballot approves the top half of the candidates it ranks",
+		Rule:        Approval,
+	},
+	"range": {
+		Name:        "range",
+		Description: "each ballot rates its candidates from 1 (first) down to 1/k (last of k), 0 when unranked",
+		Rule:        Range,
+	},
+}
+
+func LookupRule(name string) (RuleInfo, bool) {
+	info, ok := rules[name]
+	return info, ok
+}
+
+// Every voting rule, sorted by name
+func Rules() []RuleInfo {
+	infos := make([]RuleInfo, 0, len(rules))
+	for _, info := range rules {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func RuleNames() []string {
+	names := []string{}
+	for _, info := range Rules() {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+// Run the named rule on ballots that are cleaned against the candidates first
+func Elect(rule string, candidates []int, ballots []Ballot) (Tally, error) {
+	info, ok := rules[rule]
+	if !ok {
+		return Tally{}, fmt.Errorf("unknown voting rule %q (known rules: %s)", rule, strings.Join(RuleNames(), ", "))
+	}
+	tally := info.Rule(candidates, Restrict(ballots, candidates))
+	tally.Rule = rule
+	return tally, nil
+}
+
+// The winners of the same ballots under every rule, to compare the rules
+func CompareRules(candidates []int, ballots []Ballot) map[string][]int {
+	winners := map[string][]int{}
+	for _, info := range Rules() {
+		tally, _ := Elect(info.Name, candidates, ballots)
+		winners[info.Name] = tally.Winners
+	}
+	return winners
+}
+
+// Fill in the winners from the scores
+func newTally(candidates []int, scores map[int]float64) Tally {
+	tally := Tally{Scores: scores, Winners: []int{}}
+	best := math.Inf(-1)
+	for _, candidate := range candidates {
+		score := scores[candidate]
+		if score > best {
+			best, tally.Winners = score, []int{candidate}
+		} else if score == best {
+			tally.Winners = append(tally.Winners, candidate)
+		}
+	}
+	sort.Ints(tally.Winners)
+	return tally
+}
+
+func zeroScores(candidates []int) map[int]float64 {
+	scores := make(map[int]float64, len(candidates))
+	for _, candidate := range candidates {
+		scores[candidate] = 0
+	}
+	return scores
+}
+
+// ----------------------- Positional rules -----------------------
+
+func Plurality(candidates []int, ballots []Ballot) Tally {
+	scores := zeroScores(candidates)
+	for _, ballot := range ballots {
+		if len(ballot) > 0 {
+			scores[ballot[0]]++
+		}
+	}
+	return newTally(candidates, scores)
+}
+
+func Borda(candidates []int, ballots []Ballot) Tally {
+	scores := zeroScores(candidates)
+	n := len(candidates)
+	for _, ballot := range ballots {
+		for position, candidate := range ballot {
+			scores[candidate] += float64(n - position - 1)
+		}
+	}
+	return newTally(candidates, scores)
+}
+
+func Approval(candidates []int, ballots []Ballot) Tally {
+	scores := zeroScores(candidates)
+	for _, ballot := range ballots {
+		approved := (len(ballot) + 1) / 2
+		for _, candidate := range ballot[:approved] {
+			scores[candidate]++
+		}
+	}
+	return newTally(candidates, scores)
+}
+
+func Range(candidates []int, ballots []Ballot) Tally {
+	scores := zeroScores(candidates)
+	for _, ballot := range ballots {
+		for position, candidate := range ballot {
+			scores[candidate] += 1 - float64(position)/float64(len(ballot))
+		}
+	}
+	return newTally(candidates, scores)
+}
+
+// ----------------------- Pairwise rules -----------------------
+
+// d[a][b] is the number of ballots that prefer a to b
+func pairwisePreferences(candidates []int, ballots []Ballot) map[int]map[int]int {
+	d := make(map[int]map[int]int, len(candidates))
+	for _, a := range candidates {
+		d[a] = make(map[int]int, len(candidates))
+	}
+	for _, ballot := range ballots {
+		positions := ballot.positions()
+		for _, a := range candidates {
+			for _, b := range candidates {
+				if a != b && ballot.prefers(positions, a, b) {
+					d[a][b]++
+				}
+			}
+		}
+	}
+	return d
+}
+
+func Copeland(candidates []int, ballots []Ballot) Tally {
+	d := pairwisePreferences(candidates, ballots)
+	scores := zeroScores(candidates)
+	for i, a := range candidates {
+		for _, b := range candidates[i+1:] {
+			switch {
+			case d[a][b] > d[b][a]:
+				scores[a]++
+			case d[a][b] < d[b][a]:
+				scores[b]++
+			default:
+				scores[a] += 0.5
+				scores[b] += 0.5
+			}
+		}
+	}
+	return newTally(candidates, scores)
+}
+
+/*
+* Schulze: the strength of a path is its weakest head to head majority, and a
+* wins if for every b its strongest path to b is at least as strong as b's to
+* a. The score of a candidate is the number of candidates it beats this way.
+ */
+func Schulze(candidates []int, ballots []Ballot) Tally {
+	d := pairwisePreferences(candidates, ballots)
+	p := make(map[int]map[int]int, len(candidates))
+	for _, a := range candidates {
+		p[a] = make(map[int]int, len(candidates))
+		for _, b := range candidates {
+			if a != b && d[a][b] > d[b][a] {
+				p[a][b] = d[a][b]
+			}
+		}
+	}
+	for _, k := range candidates {
+		for _, a := range candidates {
+			for _, b := range candidates {
+				if a != b && a != k && b != k {
+					p[a][b] = max(p[a][b], min(p[a][k], p[k][b]))
+				}
+			}
+		}
+	}
+
+	scores := zeroScores(candidates)
+	unbeaten := map[int]bool{}
+	for _, a := range candidates {
+		unbeaten[a] = true
+		for _, b := range candidates {
+			if p[a][b] > p[b][a] {
+				scores[a]++
+			} else if p[b][a] > p[a][b] {
+				unbeaten[a] = false
+			}
+		}
+	}
+	tally := Tally{Scores: scores, Winners: []int{}}
+	for _, a := range candidates {
+		if unbeaten[a] {
+			tally.Winners = append(tally.Winners, a)
+		}
+	}
+	sort.Ints(tally.Winners)
+	return tally
+}
+
+// ----------------------- Elimination rules -----------------------
+
+/*
+* Instant-runoff: count every ballot for its highest ranked candidate still
+* standing, and eliminate the candidates with the fewest votes until one has a
+* majority of the ballots that are not exhausted. If every remaining candidate
+* is tied they all win. A candidate's score is the round it was eliminated in
+* (the winners score one more than the last round).
+ */
+func InstantRunoff(candidates []int, ballots []Ballot) Tally {
+	standing := candidateSet(candidates)
+	scores := zeroScores(candidates)
+	rounds := []map[int]float64{}
+	for round := 1; len(standing) > 0; round++ {
+		counts := map[int]float64{}
+		for candidate := range standing {
+			counts[candidate] = 0
+		}
+		active := 0.0
+		for _, ballot := range ballots {
+			for _, candidate := range ballot {
+				if standing[candidate] {
+					counts[candidate]++
+					active++
+					break
+				}
+			}
+		}
+		rounds = append(rounds, counts)
+
+		fewest, most := math.Inf(1), math.Inf(-1)
+		for _, count := range counts {
+			fewest, most = math.Min(fewest, count), math.Max(most, count)
+		}
+		if most > active/2 || fewest == most {
+			for candidate, count := range counts {
+				if count == most {
+					scores[candidate] = float64(round + 1)
+				} else {
+					scores[candidate] = float64(round)
+				}
+			}
+			break
+		}
+		for candidate, count := range counts {
+			if count == fewest {
+				scores[candidate] = float64(round)
+				delete(standing, candidate)
+			}
+		}
+	}
+	tally := newTally(candidates, scores)
+	tally.Rounds = rounds
+	return tally
+}